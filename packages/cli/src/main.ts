@@ -8,7 +8,24 @@ async function main(): Promise<void> {
     const first = args[0];
     if (
       first === "daemon" ||
+      first === "capture" ||
+      first === "cleanup" ||
       first === "config" ||
+      first === "context" ||
+      first === "doctor" ||
+      first === "embeddings" ||
+      first === "entities" ||
+      first === "import" ||
+      first === "prompt" ||
+      first === "purge" ||
+      first === "queue" ||
+      first === "reprocess" ||
+      first === "search" ||
+      first === "sessions" ||
+      first === "summaries" ||
+      first === "stats" ||
+      first === "todos" ||
+      first === "topics" ||
       first === "version" ||
       first === "-h" ||
       first === "--help"
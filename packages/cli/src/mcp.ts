@@ -42,6 +42,20 @@ export function resolveMcpServers(
   const profiles = dereConfig.profiles ?? {};
 
   for (const spec of serverSpecs) {
+    if (spec.startsWith("profile:")) {
+      const profile = spec.slice("profile:".length);
+      if (!(profile in profiles)) {
+        throw new Error(`MCP profile '${profile}' not found`);
+      }
+      for (const name of profiles[profile]?.servers ?? []) {
+        if (!seen.has(name)) {
+          resolved.push(name);
+          seen.add(name);
+        }
+      }
+      continue;
+    }
+
     if (spec in profiles) {
       for (const name of profiles[spec]?.servers ?? []) {
         if (!seen.has(name)) {
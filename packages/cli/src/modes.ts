@@ -0,0 +1,57 @@
+import { readFile } from "node:fs/promises";
+import { dirname, join } from "node:path";
+
+import { getConfigPath } from "@dere/shared-config";
+
+export type ModeDefinition = {
+  name: string;
+  description: string;
+  /** Treat sessions in this mode as wellness sessions (entity extraction hint) */
+  wellness: boolean;
+  /** Markdown body, appended to the system prompt */
+  prompt: string;
+};
+
+function modesDir(): string {
+  return join(dirname(getConfigPath()), "modes");
+}
+
+// Front-matter is flat `key: value` lines between `---` fences.
+function parseModeFile(name: string, text: string): ModeDefinition {
+  const match = text.match(/^---\r?\n([\s\S]*?)\r?\n---\r?\n?/);
+  const meta: Record<string, string> = {};
+  if (match) {
+    for (const line of (match[1] ?? "").split(/\r?\n/)) {
+      const sep = line.indexOf(":");
+      if (sep > 0) {
+        meta[line.slice(0, sep).trim()] = line
+          .slice(sep + 1)
+          .trim()
+          .replace(/^["']|["']$/g, "");
+      }
+    }
+  }
+  const body = match ? text.slice(match[0].length) : text;
+  return {
+    name,
+    description: meta.description ?? "",
+    wellness: meta.wellness === "true",
+    prompt: body.trim(),
+  };
+}
+
+/**
+ * Load a user-defined mode from ~/.config/dere/modes/<name>.md. Returns null
+ * when no such file exists, so built-in modes keep working unchanged.
+ */
+export async function loadModeDefinition(name: string): Promise<ModeDefinition | null> {
+  if (!/^[\w-]+$/.test(name)) {
+    return null;
+  }
+  try {
+    const text = await readFile(join(modesDir(), `${name}.md`), "utf-8");
+    return parseModeFile(name, text);
+  } catch {
+    return null;
+  }
+}
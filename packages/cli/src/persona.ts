@@ -1,5 +1,5 @@
 import { parse } from "@iarna/toml";
-import { readFile } from "node:fs/promises";
+import { readdir, readFile } from "node:fs/promises";
 import { basename, dirname, join } from "node:path";
 
 import { getConfigPath } from "@dere/shared-config";
@@ -67,6 +67,24 @@ async function loadPersonalityFromFile(path: string): Promise<Personality> {
   return parsePersonality(text);
 }
 
+async function listTomlNames(dir: string): Promise<string[]> {
+  try {
+    const entries = await readdir(dir, { withFileTypes: true });
+    return entries
+      .filter((entry) => entry.isFile() && entry.name.endsWith(".toml"))
+      .map((entry) => basename(entry.name, ".toml"));
+  } catch {
+    return [];
+  }
+}
+
+function answersTo(personality: Personality, name: string): boolean {
+  const wanted = name.toLowerCase();
+  return [personality.name, personality.short_name, ...personality.aliases].some(
+    (candidate) => candidate.toLowerCase() === wanted,
+  );
+}
+
 export class PersonalityLoader {
   private readonly userDir: string;
   private readonly embeddedDir: string;
@@ -76,14 +94,32 @@ export class PersonalityLoader {
     this.embeddedDir = defaultEmbeddedDir();
   }
 
+  /**
+   * Load by file name, then by name, short name, or alias, checking the user
+   * directory before the built-ins at each step.
+   */
   async load(name: string): Promise<Personality> {
-    const userPath = join(this.userDir, `${name}.toml`);
-    try {
-      return await loadPersonalityFromFile(userPath);
-    } catch {
-      const embeddedPath = join(this.embeddedDir, `${name}.toml`);
-      return await loadPersonalityFromFile(embeddedPath);
+    const dirs = [this.userDir, this.embeddedDir];
+    for (const dir of dirs) {
+      try {
+        return await loadPersonalityFromFile(join(dir, `${name}.toml`));
+      } catch {
+        // fall through to the next directory
+      }
+    }
+    for (const dir of dirs) {
+      for (const candidate of await listTomlNames(dir)) {
+        try {
+          const personality = await loadPersonalityFromFile(join(dir, `${candidate}.toml`));
+          if (answersTo(personality, name)) {
+            return personality;
+          }
+        } catch {
+          // skip unreadable definitions
+        }
+      }
     }
+    throw new Error(`Personality not found: ${name}`);
   }
 
   static normalizeName(name: string): string {
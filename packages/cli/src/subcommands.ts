@@ -1,10 +1,25 @@
-import { existsSync } from "node:fs";
-import { readFile } from "node:fs/promises";
+import { constants, existsSync, readFileSync } from "node:fs";
+import { access, mkdir, readFile, unlink } from "node:fs/promises";
 import { spawn, spawnSync } from "node:child_process";
+import { randomInt } from "node:crypto";
+import { createConnection } from "node:net";
 import { homedir } from "node:os";
-import { join } from "node:path";
+import { join, resolve as resolvePath } from "node:path";
 
-import { getConfigPath, loadConfig, getDaemonUrlFromConfig } from "@dere/shared-config";
+import { stringify } from "@iarna/toml";
+import {
+  contextCharsPerToken,
+  estimateTokens,
+  findProjectConfigPath,
+  getConfigPath,
+  loadConfig,
+  loadConfigFile,
+  loadProjectConfig,
+  getDaemonUrlFromConfig,
+} from "@dere/shared-config";
+
+import { parseClaudeTranscript } from "./transcript.js";
+import { findPluginsPath, previewSystemPrompt } from "./wrapper.js";
 
 async function resolveDaemonUrl(): Promise<string> {
   const config = await loadConfig();
@@ -17,9 +32,26 @@ Usage:
   dere [subcommand] [options] [--] [claude args...]
 
 Subcommands:
-  daemon    Daemon management
-  config    Configuration management
-  version   Show version
+  daemon      Daemon management
+  capture     Send a test message through the capture pipeline
+  cleanup     Delete old sessions and their conversations
+  config      Configuration management
+  context     Inspect the context built for a session
+  doctor      Diagnose the install (hooks, daemon, database, PATH)
+  embeddings  Conversation embedding maintenance
+  entities    Knowledge graph entity maintenance
+  import      Import past Claude Code transcripts as sessions
+  prompt      Preview the system prompt for a personality/mode combination
+  purge       Delete one session and everything recorded for it
+  queue       Watch or wait on the background task queue
+  reprocess   Re-run entity extraction, summaries, or embeddings on stored sessions
+  search      Semantic search over past conversations
+  sessions    Session history
+  summaries   List, search, or regenerate session summaries
+  stats       Session cost by project and personality
+  todos       Follow-ups extracted from sessions, across sessions
+  topics      Group recent sessions into themes by their summaries
+  version     Show version
   -h, --help  Show help
 `;
 
@@ -30,14 +62,270 @@ Usage:
   dere daemon start
   dere daemon stop
   dere daemon restart
+
+status   Checks that the daemon answers and shows its queue.
+start    Starts the daemon in the background. Only one daemon runs at a time:
+         it holds the PID file while running and refuses to start while
+         another live daemon does.
+stop     Signals the daemon named in the PID file and waits for it to exit.
+restart  Stops the running daemon, if any, then starts a new one.
 `;
 
 const CONFIG_HELP = `Configuration management
 
 Usage:
-  dere config show
+  dere config show [--raw]
+  dere config validate
   dere config path
   dere config edit
+
+show prints the effective config (global merged with .dere.toml) with
+secrets masked; --raw prints the global file as-is. validate checks the
+config parses and that the daemon, database, and graph are reachable.
+`;
+
+const EMBEDDINGS_HELP = `Conversation embedding maintenance
+
+Usage:
+  dere embeddings backfill [--limit=N]
+  dere embeddings reindex
+
+backfill embeds stored conversations that are missing vectors, or whose
+vectors came from a model other than [dere_graph].embedding_model, so after a
+model switch it migrates everything to the new one. Search only compares
+vectors from the current model. Safe to re-run; each pass resumes where the
+previous one stopped.
+
+reindex drops and recreates the vector index using the configured
+[dere_graph].vector_metric (cosine or l2). Run it after changing the metric.
+`;
+
+const ENTITIES_HELP = `Knowledge graph entity maintenance
+
+Usage:
+  dere entities list [--type=TYPE] [--limit=N] [--format=json]
+  dere entities search <query> [--type=TYPE] [--limit=N] [--format=json]
+  dere entities graph [--center=NAME [--depth=N]] [--type=TYPE] [--limit=N]
+                      [--format=json|dot]
+  dere entities calibration [--format=json]
+  dere entities link --co-occurrence [--min-count=N]
+  dere entities edit <uuid> [--type=TYPE] [--value=NAME]
+  dere entities delete <uuid>
+  dere entities confirm <uuid>
+
+list     Shows entities grouped by type, most mentioned first (✓ = verified).
+         Colors and icons per type come from [dere_graph.entity_display].
+search   Finds entities semantically related to <query>.
+graph    Shows relationships among the most-mentioned entities, or with
+         --center those within --depth hops (default 2) of one entity.
+         --format=json prints {nodes, edges}; --format=dot prints Graphviz
+         DOT (dere entities graph --format=dot | dot -Tpng -o graph.png).
+calibration
+         Counts entities per extraction-confidence band, overall and per
+         type, to help pick [dere_graph].min_entity_confidence. Entities below
+         the current threshold were never stored, so they don't appear.
+link     Links entities mentioned together in at least N conversations
+         (default 3) with a RELATED_TO edge. Existing pairs are skipped.
+edit     Fixes a mis-typed or misnamed entity; the old name becomes an alias.
+delete   Removes an entity and all of its relationships.
+confirm  Marks an entity as human-verified; dedup keeps verified entities.
+`;
+
+const SESSIONS_HELP = `Session history
+
+Usage:
+  dere sessions list [--limit=N] [--tag=TAG]
+  dere sessions chain <id>
+  dere sessions pin <id>
+  dere sessions unpin <id>
+  dere sessions tag <id> <tag>...
+
+list   Shows recent sessions, most recently active first (📌 = pinned).
+       --tag limits the list to sessions carrying that tag.
+chain  Prints the sessions <id> continues from (via -c), oldest first, with
+       the summary recorded for each.
+pin    Protects a session from dere cleanup and the retention policy.
+unpin  Makes a pinned session eligible for cleanup again.
+tag    Adds free-form labels ("bugfix", "spike") to a session. Tags can also
+       be set at launch with dere --tag=NAME.
+`;
+
+const PROMPT_HELP = `System prompt preview
+
+Usage:
+  dere prompt preview [-P NAME]... [--mode NAME] [--profile=NAME]
+                      [--append-context=TEXT|@FILE]... [--append-system-prompt TEXT]
+                      [--bare] [--context]
+
+preview  Prints the system prompt dere would pass to Claude for these flags,
+         layered the same way as a launch (personalities, then the mode, then
+         --append-system-prompt) without starting a session. --context marks
+         where resumed history and --append-context notes are injected, per
+         [context].injection_placement or a {{CONTEXT}} marker.
+`;
+
+const QUEUE_HELP = `Background task queue
+
+Usage:
+  dere queue tail [--type=TYPE] [--limit=N] [--follow]
+  dere queue wait <task-id> [--timeout=SECONDS]
+
+tail  Prints the most recent tasks (default 20) with their status. With
+      --follow (-f) it keeps running and prints each task as it moves from
+      pending to running to completed or failed, with how long it waited and
+      how long it took.
+wait  Blocks until the task completes or fails (default timeout 30s, at most
+      300s) and prints its final status. Exits non-zero if the task failed or
+      is still unfinished at the timeout.
+`;
+
+const CAPTURE_HELP = `Capture pipeline test
+
+Usage:
+  dere capture <prompt> [--session=ID] [--personality=NAME] [--from=user|assistant]
+
+Stores <prompt> through the same /conversation/capture path the hooks use and
+prints the session and conversation ids and the background work it started
+(entity extraction, emotion, embeddings), without launching Claude. Without
+--session a new session tagged "capture" is created in the current directory.
+Follow the work with "dere queue tail -f" or the daemon log.
+`;
+
+const IMPORT_HELP = `Transcript import
+
+Usage:
+  dere import <transcript.jsonl>... [--project=PATH] [--personality=NAME]
+
+Loads Claude Code session transcripts (~/.claude/projects/*/*.jsonl) into
+dere, one session per file, so history from before dere was set up becomes
+searchable. User and assistant turns keep their original times; tool calls
+and subagent traffic are left out. The project defaults to the directory
+recorded in the transcript. Imported sessions are tagged "import", embedded
+by the background backfill, and summarized. A transcript that was already
+imported is skipped.
+`;
+
+const CONTEXT_HELP = `Session context inspection
+
+Usage:
+  dere context show <session-id> [--format=text|json]
+
+show  Prints the context the daemon cached for a session: the prompt context
+      with its rough token count, the entities it drew on ranked by relevance,
+      and the session-start context, along with when it was built and whether
+      it is still fresh ([context].cache_ttl_minutes, no newer messages).
+`;
+
+const SEARCH_HELP = `Semantic search over past conversations
+
+Usage:
+  dere search <query> [--limit=N] [--project=PATH] [--min-similarity=X]
+              [--from=user|assistant] [--context=N]
+
+Prints the closest matching messages with similarity, session id, date, and
+a snippet. --project restricts to sessions started in PATH; --min-similarity
+(0-1) drops weak matches; --from=assistant searches only Claude's replies
+("what did Claude tell me about X"), --from=user only your own messages.
+--context=N (max 5) also prints the N turns before and after each match in
+its session, so short replies come with the exchange around them.
+`;
+
+const SUMMARIES_HELP = `Session summaries
+
+Usage:
+  dere summaries list [--limit=N] [--tag=TAG]
+  dere summaries search <query> [--limit=N]
+  dere summaries regenerate <session-id> [--max-words=N]
+                            [--style=brief|detailed|decisions] [--replace]
+
+list        Prints the most recent session summaries, optionally only for
+            sessions carrying TAG.
+search      Ranks stored session summaries by semantic similarity to <query>
+            and prints the best matches with their session details.
+regenerate  Summarizes a session again from its conversations at a new length
+            or style (brief: one-line TL;DR, detailed: a fuller account,
+            decisions: Discussed / Decided / To-do, drawn mostly from the
+            assistant's replies) and prints the result. It is stored as a
+            variant next to the original; --replace makes it the session's
+            summary instead.
+`;
+
+const TODOS_HELP = `Follow-ups from sessions
+
+Usage:
+  dere todos [--project=PATH] [--open|--done|--all] [--limit=N]
+  dere todos done <id>
+  dere todos reopen <id>
+
+When a session is summarized, the things left to do in it ("add tests for
+X", "refactor Y") are extracted into a list kept across sessions. dere todos
+shows the open ones, newest first; --project limits them to one project,
+--done and --all show finished items too. done and reopen mark an item by
+the #id shown in the list. Turn extraction off with [context].action_items.
+`;
+
+const STATS_HELP = `Session cost statistics
+
+Usage:
+  dere stats [--days=N] [--tag=TAG]
+
+Reports spend recorded from the statusline over the last N days (default 30),
+optionally only for sessions carrying TAG.
+`;
+
+const TOPICS_HELP = `Session topics
+
+Usage:
+  dere topics [--days=N] [--clusters=K] [--examples=N]
+
+Groups sessions active in the last N days (default 90) by what their
+summaries are about, using the summary embeddings, and prints each group
+with its session count, the projects it spans, and the summaries nearest
+its centre (--examples, default 3). The number of groups grows with the
+number of sessions unless --clusters fixes it (at most 12). Sessions whose
+summaries haven't been embedded yet are left out.
+`;
+
+const CLEANUP_HELP = `Old session cleanup
+
+Usage:
+  dere cleanup --older-than=90d [--dry-run]
+
+Deletes sessions with no activity in the given number of days, along with
+their conversations, blocks, and session-scoped rows, then reports what was
+removed. --dry-run reports the same numbers without deleting anything. The
+daemon runs this daily when [database].session_retention_days is set.
+Pinned sessions (dere sessions pin) are always kept.
+`;
+
+const PURGE_HELP = `Session purge
+
+Usage:
+  dere purge <session-id> [--yes]
+
+Deletes a session and everything stored under its id in one transaction:
+conversations and their blocks, entities, summaries and summary variants,
+the context cache, tags, costs, action items, tool events, queued tasks,
+and any cross-session summary built from it. Its knowledge graph episodes go
+too, along with entities no other episode mentions. Episodes ingested before
+they were tagged with a session can't be found this way; remove those with
+dere entities delete. Pinned sessions are purged too. Without --yes it only
+lists what would be deleted.
+`;
+
+const REPROCESS_HELP = `Reprocess stored conversations
+
+Usage:
+  dere reprocess --type=entities|summary|embeddings (--session=ID | --all)
+
+Re-runs one pipeline stage over sessions that are already stored, e.g. after
+changing the extraction prompt or model. The daemon works through them in the
+background; watch its log for progress.
+
+entities    Clears the graph episodes and unverified entities left only by
+            those messages, then extracts entities again.
+summary     Regenerates the session summary.
+embeddings  Drops the stored vectors; the backfill loop re-embeds them.
 `;
 
 function getDataDir(): string {
@@ -58,8 +346,8 @@ function pidPath(): string {
 async function daemonStatus(): Promise<void> {
   const controller = new AbortController();
   const timeout = setTimeout(() => controller.abort(), 2000);
+  const daemonUrl = await resolveDaemonUrl();
   try {
-    const daemonUrl = await resolveDaemonUrl();
     const response = await fetch(`${daemonUrl}/health`, {
       signal: controller.signal,
     });
@@ -71,21 +359,76 @@ async function daemonStatus(): Promise<void> {
     console.log("Daemon is running");
     console.log(`  DereGraph: ${String(data.dere_graph ?? "unknown")}`);
     console.log(`  Claude auth: ${String(data.claude_auth ?? "unknown")}`);
-  } catch {
-    console.error("Daemon is not running");
+
+    const statusResponse = await fetch(`${daemonUrl}/status/get`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: "{}",
+      signal: controller.signal,
+    });
+    if (statusResponse.ok) {
+      const status = (await statusResponse.json()) as {
+        queue?: Record<string, number>;
+        last_processed_at?: string | null;
+      };
+      const queue = status.queue ?? {};
+      console.log(
+        `  Queue: ${queue.pending ?? 0} pending, ${queue.processing ?? 0} processing, ${queue.failed ?? 0} failed`,
+      );
+      const lastProcessed = status.last_processed_at
+        ? new Date(status.last_processed_at).toLocaleString()
+        : "never";
+      console.log(`  Last processed: ${lastProcessed}`);
+    }
+  } catch (error) {
+    const reason =
+      error instanceof Error && error.name === "AbortError" ? "timed out" : "not reachable";
+    console.error(`Daemon is not running (${daemonUrl} ${reason})`);
+    console.error("Start it with: dere daemon start");
     process.exit(1);
   } finally {
     clearTimeout(timeout);
   }
 }
 
+function isProcessAlive(pid: number): boolean {
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    return (error as NodeJS.ErrnoException).code === "EPERM";
+  }
+}
+
+/** PID from the daemon's PID file, or null when there is none or it's unreadable. */
+async function readDaemonPid(): Promise<number | null> {
+  try {
+    const pid = Number((await readFile(pidPath(), "utf-8")).trim());
+    return Number.isInteger(pid) && pid > 0 ? pid : null;
+  } catch {
+    return null;
+  }
+}
+
+async function removeStalePidFile(): Promise<void> {
+  try {
+    await unlink(pidPath());
+  } catch {
+    // ignore
+  }
+}
+
 async function daemonStart(): Promise<void> {
-  const path = pidPath();
-  if (existsSync(path)) {
-    console.error("Daemon appears to be running (PID file exists)");
-    console.error("Use 'dere daemon status' to verify");
+  const pid = await readDaemonPid();
+  if (pid !== null && isProcessAlive(pid)) {
+    console.error(`Daemon is already running (PID ${pid})`);
+    console.error("Use 'dere daemon stop' or 'dere daemon restart'");
     process.exit(1);
   }
+  if (existsSync(pidPath())) {
+    // Left behind by a daemon that didn't shut down cleanly.
+    await removeStalePidFile();
+  }
 
   const child = spawn("bun", ["packages/daemon/src/index.ts"], {
     stdio: "ignore",
@@ -95,51 +438,379 @@ async function daemonStart(): Promise<void> {
   console.log("Daemon started");
 }
 
-async function daemonStop(): Promise<void> {
-  const path = pidPath();
-  if (!existsSync(path)) {
-    console.error("Daemon is not running (no PID file)");
-    process.exit(1);
+/**
+ * SIGTERM the daemon named in the PID file and wait for it to exit. Returns
+ * false when no daemon was running.
+ */
+async function stopDaemonProcess(timeoutMs = 10_000): Promise<boolean> {
+  const pid = await readDaemonPid();
+  if (pid === null || !isProcessAlive(pid)) {
+    if (existsSync(pidPath())) {
+      await removeStalePidFile();
+    }
+    return false;
   }
+
   try {
-    const pid = Number((await readFile(path, "utf-8")).trim());
-    if (!Number.isFinite(pid)) {
-      throw new Error("Invalid PID");
-    }
     process.kill(pid, "SIGTERM");
-    console.log(`Sent stop signal to daemon (PID ${pid})`);
   } catch (error) {
-    console.error(`Failed to stop daemon: ${String(error)}`);
+    console.error(`Failed to stop daemon (PID ${pid}): ${String(error)}`);
+    process.exit(1);
+  }
+  console.log(`Stopping daemon (PID ${pid})...`);
+  const deadline = Date.now() + timeoutMs;
+  while (isProcessAlive(pid)) {
+    if (Date.now() > deadline) {
+      console.error(`Daemon (PID ${pid}) did not exit within ${timeoutMs / 1000}s`);
+      process.exit(1);
+    }
+    await new Promise((resolve) => setTimeout(resolve, 200));
+  }
+  return true;
+}
+
+async function daemonStop(): Promise<void> {
+  if (!(await stopDaemonProcess())) {
+    console.error("Daemon is not running");
     process.exit(1);
   }
+  console.log("Daemon stopped");
 }
 
 async function daemonRestart(): Promise<void> {
-  const path = pidPath();
-  if (existsSync(path)) {
+  await stopDaemonProcess();
+  await daemonStart();
+  console.log("Daemon restarted");
+}
+
+const SECRET_KEY_PATTERN = /token|secret|password|api_key|apikey/i;
+
+function maskSecrets(value: unknown, key = ""): unknown {
+  if (typeof value === "string") {
+    if (value && SECRET_KEY_PATTERN.test(key)) {
+      return "********";
+    }
+    // Hide credentials embedded in connection URLs.
+    return value.replace(/(\/\/[^:/@\s]+:)[^@\s]+@/, "$1********@");
+  }
+  if (Array.isArray(value)) {
+    return value.map((item) => maskSecrets(item, key));
+  }
+  if (value && typeof value === "object") {
+    return Object.fromEntries(
+      Object.entries(value as Record<string, unknown>).map(([k, v]) => [k, maskSecrets(v, k)]),
+    );
+  }
+  return value;
+}
+
+async function configShow(args: string[]): Promise<void> {
+  const configPath = getConfigPath();
+  if (args.includes("--raw")) {
+    if (!existsSync(configPath)) {
+      console.error(`Config file not found: ${configPath}`);
+      process.exit(1);
+    }
+    console.log(await readFile(configPath, "utf-8"));
+    return;
+  }
+
+  const config = await loadProjectConfig();
+  const projectPath = findProjectConfigPath();
+  console.log("# Effective configuration (secrets masked)");
+  console.log(`# global:  ${configPath}${existsSync(configPath) ? "" : " (missing)"}`);
+  console.log(`# project: ${projectPath ?? "none"}`);
+  console.log(stringify(maskSecrets(config) as Record<string, unknown>));
+}
+
+type ValidationCheck = {
+  name: string;
+  ok: boolean;
+  detail: string;
+  /** Passed, but something is degraded */
+  warn?: boolean;
+  /** Suggested fix, shown for failures and warnings */
+  fix?: string;
+};
+
+function checkTcp(host: string, port: number, timeoutMs = 2000): Promise<string | null> {
+  return new Promise((resolve) => {
+    const socket = createConnection({ host, port });
+    const finish = (error: string | null) => {
+      socket.destroy();
+      resolve(error);
+    };
+    socket.setTimeout(timeoutMs, () => finish("timed out"));
+    socket.once("connect", () => finish(null));
+    socket.once("error", (error) => finish(error.message));
+  });
+}
+
+async function checkConfigFile(path: string, label: string): Promise<ValidationCheck> {
+  try {
+    await loadConfigFile(path);
+    return { name: label, ok: true, detail: path };
+  } catch (error) {
+    const cause = (error as { cause?: unknown }).cause;
+    const issues =
+      cause && typeof cause === "object" && "issues" in cause
+        ? (cause as { issues: Array<{ path: PropertyKey[]; message: string }> }).issues
+            .map((issue) => `${issue.path.map(String).join(".") || "(root)"}: ${issue.message}`)
+            .join("; ")
+        : String(error instanceof Error ? error.message : error);
+    return { name: label, ok: false, detail: `${path}: ${issues}` };
+  }
+}
+
+async function collectConfigChecks(): Promise<ValidationCheck[]> {
+  const checks: ValidationCheck[] = [];
+  const configPath = getConfigPath();
+  if (existsSync(configPath)) {
+    checks.push(await checkConfigFile(configPath, "Global config"));
+  } else {
+    const detail = `${configPath} (missing, using defaults)`;
+    checks.push({ name: "Global config", ok: true, detail });
+  }
+  const projectPath = findProjectConfigPath();
+  if (projectPath) {
+    checks.push(await checkConfigFile(projectPath, "Project config"));
+  }
+
+  const config = await loadProjectConfig();
+
+  let daemonUrl: string | null = null;
+  try {
+    daemonUrl = getDaemonUrlFromConfig(config);
+  } catch (error) {
+    checks.push({ name: "Daemon", ok: false, detail: String((error as Error).message) });
+  }
+  if (daemonUrl) {
     try {
-      const pid = Number((await readFile(path, "utf-8")).trim());
-      if (Number.isFinite(pid)) {
-        process.kill(pid, "SIGTERM");
-        console.log(`Stopping daemon (PID ${pid})...`);
-        await new Promise((resolve) => setTimeout(resolve, 2000));
-      }
+      const response = await fetch(`${daemonUrl}/health`, { signal: AbortSignal.timeout(2000) });
+      checks.push({
+        name: "Daemon",
+        ok: response.ok,
+        detail: response.ok ? daemonUrl : `${daemonUrl} returned ${response.status}`,
+      });
     } catch {
-      // ignore
+      checks.push({
+        name: "Daemon",
+        ok: false,
+        detail: `${daemonUrl} not reachable`,
+        fix: "dere daemon start",
+      });
     }
   }
-  await daemonStart();
-  console.log("Daemon restarted");
+
+  const databaseUrl =
+    process.env.DERE_DATABASE_URL ?? process.env.DATABASE_URL ?? config.database?.url ?? null;
+  if (!databaseUrl) {
+    checks.push({
+      name: "Database",
+      ok: false,
+      detail: "no database.url or DATABASE_URL set",
+      fix: "set [database].url in config.toml",
+    });
+  } else {
+    try {
+      const url = new URL(databaseUrl);
+      const host = url.hostname || "localhost";
+      const port = url.port ? Number(url.port) : 5432;
+      const error = await checkTcp(host, port);
+      checks.push({
+        name: "Database",
+        ok: error === null,
+        detail: `${host}:${port}${error ? ` (${error})` : ""}`,
+        fix: "start PostgreSQL or fix [database].url",
+      });
+    } catch {
+      checks.push({ name: "Database", ok: false, detail: "database.url is not a valid URL" });
+    }
+  }
+
+  const graphConfig = (config.dere_graph ?? {}) as Record<string, unknown>;
+  if (graphConfig.enabled !== false) {
+    const host =
+      typeof graphConfig.falkor_host === "string" ? graphConfig.falkor_host : "localhost";
+    const port = typeof graphConfig.falkor_port === "number" ? graphConfig.falkor_port : 6379;
+    const error = await checkTcp(host, port);
+    checks.push({
+      name: "FalkorDB",
+      ok: error === null,
+      detail: `${host}:${port}${error ? ` (${error})` : ""}`,
+      fix: "just falkordb, or set [dere_graph].enabled = false",
+    });
+    checks.push({
+      name: "Embeddings",
+      ok: Boolean(process.env.OPENAI_API_KEY),
+      detail: process.env.OPENAI_API_KEY ? "OPENAI_API_KEY set" : "OPENAI_API_KEY not set",
+      fix: "export OPENAI_API_KEY",
+    });
+  }
+
+  const dataDir = getDataDir();
+  try {
+    await mkdir(dataDir, { recursive: true });
+    await access(dataDir, constants.W_OK);
+    checks.push({ name: "Data directory", ok: true, detail: dataDir });
+  } catch {
+    checks.push({ name: "Data directory", ok: false, detail: `${dataDir} is not writable` });
+  }
+
+  return checks;
 }
 
-async function configShow(): Promise<void> {
-  const configPath = getConfigPath();
-  if (!existsSync(configPath)) {
-    console.error(`Config file not found: ${configPath}`);
+function printChecks(checks: ValidationCheck[]): void {
+  for (const check of checks) {
+    const label = !check.ok ? "FAIL" : check.warn ? "warn" : "ok  ";
+    console.log(`${label}  ${check.name.padEnd(15)} ${check.detail}`);
+    if ((!check.ok || check.warn) && check.fix) {
+      console.log(`      ${"".padEnd(15)} fix: ${check.fix}`);
+    }
+  }
+  if (checks.some((check) => !check.ok)) {
     process.exit(1);
   }
-  const text = await readFile(configPath, "utf-8");
-  console.log(text);
+}
+
+async function configValidate(): Promise<void> {
+  printChecks(await collectConfigChecks());
+}
+
+function commandCheck(name: string, command: string, fix: string): ValidationCheck {
+  const result = spawnSync(command, ["--version"], { encoding: "utf-8" });
+  if (result.error || result.status !== 0) {
+    return { name, ok: false, detail: `${command} not found in PATH`, fix };
+  }
+  return { name, ok: true, detail: result.stdout.trim().split("\n")[0] ?? command };
+}
+
+function pluginChecks(): ValidationCheck[] {
+  const pluginsPath = findPluginsPath();
+  if (!pluginsPath) {
+    return [
+      {
+        name: "Plugins",
+        ok: false,
+        detail: "plugin marketplace not found",
+        fix: "run dere from the repo checkout or reinstall with `just install`",
+      },
+    ];
+  }
+
+  const checks: ValidationCheck[] = [{ name: "Plugins", ok: true, detail: pluginsPath }];
+  const coreRoot = join(pluginsPath, "dere_core");
+  const hooksFile = join(coreRoot, "hooks", "hooks.json");
+  const missing = new Set<string>();
+  try {
+    const hooks = JSON.parse(readFileSync(hooksFile, "utf-8")) as {
+      hooks?: Record<string, Array<{ hooks?: Array<{ command?: string }> }>>;
+    };
+    for (const matchers of Object.values(hooks.hooks ?? {})) {
+      for (const hook of matchers.flatMap((matcher) => matcher.hooks ?? [])) {
+        const script = hook.command?.match(/\$\{CLAUDE_PLUGIN_ROOT\}(\S+)/)?.[1];
+        if (script && !existsSync(join(coreRoot, script))) {
+          missing.add(script);
+        }
+      }
+    }
+    checks.push({
+      name: "Hook scripts",
+      ok: missing.size === 0,
+      detail: missing.size === 0 ? hooksFile : `missing: ${[...missing].join(", ")}`,
+      fix: "restore plugins/dere_core/hooks from the repo",
+    });
+  } catch (error) {
+    checks.push({
+      name: "Hook scripts",
+      ok: false,
+      detail: `${hooksFile}: ${String((error as Error).message)}`,
+      fix: "restore plugins/dere_core/hooks/hooks.json from the repo",
+    });
+  }
+
+  const statusline = join(coreRoot, "scripts", "dere-statusline.ts");
+  const hasStatusline = existsSync(statusline);
+  checks.push({
+    name: "Statusline",
+    ok: true,
+    warn: !hasStatusline,
+    detail: hasStatusline ? statusline : `${statusline} missing; statusline disabled`,
+    fix: "restore plugins/dere_core/scripts/dere-statusline.ts from the repo",
+  });
+  return checks;
+}
+
+async function daemonDatabaseChecks(): Promise<ValidationCheck[]> {
+  const daemonUrl = await resolveDaemonUrl();
+  const checks: ValidationCheck[] = [];
+  try {
+    const health = await fetch(`${daemonUrl}/health`, { signal: AbortSignal.timeout(2000) });
+    const data = (await health.json()) as Record<string, unknown>;
+    const authOk = data.claude_auth === "ok";
+    checks.push({
+      name: "Claude auth",
+      ok: authOk,
+      detail: `daemon reports ${String(data.claude_auth ?? "unknown")}`,
+      fix: "run `claude` and log in, then POST /auth/reset",
+    });
+
+    const response = await fetch(`${daemonUrl}/health/database`, {
+      signal: AbortSignal.timeout(5000),
+    });
+    const schema = (await response.json()) as {
+      error?: string;
+      pending?: string[];
+      unknown?: string[];
+    };
+    if (!response.ok) {
+      checks.push({
+        name: "Schema",
+        ok: false,
+        detail: schema.error ?? `daemon returned ${response.status}`,
+        fix: "check the daemon can reach PostgreSQL",
+      });
+    } else if ((schema.unknown ?? []).length > 0) {
+      checks.push({
+        name: "Schema",
+        ok: false,
+        detail: `database is newer than this dere (${(schema.unknown ?? []).join(", ")})`,
+        fix: "upgrade dere",
+      });
+    } else {
+      const pending = schema.pending ?? [];
+      checks.push({
+        name: "Schema",
+        ok: pending.length === 0,
+        detail: pending.length === 0 ? "up to date" : `pending: ${pending.join(", ")}`,
+        fix: "cd packages/daemon && bun run migrate",
+      });
+    }
+  } catch {
+    // Daemon reachability is already reported by the config checks.
+  }
+
+  const udsPath = process.env.DERE_DAEMON_UDS;
+  if (udsPath) {
+    checks.push({
+      name: "Daemon socket",
+      ok: existsSync(udsPath),
+      detail: existsSync(udsPath) ? udsPath : `${udsPath} missing`,
+      fix: "restart the daemon with DERE_DAEMON_UDS set",
+    });
+  }
+  return checks;
+}
+
+async function doctor(): Promise<void> {
+  const checks = [
+    commandCheck("Claude CLI", "claude", "npm install -g @anthropic-ai/claude-code"),
+    commandCheck("Bun", "bun", "install bun from https://bun.sh (hooks run under bun)"),
+    ...pluginChecks(),
+    ...(await collectConfigChecks()),
+    ...(await daemonDatabaseChecks()),
+  ];
+  printChecks(checks);
 }
 
 function configPath(): void {
@@ -156,42 +827,1737 @@ function configEdit(): void {
   }
 }
 
-export async function runSubcommand(args: string[]): Promise<void> {
-  if (args.length === 0 || args[0] === "--help" || args[0] === "-h") {
-    console.log(MAIN_HELP.trim());
-    return;
+function parseLimitFlag(args: string[]): number | null {
+  for (let i = 0; i < args.length; i += 1) {
+    const arg = args[i] ?? "";
+    let value: string | undefined;
+    if (arg.startsWith("--limit=")) {
+      value = arg.slice("--limit=".length);
+    } else if (arg === "--limit") {
+      value = args[i + 1];
+    }
+    if (value !== undefined) {
+      const parsed = Number.parseInt(value, 10);
+      if (!Number.isFinite(parsed) || parsed <= 0) {
+        console.error(`Invalid --limit value: ${value}`);
+        process.exit(1);
+      }
+      return parsed;
+    }
   }
+  return null;
+}
 
-  const [command, ...rest] = args;
-  if (command === "version") {
-    console.log("dere 0.1.0");
-    return;
-  }
-  if (command === "daemon") {
-    const sub = rest[0];
-    if (!sub || sub === "--help" || sub === "-h") {
-      console.log(DAEMON_HELP.trim());
-      return;
-    }
-    if (sub === "status") {
-      await daemonStatus();
-      return;
+// How long to wait out a backfill the daemon is already running: 30 x 2s.
+const BACKFILL_BUSY_RETRIES = 30;
+const BACKFILL_BUSY_WAIT_MS = 2000;
+
+async function embeddingsBackfill(args: string[]): Promise<void> {
+  const limit = parseLimitFlag(args);
+  const daemonUrl = await resolveDaemonUrl();
+  const batchSize = 50;
+  let processed = 0;
+  let remaining: number | null = null;
+  let busyRetries = 0;
+
+  while (limit === null || processed < limit) {
+    const requested = limit === null ? batchSize : Math.min(batchSize, limit - processed);
+    let response: Response;
+    try {
+      response = await fetch(`${daemonUrl}/embeddings/backfill`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ limit: requested }),
+      });
+    } catch {
+      console.error("Daemon is not running");
+      process.exit(1);
     }
-    if (sub === "start") {
-      await daemonStart();
-      return;
+
+    if (response.status === 409) {
+      busyRetries += 1;
+      if (busyRetries > BACKFILL_BUSY_RETRIES) {
+        console.error("Another embedding backfill is still running; try again later");
+        process.exit(1);
+      }
+      await new Promise((resolve) => setTimeout(resolve, BACKFILL_BUSY_WAIT_MS));
+      continue;
     }
-    if (sub === "stop") {
-      await daemonStop();
-      return;
+    busyRetries = 0;
+    const data = (await response.json()) as Record<string, unknown>;
+    if (!response.ok) {
+      console.error(`Backfill failed: ${String(data.error ?? response.statusText)}`);
+      process.exit(1);
     }
-    if (sub === "restart") {
-      await daemonRestart();
-      return;
+
+    const embedded = Number(data.embedded ?? 0);
+    const seeded = Number(data.seeded ?? 0);
+    remaining = Number(data.remaining ?? 0);
+    processed += embedded;
+    console.log(`Embedded ${processed} conversation blocks (${remaining} remaining)`);
+
+    if (remaining === 0 || (embedded === 0 && seeded === 0)) {
+      break;
     }
-    console.log(DAEMON_HELP.trim());
+  }
+
+  if (remaining !== null && remaining > 0) {
+    console.log(`Backfill stopped with ${remaining} conversation blocks still unembedded`);
+    return;
+  }
+  console.log("Backfill complete");
+}
+
+async function embeddingsReindex(): Promise<void> {
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/embeddings/reindex`, { method: "POST" });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as Record<string, unknown>;
+  if (!response.ok) {
+    console.error(`Reindex failed: ${String(data.error ?? response.statusText)}`);
+    process.exit(1);
+  }
+  console.log(`Rebuilt vector index (${String(data.metric)})`);
+}
+
+function readFlag(args: string[], name: string): string | null {
+  for (let i = 0; i < args.length; i += 1) {
+    const arg = args[i] ?? "";
+    if (arg.startsWith(`${name}=`)) {
+      return arg.slice(name.length + 1);
+    }
+    if (arg === name && args[i + 1] !== undefined) {
+      return args[i + 1] ?? null;
+    }
+  }
+  return null;
+}
+
+type QueueTask = {
+  id: number;
+  task_type: string;
+  status: string;
+  created_at: string;
+  processed_at: string | null;
+  error_message: string | null;
+};
+
+const QUEUE_POLL_MS = 1000;
+// Re-read a little before the last poll so rows committed late aren't missed;
+// transitions already printed are skipped.
+const QUEUE_POLL_OVERLAP_MS = 2000;
+
+function formatSeconds(ms: number): string {
+  return `${(Math.max(ms, 0) / 1000).toFixed(1)}s`;
+}
+
+function describeQueueTask(task: QueueTask, previous: QueueTask | undefined): string {
+  const created = Date.parse(task.created_at);
+  const processed = task.processed_at ? Date.parse(task.processed_at) : null;
+  const time = new Date(processed ?? created).toLocaleTimeString();
+  const transition = previous ? `${previous.status} → ${task.status}` : task.status;
+
+  let timing = "";
+  if (processed !== null && task.status !== "pending") {
+    const startedAt =
+      previous?.processed_at && previous.status !== "pending"
+        ? Date.parse(previous.processed_at)
+        : null;
+    if (task.status === "completed" || task.status === "failed") {
+      timing =
+        startedAt !== null
+          ? ` (took ${formatSeconds(processed - startedAt)})`
+          : ` (${formatSeconds(processed - created)} after queued)`;
+    } else {
+      timing = ` (waited ${formatSeconds(processed - created)})`;
+    }
+  }
+  const error = task.status === "failed" && task.error_message ? `: ${task.error_message}` : "";
+  return `${time}  #${task.id} ${task.task_type}  ${transition}${timing}${error}`;
+}
+
+async function fetchQueueTasks(
+  daemonUrl: string,
+  params: URLSearchParams,
+): Promise<{ tasks: QueueTask[]; now: string }> {
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/queue/tasks?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+  const data = (await response.json()) as { error?: string; tasks?: QueueTask[]; now?: string };
+  if (!response.ok) {
+    console.error(`Failed to read queue: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+  return { tasks: data.tasks ?? [], now: data.now ?? new Date().toISOString() };
+}
+
+async function queueTail(args: string[]): Promise<void> {
+  const type = readFlag(args, "--type");
+  const limit = parseLimitFlag(args) ?? 20;
+  const follow = args.includes("--follow") || args.includes("-f");
+  const daemonUrl = await resolveDaemonUrl();
+
+  const params = new URLSearchParams({ limit: String(limit) });
+  if (type) {
+    params.set("type", type);
+  }
+  const initial = await fetchQueueTasks(daemonUrl, params);
+  const seen = new Map<number, QueueTask>();
+  for (const task of initial.tasks) {
+    console.log(describeQueueTask(task, undefined));
+    seen.set(task.id, task);
+  }
+  if (!follow) {
+    if (initial.tasks.length === 0) {
+      console.log(type ? `No ${type} tasks` : "No tasks");
+    }
+    return;
+  }
+
+  let since = initial.now;
+  while (true) {
+    await new Promise((resolve) => setTimeout(resolve, QUEUE_POLL_MS));
+    params.set("since", new Date(Date.parse(since) - QUEUE_POLL_OVERLAP_MS).toISOString());
+    params.set("limit", "500");
+    const update = await fetchQueueTasks(daemonUrl, params);
+    for (const task of update.tasks) {
+      const previous = seen.get(task.id);
+      if (previous?.status === task.status) {
+        continue;
+      }
+      console.log(describeQueueTask(task, previous));
+      seen.set(task.id, task);
+    }
+    since = update.now;
+  }
+}
+
+async function capture(args: string[]): Promise<void> {
+  const valueFlags = new Set(["--session", "--personality", "--from"]);
+  const prompt = args
+    .filter((arg, i) => !arg.startsWith("--") && !valueFlags.has(args[i - 1] ?? ""))
+    .join(" ")
+    .trim();
+  if (!prompt) {
+    console.error("Usage: dere capture <prompt> [--session=ID] [--personality=NAME]");
+    process.exit(1);
+  }
+  const sessionRaw = readFlag(args, "--session");
+  const sessionId = sessionRaw === null ? randomInt(1, 2 ** 31 - 1) : Number(sessionRaw);
+  if (!Number.isInteger(sessionId) || sessionId <= 0) {
+    console.error(`Invalid --session value: ${sessionRaw}`);
+    process.exit(1);
+  }
+  const from = readFlag(args, "--from") ?? "user";
+  if (from !== "user" && from !== "assistant") {
+    console.error(`Invalid --from value: ${from} (use user or assistant)`);
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/conversation/capture`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        session_id: sessionId,
+        personality: readFlag(args, "--personality") ?? "tsun",
+        project_path: process.cwd(),
+        prompt,
+        message_type: from,
+        medium: "cli",
+        user_id: process.env.USER ?? process.env.USERNAME ?? "default",
+        tags: sessionRaw === null ? ["capture"] : [],
+      }),
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    status?: string;
+    conversation_id?: number;
+    background?: string[];
+  };
+  if (!response.ok) {
+    console.error(`Capture failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+  console.log(`Session:      ${sessionId}`);
+  console.log(`Conversation: ${data.conversation_id ?? "-"} (${data.status ?? "unknown"})`);
+  const background = data.background ?? [];
+  console.log(`Background:   ${background.length > 0 ? background.join(", ") : "none"}`);
+}
+
+async function importTranscripts(args: string[]): Promise<void> {
+  const valueFlags = new Set(["--project", "--personality"]);
+  const paths = args.filter(
+    (arg, i) => !arg.startsWith("--") && !valueFlags.has(args[i - 1] ?? ""),
+  );
+  if (paths.length === 0) {
+    console.error("Usage: dere import <transcript.jsonl>... [--project=PATH] [--personality=NAME]");
+    process.exit(1);
+  }
+  const project = readFlag(args, "--project");
+  const personality = readFlag(args, "--personality");
+
+  const daemonUrl = await resolveDaemonUrl();
+  let failed = 0;
+  for (const path of paths) {
+    let transcript: ReturnType<typeof parseClaudeTranscript>;
+    try {
+      transcript = parseClaudeTranscript(await readFile(path, "utf-8"));
+    } catch (error) {
+      console.error(`${path}: ${error instanceof Error ? error.message : String(error)}`);
+      failed += 1;
+      continue;
+    }
+    if (transcript.turns.length === 0) {
+      console.log(`${path}: no conversation turns, skipped`);
+      continue;
+    }
+    const workingDir = project ? resolvePath(project) : transcript.cwd;
+    if (!workingDir) {
+      console.error(`${path}: no working directory recorded; pass --project`);
+      failed += 1;
+      continue;
+    }
+
+    let response: Response;
+    try {
+      response = await fetch(`${daemonUrl}/sessions/import`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          working_dir: workingDir,
+          personality,
+          claude_session_id: transcript.claudeSessionId,
+          turns: transcript.turns,
+        }),
+      });
+    } catch {
+      console.error("Daemon is not running");
+      process.exit(1);
+    }
+    const data = (await response.json()) as {
+      error?: string;
+      session_id?: number;
+      imported?: number;
+    };
+    if (response.status === 409) {
+      console.log(`${path}: already imported as #${data.session_id ?? "?"}, skipped`);
+      continue;
+    }
+    if (!response.ok) {
+      console.error(`${path}: ${data.error ?? response.statusText}`);
+      failed += 1;
+      continue;
+    }
+    console.log(`${path}: #${data.session_id} (${data.imported ?? 0} turns)`);
+  }
+  if (failed > 0) {
+    process.exit(1);
+  }
+}
+
+type ContextCacheView = {
+  session_id: number;
+  context: string;
+  metadata: Record<string, unknown>;
+  created_at: string | null;
+  ttl_minutes: number;
+  stale: string | null;
+};
+
+async function contextShow(args: string[]): Promise<void> {
+  const format = readFormatFlag(args);
+  const sessionId = Number(args.find((arg) => !arg.startsWith("--")));
+  if (!Number.isInteger(sessionId) || sessionId <= 0) {
+    console.error("Usage: dere context show <session-id> [--format=text|json]");
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/context/cache/${sessionId}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as ContextCacheView & { error?: string };
+  if (!response.ok) {
+    console.error(data.error ?? response.statusText);
+    process.exit(1);
+  }
+  if (format === "json") {
+    console.log(JSON.stringify(data, null, 2));
+    return;
+  }
+
+  const meta = data.metadata;
+  const built = data.created_at ? new Date(data.created_at).toLocaleString() : "unknown";
+  const freshness = data.stale ? `stale: ${data.stale}` : `fresh, ttl ${data.ttl_minutes}m`;
+  console.log(`Session #${data.session_id}  built ${built}  (${freshness})`);
+
+  const context = data.context.trim();
+  if (context) {
+    const edges = Array.isArray(meta.edges) ? meta.edges.length : 0;
+    let charsPerToken: number | undefined;
+    try {
+      charsPerToken = contextCharsPerToken(await loadConfig());
+    } catch {
+      charsPerToken = undefined;
+    }
+    const tokens = estimateTokens(context, charsPerToken);
+    console.log(`\nPrompt context (~${tokens} tokens, ${edges} facts):`);
+    console.log(context);
+  } else {
+    console.log("\nNo prompt context built yet");
+  }
+
+  const entities = (Array.isArray(meta.entities) ? meta.entities : []) as Array<{
+    name?: string;
+    relevance?: number;
+  }>;
+  if (entities.length > 0) {
+    const halfLife =
+      typeof meta.recency_half_life_days === "number"
+        ? ` (recency half-life ${meta.recency_half_life_days}d)`
+        : "";
+    console.log(`\nSources${halfLife}:`);
+    const ranked = [...entities].sort((a, b) => (b.relevance ?? -1) - (a.relevance ?? -1));
+    for (const entity of ranked) {
+      const score = typeof entity.relevance === "number" ? entity.relevance.toFixed(2) : "  - ";
+      console.log(`  ${score}  ${entity.name ?? "?"}`);
+    }
+  }
+
+  if (meta.session_start_queried) {
+    const queriedAt =
+      typeof meta.query_timestamp === "number"
+        ? new Date(meta.query_timestamp * 1000).toLocaleString()
+        : "unknown";
+    const sessionType = typeof meta.session_type === "string" ? meta.session_type : "unknown";
+    const startText =
+      typeof meta.session_start_results === "string" ? meta.session_start_results.trim() : "";
+    console.log(`\nSession-start context (${sessionType}, queried ${queriedAt}):`);
+    console.log(startText || "(empty)");
+  }
+}
+
+async function queueWait(args: string[]): Promise<void> {
+  const taskId = Number(args.find((arg) => !arg.startsWith("--")));
+  if (!Number.isInteger(taskId) || taskId <= 0) {
+    console.error("Usage: dere queue wait <task-id> [--timeout=SECONDS]");
+    process.exit(1);
+  }
+  const timeoutRaw = readFlag(args, "--timeout");
+  const timeoutSeconds = timeoutRaw === null ? 30 : Number(timeoutRaw);
+  if (!Number.isFinite(timeoutSeconds) || timeoutSeconds < 0) {
+    console.error(`Invalid --timeout value: ${timeoutRaw}`);
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  const params = new URLSearchParams({ timeout_ms: String(Math.round(timeoutSeconds * 1000)) });
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/queue/tasks/${taskId}/wait?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    task?: { id: number; task_type: string; status: string; error_message: string | null };
+    timed_out?: boolean;
+  };
+  if (!response.ok || !data.task) {
+    console.error(data.error ?? response.statusText);
+    process.exit(1);
+  }
+  const task = data.task;
+  const suffix = task.error_message ? `: ${task.error_message}` : "";
+  if (data.timed_out) {
+    console.log(`#${task.id} ${task.task_type} still ${task.status} after ${timeoutSeconds}s`);
+    process.exit(1);
+  }
+  console.log(`#${task.id} ${task.task_type} ${task.status}${suffix}`);
+  if (task.status !== "completed") {
+    process.exit(1);
+  }
+}
+
+async function searchConversations(args: string[]): Promise<void> {
+  const valueFlags = new Set(["--limit", "--project", "--min-similarity", "--from", "--context"]);
+  const query = args
+    .filter((arg, i) => !arg.startsWith("--") && !valueFlags.has(args[i - 1] ?? ""))
+    .join(" ")
+    .trim();
+  if (!query) {
+    console.error("Usage: dere search <query> [--limit=N] [--project=PATH] [--min-similarity=X]");
+    process.exit(1);
+  }
+  const limit = parseLimitFlag(args) ?? 10;
+  const project = readFlag(args, "--project");
+  const minRaw = readFlag(args, "--min-similarity");
+  const minSimilarity = minRaw === null ? null : Number(minRaw);
+  if (minSimilarity !== null && !Number.isFinite(minSimilarity)) {
+    console.error(`Invalid --min-similarity value: ${minRaw}`);
+    process.exit(1);
+  }
+  const from = readFlag(args, "--from");
+  if (from !== null && from !== "user" && from !== "assistant") {
+    console.error(`Invalid --from value: ${from} (use user or assistant)`);
+    process.exit(1);
+  }
+  const contextRaw = readFlag(args, "--context");
+  const contextWindow = contextRaw === null ? 0 : Number(contextRaw);
+  if (!Number.isInteger(contextWindow) || contextWindow < 0) {
+    console.error(`Invalid --context value: ${contextRaw}`);
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/search/conversations`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        query,
+        limit,
+        working_dir: project ? resolvePath(project) : null,
+        min_similarity: minSimilarity,
+        message_type: from,
+        context_window: contextWindow,
+      }),
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    results?: Array<{
+      text: string | null;
+      session_id: number;
+      message_type: string;
+      timestamp: number;
+      similarity: number;
+      conversation_id: number;
+      context?: Array<{ conversation_id: number; message_type: string; prompt: string }>;
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Search failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const results = data.results ?? [];
+  if (results.length === 0) {
+    console.log("No matching conversations");
+    return;
+  }
+  for (const result of results) {
+    const date = new Date(result.timestamp * 1000).toISOString().slice(0, 10);
+    const text = (result.text ?? "").replace(/\s+/g, " ").trim();
+    const snippet = text.length > 100 ? `${text.slice(0, 100)}...` : text;
+    const score = Number(result.similarity).toFixed(2);
+    console.log(`${score}  #${result.session_id}  ${date}  ${result.message_type}: ${snippet}`);
+    for (const turn of result.context ?? []) {
+      const turnText = turn.prompt.replace(/\s+/g, " ").trim();
+      const turnSnippet = turnText.length > 100 ? `${turnText.slice(0, 100)}...` : turnText;
+      const marker = turn.conversation_id === result.conversation_id ? ">" : " ";
+      console.log(`    ${marker} ${turn.message_type}: ${turnSnippet}`);
+    }
+  }
+}
+
+async function summariesList(args: string[]): Promise<void> {
+  const limit = parseLimitFlag(args) ?? 10;
+  const tag = readFlag(args, "--tag");
+  const params = new URLSearchParams({ limit: String(limit) });
+  if (tag) {
+    params.set("tag", tag);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/sessions/summaries?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    sessions?: Array<{
+      id: number;
+      name: string | null;
+      working_dir: string;
+      personality: string | null;
+      start_time: number;
+      summary: string;
+      tags: string[];
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Failed to list summaries: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const sessions = data.sessions ?? [];
+  if (sessions.length === 0) {
+    console.log(tag ? `No summaries for sessions tagged '${tag}'` : "No summaries");
+    return;
+  }
+  for (const session of sessions) {
+    const started = new Date(session.start_time * 1000).toLocaleString();
+    const label = session.name ? ` ${session.name}` : "";
+    const personality = session.personality ? ` [${session.personality}]` : "";
+    const tags = session.tags.length > 0 ? `  #${session.tags.join(" #")}` : "";
+    console.log(`#${session.id}${label}${personality}  ${started}${tags}`);
+    console.log(`   ${session.working_dir}`);
+    console.log(`   ${session.summary}`);
+  }
+}
+
+async function summariesRegenerate(args: string[]): Promise<void> {
+  const sessionId = Number(args.find((arg) => !arg.startsWith("--")));
+  if (!Number.isInteger(sessionId) || sessionId <= 0) {
+    console.error("Usage: dere summaries regenerate <session-id> [--max-words=N] [--style=NAME]");
+    process.exit(1);
+  }
+  const maxWordsRaw = readFlag(args, "--max-words");
+  const maxWords = maxWordsRaw === null ? undefined : Number(maxWordsRaw);
+  if (maxWords !== undefined && (!Number.isInteger(maxWords) || maxWords <= 0)) {
+    console.error(`Invalid --max-words value: ${maxWordsRaw}`);
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/sessions/${sessionId}/summary/regenerate`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        max_words: maxWords,
+        style: readFlag(args, "--style") ?? undefined,
+        replace: args.includes("--replace"),
+      }),
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    summary?: string;
+    summary_type?: string;
+    max_words?: number;
+    replaced?: boolean;
+  };
+  if (!response.ok) {
+    console.error(`Regenerate failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+  const where = data.replaced ? "replaced the session summary" : "saved as a variant";
+  console.log(`#${sessionId} ${data.summary_type} (≤${data.max_words} words, ${where})`);
+  for (const line of (data.summary ?? "").split("\n")) {
+    console.log(`   ${line}`);
+  }
+}
+
+async function summariesSearch(args: string[]): Promise<void> {
+  const limit = parseLimitFlag(args) ?? 10;
+  const query = args
+    .filter((arg, i) => !arg.startsWith("--") && args[i - 1] !== "--limit")
+    .join(" ")
+    .trim();
+  if (!query) {
+    console.error("Usage: dere summaries search <query> [--limit=N]");
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/search/summaries`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ query, limit }),
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    results?: Array<{
+      id: number;
+      name: string | null;
+      working_dir: string;
+      personality: string | null;
+      start_time: number;
+      summary: string | null;
+      similarity: number;
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Summary search failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const results = data.results ?? [];
+  if (results.length === 0) {
+    console.log("No matching summaries");
+    return;
+  }
+  results.forEach((session, index) => {
+    const started = new Date(session.start_time * 1000).toLocaleString();
+    const label = session.name ? ` ${session.name}` : "";
+    const personality = session.personality ? ` [${session.personality}]` : "";
+    const score = Number(session.similarity).toFixed(2);
+    console.log(`${index + 1}. #${session.id}${label}${personality}  ${started}  (${score})`);
+    console.log(`   ${session.working_dir}`);
+    console.log(`   ${session.summary ?? "(no summary)"}`);
+  });
+}
+
+const ANSI_COLORS: Record<string, string> = {
+  red: "\u001b[31m",
+  green: "\u001b[32m",
+  yellow: "\u001b[93m",
+  blue: "\u001b[34m",
+  magenta: "\u001b[35m",
+  cyan: "\u001b[36m",
+  gray: "\u001b[90m",
+  white: "\u001b[97m",
+};
+const ANSI_RESET = "\u001b[0m";
+
+type EntityTypeStyle = { color: string; icon: string };
+
+const DEFAULT_ENTITY_STYLES: Record<string, EntityTypeStyle> = {
+  person: { color: "green", icon: "👤" },
+  user: { color: "green", icon: "👤" },
+  assistant: { color: "magenta", icon: "✦" },
+  technology: { color: "cyan", icon: "⚙" },
+  project: { color: "blue", icon: "◆" },
+  file: { color: "yellow", icon: "📄" },
+  organization: { color: "magenta", icon: "🏢" },
+  location: { color: "red", icon: "📍" },
+};
+const FALLBACK_ENTITY_STYLE: EntityTypeStyle = { color: "gray", icon: "●" };
+
+/** Built-in styles overlaid with [dere_graph.entity_display], keyed by lowercase type. */
+async function loadEntityStyles(): Promise<Record<string, EntityTypeStyle>> {
+  const styles = { ...DEFAULT_ENTITY_STYLES };
+  try {
+    const config = await loadConfig();
+    const graphConfig = (config.dere_graph ?? {}) as Record<string, unknown>;
+    const display = (graphConfig.entity_display ?? {}) as Record<string, unknown>;
+    for (const [type, raw] of Object.entries(display)) {
+      const entry = (raw ?? {}) as Record<string, unknown>;
+      const base = styles[type.toLowerCase()] ?? FALLBACK_ENTITY_STYLE;
+      styles[type.toLowerCase()] = {
+        color: typeof entry.color === "string" ? entry.color : base.color,
+        icon: typeof entry.icon === "string" ? entry.icon : base.icon,
+      };
+    }
+  } catch {
+    // built-in styles only
+  }
+  return styles;
+}
+
+function paint(text: string, color: string): string {
+  // https://no-color.org
+  if (process.env.NO_COLOR || !process.stdout.isTTY) {
+    return text;
+  }
+  const code = ANSI_COLORS[color.toLowerCase()];
+  return code ? `${code}${text}${ANSI_RESET}` : text;
+}
+
+type OutputFormat = "text" | "json" | "dot";
+
+function readFormatFlag(
+  args: string[],
+  allowed: OutputFormat[] = ["text", "json"],
+): OutputFormat {
+  const format = readFlag(args, "--format") ?? "text";
+  if (!allowed.includes(format as OutputFormat)) {
+    console.error(`Invalid --format value: ${format} (use ${allowed.join(", ")})`);
+    process.exit(1);
+  }
+  return format as OutputFormat;
+}
+
+async function entitiesList(args: string[]): Promise<void> {
+  const format = readFormatFlag(args);
+  const type = readFlag(args, "--type");
+  const limit = parseLimitFlag(args) ?? 100;
+  const params = new URLSearchParams({ limit: String(limit), sort_by: "mention_count" });
+  if (type) {
+    params.set("labels", type);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/kg/entities?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    total?: number;
+    entities?: Array<{
+      uuid: string;
+      name: string;
+      labels: string[];
+      mention_count: number;
+      verified?: boolean;
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Listing failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const entities = data.entities ?? [];
+  if (format === "json") {
+    console.log(JSON.stringify({ entities, total: data.total ?? entities.length }, null, 2));
+    return;
+  }
+  if (entities.length === 0) {
+    console.log("No entities");
+    return;
+  }
+
+  const groups = new Map<string, typeof entities>();
+  for (const entity of entities) {
+    const entityType = entity.labels.find((label) => label !== "Entity") ?? "Other";
+    const group = groups.get(entityType) ?? [];
+    group.push(entity);
+    groups.set(entityType, group);
+  }
+
+  const styles = await loadEntityStyles();
+  const ordered = Array.from(groups.entries()).sort((a, b) => b[1].length - a[1].length);
+  for (const [entityType, group] of ordered) {
+    const style = styles[entityType.toLowerCase()] ?? FALLBACK_ENTITY_STYLE;
+    console.log(paint(`${style.icon} ${entityType} (${group.length})`, style.color));
+    for (const entity of group) {
+      const verified = entity.verified ? " ✓" : "";
+      const mentions = `${entity.mention_count} mention${entity.mention_count === 1 ? "" : "s"}`;
+      console.log(`  ${entity.uuid}  ${entity.name}${verified}  (${mentions})`);
+    }
+  }
+  if ((data.total ?? 0) > entities.length) {
+    console.log(`\nShowing ${entities.length} of ${data.total}; use --limit to see more`);
+  }
+}
+
+async function entitiesSearch(args: string[]): Promise<void> {
+  const format = readFormatFlag(args);
+  const valueFlags = new Set(["--type", "--limit", "--format"]);
+  const query = args
+    .filter((arg, index) => !arg.startsWith("--") && !valueFlags.has(args[index - 1] ?? ""))
+    .join(" ")
+    .trim();
+  if (!query) {
+    console.error("Usage: dere entities search <query> [--type=TYPE] [--limit=N]");
+    process.exit(1);
+  }
+  const type = readFlag(args, "--type");
+  const limit = parseLimitFlag(args) ?? 20;
+  const params = new URLSearchParams({
+    query,
+    limit: String(limit),
+    include_edges: "false",
+    include_facts: "false",
+  });
+  if (type) {
+    params.set("labels", type);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/kg/search?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    entities?: Array<{
+      uuid: string;
+      name: string;
+      labels: string[];
+      summary: string;
+      verified?: boolean;
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Search failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const entities = data.entities ?? [];
+  if (format === "json") {
+    console.log(JSON.stringify({ query, entities }, null, 2));
+    return;
+  }
+  if (entities.length === 0) {
+    console.log(`No entities match '${query}'`);
+    return;
+  }
+  const styles = await loadEntityStyles();
+  for (const entity of entities) {
+    const entityType = entity.labels.find((label) => label !== "Entity") ?? "Other";
+    const style = styles[entityType.toLowerCase()] ?? FALLBACK_ENTITY_STYLE;
+    const verified = entity.verified ? " ✓" : "";
+    const name = paint(`${style.icon} ${entity.name}`, style.color);
+    console.log(`${name}${verified}  [${entityType}]`);
+    if (entity.summary) {
+      console.log(`   ${entity.summary}`);
+    }
+  }
+}
+
+type GraphNode = { id: string; value: string; type: string; confidence: number };
+type GraphEdge = { from: string; to: string; type: string; confidence: number };
+
+const DOT_SHAPES = ["ellipse", "box", "diamond", "hexagon", "octagon", "parallelogram", "house"];
+
+function dotString(value: string): string {
+  return `"${value.replace(/\\/g, "\\\\").replace(/"/g, '\\"').replace(/\n/g, "\\n")}"`;
+}
+
+/** Graphviz DOT with one shape and color per entity type; stronger edges draw thicker. */
+function toDot(
+  nodes: GraphNode[],
+  edges: GraphEdge[],
+  styles: Record<string, EntityTypeStyle>,
+): string {
+  const types = Array.from(new Set(nodes.map((node) => node.type))).sort();
+  const lines = ["digraph entities {", "  rankdir=LR;", "  node [style=filled];"];
+  for (const node of nodes) {
+    const style = styles[node.type.toLowerCase()] ?? FALLBACK_ENTITY_STYLE;
+    const shape = DOT_SHAPES[types.indexOf(node.type) % DOT_SHAPES.length] ?? "ellipse";
+    lines.push(
+      `  ${dotString(node.id)} [label=${dotString(node.value)}, shape=${shape}, ` +
+        `fillcolor=${dotString(style.color)}, tooltip=${dotString(node.type)}];`,
+    );
+  }
+  for (const edge of edges) {
+    const weight = Math.min(Math.max(edge.confidence, 0), 1);
+    lines.push(
+      `  ${dotString(edge.from)} -> ${dotString(edge.to)} [label=${dotString(edge.type)}, ` +
+        `penwidth=${(1 + weight * 2).toFixed(1)}, weight=${Math.round(weight * 10) || 1}];`,
+    );
+  }
+  lines.push("}");
+  return lines.join("\n");
+}
+
+async function entitiesGraph(args: string[]): Promise<void> {
+  const format = readFormatFlag(args, ["text", "json", "dot"]);
+  const type = readFlag(args, "--type");
+  const center = readFlag(args, "--center");
+  const depth = readFlag(args, "--depth");
+  if (depth !== null && !/^[1-9]\d*$/.test(depth)) {
+    console.error(`Invalid --depth value: ${depth}`);
+    process.exit(1);
+  }
+  const limit = parseLimitFlag(args) ?? 100;
+  const params = new URLSearchParams({ limit: String(limit) });
+  if (type) {
+    params.set("labels", type);
+  }
+  if (center) {
+    params.set("center", center);
+    params.set("depth", depth ?? "2");
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/kg/graph?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    nodes?: GraphNode[];
+    edges?: GraphEdge[];
+  };
+  if (!response.ok) {
+    console.error(`Graph export failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const nodes = data.nodes ?? [];
+  const edges = data.edges ?? [];
+  if (format === "json") {
+    console.log(JSON.stringify({ nodes, edges }, null, 2));
+    return;
+  }
+  if (format === "dot") {
+    console.log(toDot(nodes, edges, await loadEntityStyles()));
+    return;
+  }
+  if (edges.length === 0) {
+    console.log(
+      center
+        ? `No relationships within ${depth ?? 2} hops of ${center}`
+        : `No relationships among the top ${nodes.length} entities`,
+    );
+    return;
+  }
+  const names = new Map(nodes.map((node) => [node.id, node.value]));
+  for (const edge of edges) {
+    const from = names.get(edge.from) ?? edge.from;
+    const to = names.get(edge.to) ?? edge.to;
+    console.log(`${from} --[${edge.type}]--> ${to}`);
+  }
+  console.log(`\n${nodes.length} entities, ${edges.length} relationships`);
+}
+
+async function entitiesCalibration(args: string[]): Promise<void> {
+  const format = readFormatFlag(args);
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/kg/calibration`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    bands?: Array<{ min: number; max: number; count: number; verified: number }>;
+    by_type?: Record<string, Record<string, number>>;
+    unscored?: number;
+    total?: number;
+  };
+  if (!response.ok) {
+    console.error(`Calibration report failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+  if (format === "json") {
+    console.log(JSON.stringify(data, null, 2));
+    return;
+  }
+
+  const bands = data.bands ?? [];
+  if (bands.length === 0) {
+    console.log("No entities with a recorded extraction confidence yet");
+    return;
+  }
+  let threshold = "0.5";
+  try {
+    const graphConfig = ((await loadConfig()).dere_graph ?? {}) as Record<string, unknown>;
+    if (typeof graphConfig.min_entity_confidence === "number") {
+      threshold = String(graphConfig.min_entity_confidence);
+    }
+  } catch {
+    // default threshold
+  }
+
+  const scored = bands.reduce((sum, band) => sum + band.count, 0);
+  const widest = Math.max(...bands.map((band) => band.count));
+  console.log(
+    `Extraction confidence for ${scored} entities ` +
+      `(${data.unscored ?? 0} unscored; min_entity_confidence = ${threshold})\n`,
+  );
+  console.log("  band      count  verified");
+  for (const band of bands) {
+    const label = `${band.min.toFixed(1)}-${band.max.toFixed(1)}`;
+    const bar = "█".repeat(Math.max(1, Math.round((band.count / widest) * 30)));
+    console.log(
+      `  ${label}  ${String(band.count).padStart(6)}  ${String(band.verified).padStart(8)}  ${bar}`,
+    );
+  }
+
+  const keys = bands.map((band) => band.min.toFixed(1));
+  const types = Object.entries(data.by_type ?? {}).sort(([a], [b]) => a.localeCompare(b));
+  const typeWidth = Math.max(4, ...types.map(([type]) => type.length));
+  console.log("\nBy type:");
+  console.log(`  ${"type".padEnd(typeWidth)}  ${keys.map((key) => key.padStart(5)).join(" ")}`);
+  for (const [type, counts] of types) {
+    const cells = keys.map((key) => String(counts[key] ?? 0).padStart(5)).join(" ");
+    console.log(`  ${type.padEnd(typeWidth)}  ${cells}`);
+  }
+}
+
+async function entitiesLink(args: string[]): Promise<void> {
+  if (!args.includes("--co-occurrence")) {
+    console.error("Specify a linking strategy: --co-occurrence");
+    process.exit(1);
+  }
+
+  let minCount: number | null = null;
+  for (let i = 0; i < args.length; i += 1) {
+    const arg = args[i] ?? "";
+    const value = arg.startsWith("--min-count=")
+      ? arg.slice("--min-count=".length)
+      : arg === "--min-count"
+        ? args[i + 1]
+        : undefined;
+    if (value !== undefined) {
+      const parsed = Number.parseInt(value, 10);
+      if (!Number.isFinite(parsed) || parsed <= 0) {
+        console.error(`Invalid --min-count value: ${value}`);
+        process.exit(1);
+      }
+      minCount = parsed;
+    }
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  const query = minCount === null ? "" : `?min_count=${minCount}`;
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/kg/entities/link${query}`, { method: "POST" });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as Record<string, unknown>;
+  if (!response.ok) {
+    console.error(`Linking failed: ${String(data.error ?? response.statusText)}`);
+    process.exit(1);
+  }
+
+  const linked = Number(data.linked ?? 0);
+  console.log(
+    `Linked ${linked} entity pair${linked === 1 ? "" : "s"} ` +
+      `(co-occurring in at least ${String(data.min_count ?? minCount)} conversations)`,
+  );
+}
+
+async function sendEntityRequest(
+  uuid: string,
+  method: string,
+  suffix = "",
+  body?: Record<string, unknown>,
+): Promise<void> {
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/kg/entities/${encodeURIComponent(uuid)}${suffix}`, {
+      method,
+      headers: body ? { "Content-Type": "application/json" } : undefined,
+      body: body ? JSON.stringify(body) : undefined,
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  if (!response.ok) {
+    const data = (await response.json().catch(() => ({}))) as Record<string, unknown>;
+    console.error(`Entity ${uuid}: ${String(data.error ?? response.statusText)}`);
+    process.exit(1);
+  }
+}
+
+async function entitiesEdit(args: string[]): Promise<void> {
+  const uuid = args[0];
+  const type = readFlag(args, "--type");
+  const name = readFlag(args, "--value");
+  if (!uuid || uuid.startsWith("--") || (!type && !name)) {
+    console.error("Usage: dere entities edit <uuid> [--type=TYPE] [--value=NAME]");
+    process.exit(1);
+  }
+  await sendEntityRequest(uuid, "PATCH", "", { type, name });
+  console.log(`Updated entity ${uuid}`);
+}
+
+async function entitiesDelete(args: string[]): Promise<void> {
+  const uuid = args[0];
+  if (!uuid) {
+    console.error("Usage: dere entities delete <uuid>");
+    process.exit(1);
+  }
+  await sendEntityRequest(uuid, "DELETE");
+  console.log(`Deleted entity ${uuid}`);
+}
+
+async function entitiesConfirm(args: string[]): Promise<void> {
+  const uuid = args[0];
+  if (!uuid) {
+    console.error("Usage: dere entities confirm <uuid>");
+    process.exit(1);
+  }
+  await sendEntityRequest(uuid, "POST", "/verify");
+  console.log(`Verified entity ${uuid}`);
+}
+
+async function sessionsChain(args: string[]): Promise<void> {
+  const sessionId = args[0];
+  if (!sessionId || !/^\d+$/.test(sessionId)) {
+    console.error("Usage: dere sessions chain <id>");
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/sessions/${sessionId}/chain`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    chain?: Array<{
+      id: number;
+      name: string | null;
+      working_dir: string;
+      personality: string | null;
+      start_time: number;
+      summary: string | null;
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Failed to load session chain: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const chain = data.chain ?? [];
+  chain.forEach((session, index) => {
+    const started = new Date(session.start_time * 1000).toLocaleString();
+    const label = session.name ? ` ${session.name}` : "";
+    const personality = session.personality ? ` [${session.personality}]` : "";
+    console.log(`${index + 1}. #${session.id}${label}${personality}  ${started}`);
+    console.log(`   ${session.working_dir}`);
+    console.log(`   ${session.summary ?? "(no summary)"}`);
+  });
+}
+
+async function sessionsList(args: string[]): Promise<void> {
+  const limit = parseLimitFlag(args) ?? 20;
+  const tag = readFlag(args, "--tag");
+  const params = new URLSearchParams({ limit: String(limit) });
+  if (tag) {
+    params.set("tag", tag);
+  }
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/sessions/list?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    sessions?: Array<{
+      id: number;
+      name: string | null;
+      working_dir: string;
+      personality: string | null;
+      last_activity: string;
+      pinned: boolean;
+      tags: string[];
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Failed to list sessions: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const sessions = data.sessions ?? [];
+  if (sessions.length === 0) {
+    console.log(tag ? `No sessions tagged '${tag}'` : "No sessions");
+    return;
+  }
+  for (const session of sessions) {
+    const pin = session.pinned ? "📌" : "  ";
+    const active = new Date(session.last_activity).toLocaleString();
+    const label = session.name ? ` ${session.name}` : "";
+    const personality = session.personality ? ` [${session.personality}]` : "";
+    const tags = session.tags.length > 0 ? `  #${session.tags.join(" #")}` : "";
+    console.log(
+      `${pin} #${session.id}${label}${personality}  ${active}  ${session.working_dir}${tags}`,
+    );
+  }
+}
+
+async function sessionsTag(args: string[]): Promise<void> {
+  const [sessionId, ...tags] = args;
+  if (!sessionId || !/^\d+$/.test(sessionId) || tags.length === 0) {
+    console.error("Usage: dere sessions tag <id> <tag>...");
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/sessions/${sessionId}/tags`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ tags }),
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as { error?: string; tags?: string[] };
+  if (!response.ok) {
+    console.error(`Failed to tag session: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+  console.log(`Session #${sessionId} tags: ${(data.tags ?? []).join(", ")}`);
+}
+
+async function sessionsSetPinned(args: string[], pinned: boolean): Promise<void> {
+  const action = pinned ? "pin" : "unpin";
+  const sessionId = args[0];
+  if (!sessionId || !/^\d+$/.test(sessionId)) {
+    console.error(`Usage: dere sessions ${action} <id>`);
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/sessions/${sessionId}/${action}`, { method: "POST" });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as { error?: string };
+  if (!response.ok) {
+    console.error(`Failed to ${action} session: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+  console.log(pinned ? `Pinned session #${sessionId}` : `Unpinned session #${sessionId}`);
+}
+
+async function todosList(args: string[]): Promise<void> {
+  const limit = parseLimitFlag(args) ?? 50;
+  const project = readFlag(args, "--project");
+  const status = args.includes("--all") ? "all" : args.includes("--done") ? "done" : "open";
+  const params = new URLSearchParams({ status, limit: String(limit) });
+  if (project) {
+    params.set("working_dir", resolvePath(project));
+  }
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/action-items?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    items?: Array<{
+      id: number;
+      session_id: number;
+      working_dir: string;
+      text: string;
+      status: string;
+      created_at: string;
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Failed to list todos: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const items = data.items ?? [];
+  if (items.length === 0) {
+    console.log(status === "open" ? "No open todos" : "No todos");
+    return;
+  }
+  for (const item of items) {
+    const mark = item.status === "done" ? "[x]" : "[ ]";
+    const created = new Date(item.created_at).toLocaleDateString();
+    const where = project ? "" : `  ${item.working_dir}`;
+    const origin = `(session #${item.session_id}, ${created})`;
+    console.log(`${mark} #${item.id} ${item.text}  ${origin}${where}`);
+  }
+}
+
+async function todosSetStatus(args: string[], done: boolean): Promise<void> {
+  const action = done ? "done" : "reopen";
+  const itemId = args[0];
+  if (!itemId || !/^\d+$/.test(itemId)) {
+    console.error(`Usage: dere todos ${action} <id>`);
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/action-items/${itemId}/status`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ status: done ? "done" : "open" }),
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as { error?: string };
+  if (!response.ok) {
+    console.error(`Failed to update todo: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+  console.log(done ? `Marked #${itemId} done` : `Reopened #${itemId}`);
+}
+
+function parseDaysFlag(args: string[], fallback = 30): number {
+  for (let i = 0; i < args.length; i += 1) {
+    const arg = args[i] ?? "";
+    const value = arg.startsWith("--days=")
+      ? arg.slice("--days=".length)
+      : arg === "--days"
+        ? args[i + 1]
+        : undefined;
+    if (value !== undefined) {
+      const parsed = Number.parseInt(value, 10);
+      if (!Number.isFinite(parsed) || parsed <= 0) {
+        console.error(`Invalid --days value: ${value}`);
+        process.exit(1);
+      }
+      return parsed;
+    }
+  }
+  return fallback;
+}
+
+type CostBreakdown = Array<{ key: string; total_cost_usd: number; sessions: number }>;
+
+function printCostBreakdown(title: string, rows: CostBreakdown): void {
+  if (rows.length === 0) {
+    return;
+  }
+  console.log(`\n${title}:`);
+  for (const row of rows) {
+    const cost = `$${row.total_cost_usd.toFixed(2)}`.padStart(10);
+    console.log(`  ${cost}  ${row.key} (${row.sessions} sessions)`);
+  }
+}
+
+async function stats(args: string[]): Promise<void> {
+  const days = parseDaysFlag(args);
+  const tag = readFlag(args, "--tag");
+  const params = new URLSearchParams({ days: String(days) });
+  if (tag) {
+    params.set("tag", tag);
+  }
+  const daemonUrl = await resolveDaemonUrl();
+  let data: {
+    total_cost_usd?: number;
+    sessions?: number;
+    by_project?: CostBreakdown;
+    by_personality?: CostBreakdown;
+  };
+  try {
+    const response = await fetch(`${daemonUrl}/costs/stats?${params.toString()}`);
+    if (!response.ok) {
+      console.error(`Failed to load stats: ${response.statusText}`);
+      process.exit(1);
+    }
+    data = (await response.json()) as typeof data;
+  } catch {
+    console.error("Daemon is not running");
     process.exit(1);
   }
+
+  const total = `$${(data.total_cost_usd ?? 0).toFixed(2)}`;
+  const scope = tag ? ` tagged '${tag}'` : "";
+  console.log(`Last ${days} days: ${total} across ${data.sessions ?? 0} sessions${scope}`);
+  printCostBreakdown("By project", data.by_project ?? []);
+  printCostBreakdown("By personality", data.by_personality ?? []);
+}
+
+function parseOlderThan(args: string[]): number {
+  const raw = readFlag(args, "--older-than");
+  if (raw === null) {
+    console.error("--older-than is required (e.g. --older-than=90d)");
+    process.exit(1);
+  }
+  const match = /^(\d+)d?$/.exec(raw.trim());
+  const days = match ? Number.parseInt(match[1] ?? "", 10) : Number.NaN;
+  if (!Number.isFinite(days) || days <= 0) {
+    console.error(`Invalid --older-than value: ${raw}`);
+    process.exit(1);
+  }
+  return days;
+}
+
+async function cleanup(args: string[]): Promise<void> {
+  const olderThanDays = parseOlderThan(args);
+  const dryRun = args.includes("--dry-run");
+  const daemonUrl = await resolveDaemonUrl();
+  let data: {
+    error?: string;
+    sessions?: number;
+    conversations?: number;
+    blocks?: number;
+    entities?: number;
+    other_rows?: number;
+    freed_bytes?: number;
+  };
+  try {
+    const response = await fetch(`${daemonUrl}/sessions/cleanup`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ older_than_days: olderThanDays, dry_run: dryRun }),
+    });
+    data = (await response.json()) as typeof data;
+    if (!response.ok) {
+      console.error(`Cleanup failed: ${data.error ?? response.statusText}`);
+      process.exit(1);
+    }
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const freedMb = ((data.freed_bytes ?? 0) / (1024 * 1024)).toFixed(1);
+  const verb = dryRun ? "Would remove" : "Removed";
+  console.log(
+    `${verb} ${data.sessions ?? 0} sessions older than ${olderThanDays} days (~${freedMb} MB)`,
+  );
+  console.log(`  conversations: ${data.conversations ?? 0}`);
+  console.log(`  blocks:        ${data.blocks ?? 0}`);
+  console.log(`  entities:      ${data.entities ?? 0}`);
+  console.log(`  other rows:    ${data.other_rows ?? 0}`);
+}
+
+async function topics(args: string[]): Promise<void> {
+  const days = parseDaysFlag(args, 90);
+  const params = new URLSearchParams({ days: String(days) });
+  for (const flag of ["clusters", "examples"]) {
+    const value = readFlag(args, `--${flag}`);
+    if (value === null) {
+      continue;
+    }
+    if (!/^\d+$/.test(value) || Number(value) <= 0) {
+      console.error(`Invalid --${flag} value: ${value}`);
+      process.exit(1);
+    }
+    params.set(flag, value);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/topics?${params.toString()}`);
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    sessions?: number;
+    clusters?: Array<{
+      size: number;
+      cohesion: number;
+      projects: Array<{ working_dir: string; sessions: number }>;
+      examples: Array<{ id: number; summary: string; similarity: number }>;
+    }>;
+  };
+  if (!response.ok) {
+    console.error(`Failed to cluster topics: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const clusters = data.clusters ?? [];
+  if (clusters.length === 0) {
+    console.log(`Not enough summarized sessions in the last ${days} days to group`);
+    return;
+  }
+  console.log(
+    `${data.sessions ?? 0} sessions from the last ${days} days, ${clusters.length} topics`,
+  );
+  clusters.forEach((cluster, index) => {
+    const projects = cluster.projects
+      .slice(0, 3)
+      .map((project) => `${project.working_dir} (${project.sessions})`)
+      .join(", ");
+    const more = cluster.projects.length > 3 ? `, +${cluster.projects.length - 3} more` : "";
+    const cohesion = cluster.cohesion.toFixed(2);
+    console.log(`\n${index + 1}. ${cluster.size} sessions, cohesion ${cohesion}`);
+    console.log(`   Projects: ${projects}${more}`);
+    for (const example of cluster.examples) {
+      const summary = example.summary.replace(/\s+/g, " ").trim();
+      const line = summary.length > 100 ? `${summary.slice(0, 99)}…` : summary;
+      console.log(`   #${example.id}  ${line}`);
+    }
+  });
+}
+
+async function purge(args: string[]): Promise<void> {
+  const sessionId = args.find((arg) => !arg.startsWith("-"));
+  if (!sessionId || !/^\d+$/.test(sessionId)) {
+    console.error("Usage: dere purge <session-id> [--yes]");
+    process.exit(1);
+  }
+  const confirmed = args.includes("--yes") || args.includes("-y");
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/sessions/${sessionId}/purge`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ dry_run: !confirmed }),
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as {
+    error?: string;
+    tables?: Record<string, number>;
+    graph_error?: string;
+  };
+  if (!response.ok) {
+    console.error(`Failed to purge session #${sessionId}: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+
+  const rows = Object.entries(data.tables ?? {}).filter(([, count]) => count > 0);
+  console.log(confirmed ? `Purged session #${sessionId}` : `Would purge session #${sessionId}`);
+  const width = Math.max(0, ...rows.map(([table]) => table.length));
+  for (const [table, count] of rows.sort(([a], [b]) => a.localeCompare(b))) {
+    console.log(`  ${`${table}:`.padEnd(width + 1)} ${count}`);
+  }
+  if (data.graph_error) {
+    const outcome = confirmed ? "were not removed" : "could not be counted";
+    console.error(`Warning: knowledge graph episodes ${outcome}: ${data.graph_error}`);
+  }
+  if (!confirmed) {
+    console.log(`Nothing deleted; run dere purge ${sessionId} --yes to delete it.`);
+  }
+}
+
+async function reprocess(args: string[]): Promise<void> {
+  const type = readFlag(args, "--type");
+  const session = readFlag(args, "--session");
+  const all = args.includes("--all");
+  if (!type || (!session && !all) || (session && all)) {
+    console.log(REPROCESS_HELP.trim());
+    process.exit(1);
+  }
+  if (session && !/^\d+$/.test(session)) {
+    console.error(`Invalid --session value: ${session}`);
+    process.exit(1);
+  }
+
+  const daemonUrl = await resolveDaemonUrl();
+  let response: Response;
+  try {
+    response = await fetch(`${daemonUrl}/sessions/reprocess`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        type,
+        session_id: session ? Number(session) : undefined,
+        all: all || undefined,
+      }),
+    });
+  } catch {
+    console.error("Daemon is not running");
+    process.exit(1);
+  }
+
+  const data = (await response.json()) as { error?: string; sessions?: number };
+  if (!response.ok) {
+    console.error(`Reprocess failed: ${data.error ?? response.statusText}`);
+    process.exit(1);
+  }
+  console.log(`Reprocessing ${type} for ${data.sessions ?? 0} sessions in the daemon`);
+}
+
+export async function runSubcommand(args: string[]): Promise<void> {
+  if (args.length === 0 || args[0] === "--help" || args[0] === "-h") {
+    console.log(MAIN_HELP.trim());
+    return;
+  }
+
+  const [command, ...rest] = args;
+  if (command === "version") {
+    console.log("dere 0.1.0");
+    return;
+  }
+  if (command === "daemon") {
+    const sub = rest[0];
+    if (!sub || sub === "--help" || sub === "-h") {
+      console.log(DAEMON_HELP.trim());
+      return;
+    }
+    if (sub === "status") {
+      await daemonStatus();
+      return;
+    }
+    if (sub === "start") {
+      await daemonStart();
+      return;
+    }
+    if (sub === "stop") {
+      await daemonStop();
+      return;
+    }
+    if (sub === "restart") {
+      await daemonRestart();
+      return;
+    }
+    console.log(DAEMON_HELP.trim());
+    process.exit(1);
+  }
+  if (command === "doctor") {
+    await doctor();
+    return;
+  }
   if (command === "config") {
     const sub = rest[0];
     if (!sub || sub === "--help" || sub === "-h") {
@@ -199,7 +2565,11 @@ export async function runSubcommand(args: string[]): Promise<void> {
       return;
     }
     if (sub === "show") {
-      await configShow();
+      await configShow(rest.slice(1));
+      return;
+    }
+    if (sub === "validate") {
+      await configValidate();
       return;
     }
     if (sub === "path") {
@@ -214,6 +2584,246 @@ export async function runSubcommand(args: string[]): Promise<void> {
     process.exit(1);
   }
 
+  if (command === "embeddings") {
+    const sub = rest[0];
+    if (!sub || sub === "--help" || sub === "-h") {
+      console.log(EMBEDDINGS_HELP.trim());
+      return;
+    }
+    if (sub === "backfill") {
+      await embeddingsBackfill(rest.slice(1));
+      return;
+    }
+    if (sub === "reindex") {
+      await embeddingsReindex();
+      return;
+    }
+    console.log(EMBEDDINGS_HELP.trim());
+    process.exit(1);
+  }
+
+  if (command === "entities") {
+    const sub = rest[0];
+    if (!sub || sub === "--help" || sub === "-h") {
+      console.log(ENTITIES_HELP.trim());
+      return;
+    }
+    if (sub === "list") {
+      await entitiesList(rest.slice(1));
+      return;
+    }
+    if (sub === "search") {
+      await entitiesSearch(rest.slice(1));
+      return;
+    }
+    if (sub === "graph") {
+      await entitiesGraph(rest.slice(1));
+      return;
+    }
+    if (sub === "calibration") {
+      await entitiesCalibration(rest.slice(1));
+      return;
+    }
+    if (sub === "link") {
+      await entitiesLink(rest.slice(1));
+      return;
+    }
+    if (sub === "edit") {
+      await entitiesEdit(rest.slice(1));
+      return;
+    }
+    if (sub === "delete") {
+      await entitiesDelete(rest.slice(1));
+      return;
+    }
+    if (sub === "confirm") {
+      await entitiesConfirm(rest.slice(1));
+      return;
+    }
+    console.log(ENTITIES_HELP.trim());
+    process.exit(1);
+  }
+
+  if (command === "sessions") {
+    const sub = rest[0];
+    if (!sub || sub === "--help" || sub === "-h") {
+      console.log(SESSIONS_HELP.trim());
+      return;
+    }
+    if (sub === "list") {
+      await sessionsList(rest.slice(1));
+      return;
+    }
+    if (sub === "chain") {
+      await sessionsChain(rest.slice(1));
+      return;
+    }
+    if (sub === "pin" || sub === "unpin") {
+      await sessionsSetPinned(rest.slice(1), sub === "pin");
+      return;
+    }
+    if (sub === "tag") {
+      await sessionsTag(rest.slice(1));
+      return;
+    }
+    console.log(SESSIONS_HELP.trim());
+    process.exit(1);
+  }
+
+  if (command === "todos") {
+    const sub = rest[0];
+    if (sub === "--help" || sub === "-h") {
+      console.log(TODOS_HELP.trim());
+      return;
+    }
+    if (sub === "done" || sub === "reopen") {
+      await todosSetStatus(rest.slice(1), sub === "done");
+      return;
+    }
+    await todosList(sub === "list" ? rest.slice(1) : rest);
+    return;
+  }
+
+  if (command === "search") {
+    if (rest[0] === "--help" || rest[0] === "-h") {
+      console.log(SEARCH_HELP.trim());
+      return;
+    }
+    await searchConversations(rest);
+    return;
+  }
+
+  if (command === "summaries") {
+    const sub = rest[0];
+    if (!sub || sub === "--help" || sub === "-h") {
+      console.log(SUMMARIES_HELP.trim());
+      return;
+    }
+    if (sub === "list") {
+      await summariesList(rest.slice(1));
+      return;
+    }
+    if (sub === "search") {
+      await summariesSearch(rest.slice(1));
+      return;
+    }
+    if (sub === "regenerate") {
+      await summariesRegenerate(rest.slice(1));
+      return;
+    }
+    console.log(SUMMARIES_HELP.trim());
+    process.exit(1);
+  }
+
+  if (command === "prompt") {
+    const sub = rest[0];
+    if (!sub || sub === "--help" || sub === "-h") {
+      console.log(PROMPT_HELP.trim());
+      return;
+    }
+    if (sub === "preview") {
+      await previewSystemPrompt(rest.slice(1));
+      return;
+    }
+    console.log(PROMPT_HELP.trim());
+    process.exit(1);
+  }
+
+  if (command === "queue") {
+    const sub = rest[0];
+    if (!sub || sub === "--help" || sub === "-h") {
+      console.log(QUEUE_HELP.trim());
+      return;
+    }
+    if (sub === "tail") {
+      await queueTail(rest.slice(1));
+      return;
+    }
+    if (sub === "wait") {
+      await queueWait(rest.slice(1));
+      return;
+    }
+    console.log(QUEUE_HELP.trim());
+    process.exit(1);
+  }
+
+  if (command === "context") {
+    const sub = rest[0];
+    if (!sub || sub === "--help" || sub === "-h") {
+      console.log(CONTEXT_HELP.trim());
+      return;
+    }
+    if (sub === "show") {
+      await contextShow(rest.slice(1));
+      return;
+    }
+    console.log(CONTEXT_HELP.trim());
+    process.exit(1);
+  }
+
+  if (command === "capture") {
+    if (rest.length === 0 || rest[0] === "--help" || rest[0] === "-h") {
+      console.log(CAPTURE_HELP.trim());
+      return;
+    }
+    await capture(rest);
+    return;
+  }
+
+  if (command === "import") {
+    if (rest.length === 0 || rest[0] === "--help" || rest[0] === "-h") {
+      console.log(IMPORT_HELP.trim());
+      return;
+    }
+    await importTranscripts(rest);
+    return;
+  }
+
+  if (command === "purge") {
+    if (rest.length === 0 || rest[0] === "--help" || rest[0] === "-h") {
+      console.log(PURGE_HELP.trim());
+      return;
+    }
+    await purge(rest);
+    return;
+  }
+
+  if (command === "reprocess") {
+    if (rest[0] === "--help" || rest[0] === "-h") {
+      console.log(REPROCESS_HELP.trim());
+      return;
+    }
+    await reprocess(rest);
+    return;
+  }
+
+  if (command === "cleanup") {
+    if (rest[0] === "--help" || rest[0] === "-h") {
+      console.log(CLEANUP_HELP.trim());
+      return;
+    }
+    await cleanup(rest);
+    return;
+  }
+
+  if (command === "topics") {
+    if (rest[0] === "--help" || rest[0] === "-h") {
+      console.log(TOPICS_HELP.trim());
+      return;
+    }
+    await topics(rest);
+    return;
+  }
+
+  if (command === "stats") {
+    if (rest[0] === "--help" || rest[0] === "-h") {
+      console.log(STATS_HELP.trim());
+      return;
+    }
+    await stats(rest);
+    return;
+  }
+
   console.log(MAIN_HELP.trim());
   process.exit(1);
 }
@@ -0,0 +1,99 @@
+/**
+ * Claude Code session transcripts (~/.claude/projects/<project>/<session>.jsonl):
+ * one JSON entry per line, with a user or assistant message, an ISO
+ * timestamp, and the session id and working directory it was recorded in.
+ */
+
+type TranscriptEntry = {
+  type?: string;
+  isMeta?: boolean;
+  isSidechain?: boolean;
+  timestamp?: string;
+  sessionId?: string;
+  cwd?: string;
+  message?: {
+    role?: string;
+    content?: string | Array<{ type?: string; text?: string }>;
+  };
+};
+
+export type TranscriptTurn = {
+  message_type: "user" | "assistant";
+  prompt: string;
+  timestamp: number;
+};
+
+export type ParsedTranscript = {
+  claudeSessionId: string | null;
+  cwd: string | null;
+  turns: TranscriptTurn[];
+};
+
+// Slash command bookkeeping Claude Code records as user messages.
+const COMMAND_MARKUP_RE = /^<(command-|local-command-)/;
+
+function entryText(entry: TranscriptEntry): string {
+  const content = entry.message?.content;
+  if (typeof content === "string") {
+    return content;
+  }
+  if (!Array.isArray(content)) {
+    return "";
+  }
+  // Tool calls and results are skipped; only what was said is imported.
+  return content
+    .filter((item) => item?.type === "text")
+    .map((item) => item.text ?? "")
+    .filter(Boolean)
+    .join("\n");
+}
+
+/**
+ * Turn a transcript into user/assistant turns in order. Claude Code writes
+ * one assistant entry per content block, so consecutive assistant entries are
+ * joined into a single reply. Subagent (sidechain) and meta entries are skipped.
+ */
+export function parseClaudeTranscript(text: string): ParsedTranscript {
+  let claudeSessionId: string | null = null;
+  let cwd: string | null = null;
+  const turns: TranscriptTurn[] = [];
+
+  for (const line of text.split("\n")) {
+    const trimmed = line.trim();
+    if (!trimmed) {
+      continue;
+    }
+    let entry: TranscriptEntry;
+    try {
+      entry = JSON.parse(trimmed) as TranscriptEntry;
+    } catch {
+      continue;
+    }
+    if (entry.type !== "user" && entry.type !== "assistant") {
+      continue;
+    }
+    if (entry.isMeta || entry.isSidechain) {
+      continue;
+    }
+    claudeSessionId ??= entry.sessionId ?? null;
+    cwd ??= entry.cwd ?? null;
+
+    const prompt = entryText(entry).trim();
+    if (!prompt || (entry.type === "user" && COMMAND_MARKUP_RE.test(prompt))) {
+      continue;
+    }
+    const parsedTime = entry.timestamp ? Date.parse(entry.timestamp) : Number.NaN;
+    const previous = turns[turns.length - 1];
+    const timestamp = Number.isNaN(parsedTime)
+      ? (previous?.timestamp ?? Math.floor(Date.now() / 1000))
+      : Math.floor(parsedTime / 1000);
+
+    if (entry.type === "assistant" && previous?.message_type === "assistant") {
+      previous.prompt = `${previous.prompt}\n${prompt}`;
+      continue;
+    }
+    turns.push({ message_type: entry.type, prompt, timestamp });
+  }
+
+  return { claudeSessionId, cwd, turns };
+}
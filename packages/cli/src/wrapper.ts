@@ -1,25 +1,32 @@
-import { existsSync } from "node:fs";
-import { readFile, unlink, writeFile } from "node:fs/promises";
+import { closeSync, existsSync, fstatSync, openSync, rmSync } from "node:fs";
+import { readFile, writeFile } from "node:fs/promises";
 import { spawn } from "node:child_process";
+import { randomInt } from "node:crypto";
 import { homedir, tmpdir } from "node:os";
 import { dirname, join, resolve } from "node:path";
+import { createInterface } from "node:readline/promises";
 import { fileURLToPath } from "node:url";
 
 import {
+  captureExcludePaths,
+  findProjectConfigPath,
+  isCaptureExcluded,
   loadConfig,
+  loadProjectConfig,
+  parseTomlString,
   getConfigPath,
   getDaemonUrlFromConfig,
   type DereConfig,
 } from "@dere/shared-config";
 
 import { buildMcpConfig } from "./mcp.js";
+import { loadModeDefinition } from "./modes.js";
 import { PersonalityLoader } from "./persona.js";
 import type { ClaudeCodeSettings, MarketplaceSource, StatusLineConfig } from "./types.js";
 
+// sessions.id is a Postgres integer, so ids stay below 2^31.
 function generateSessionId(): number {
-  const base = BigInt(Date.now()) * 1_000_000n;
-  const mask = (1n << 31n) - 1n;
-  return Number(base & mask);
+  return randomInt(1, 2 ** 31 - 1);
 }
 
 type ParsedArgs = {
@@ -27,7 +34,10 @@ type ParsedArgs = {
   outputStyle: string | null;
   continueConv: boolean;
   resume: string | null;
+  listSessions: boolean;
   bare: boolean;
+  fast: boolean;
+  profile: string | null;
   mode: string | null;
   model: string | null;
   fallbackModel: string | null;
@@ -39,6 +49,9 @@ type ParsedArgs = {
   ide: boolean;
   mcpServers: string[];
   dryRun: boolean;
+  disabledTasks: Set<string>;
+  appendContext: string[];
+  tags: string[];
   passthrough: string[];
 };
 
@@ -48,7 +61,10 @@ function parseArgs(args: string[]): ParsedArgs {
     outputStyle: null,
     continueConv: false,
     resume: null,
+    listSessions: false,
     bare: false,
+    fast: false,
+    profile: null,
     mode: null,
     model: null,
     fallbackModel: null,
@@ -60,6 +76,9 @@ function parseArgs(args: string[]): ParsedArgs {
     ide: false,
     mcpServers: [],
     dryRun: false,
+    disabledTasks: new Set(),
+    appendContext: [],
+    tags: [],
     passthrough: [],
   };
 
@@ -75,6 +94,14 @@ function parseArgs(args: string[]): ParsedArgs {
       }
       continue;
     }
+    if (arg?.startsWith("--personality=")) {
+      const name = arg.slice("--personality=".length);
+      if (name) {
+        state.personalities.push(name);
+      }
+      i += 1;
+      continue;
+    }
     if (arg === "--output-style" && args[i + 1]) {
       state.outputStyle = args[i + 1] as string;
       i += 2;
@@ -90,11 +117,31 @@ function parseArgs(args: string[]): ParsedArgs {
       i += 2;
       continue;
     }
+    if (arg === "--list-sessions") {
+      state.listSessions = true;
+      i += 1;
+      continue;
+    }
     if (arg === "--bare") {
       state.bare = true;
       i += 1;
       continue;
     }
+    if (arg === "--fast") {
+      state.fast = true;
+      i += 1;
+      continue;
+    }
+    if (arg === "--profile" && args[i + 1]) {
+      state.profile = args[i + 1] as string;
+      i += 2;
+      continue;
+    }
+    if (arg?.startsWith("--profile=")) {
+      state.profile = arg.slice("--profile=".length) || null;
+      i += 1;
+      continue;
+    }
     if (arg === "--mode" && args[i + 1]) {
       state.mode = args[i + 1] as string;
       i += 2;
@@ -145,11 +192,51 @@ function parseArgs(args: string[]): ParsedArgs {
       i += 2;
       continue;
     }
+    // Profiles and tags resolve through mcp_config.json alongside --mcp names.
+    if ((arg === "--mcp-profile" || arg === "--mcp-tag") && args[i + 1]) {
+      const prefix = arg === "--mcp-profile" ? "profile:" : "tag:";
+      state.mcpServers.push(`${prefix}${args[i + 1] as string}`);
+      i += 2;
+      continue;
+    }
+    if (arg?.startsWith("--mcp-profile=") || arg?.startsWith("--mcp-tag=")) {
+      const [flag, value] = arg.split("=", 2) as [string, string];
+      if (value) {
+        state.mcpServers.push(`${flag === "--mcp-profile" ? "profile:" : "tag:"}${value}`);
+      }
+      i += 1;
+      continue;
+    }
+    if (arg === "--append-context" && args[i + 1] !== undefined) {
+      state.appendContext.push(args[i + 1] as string);
+      i += 2;
+      continue;
+    }
+    if (arg?.startsWith("--append-context=")) {
+      state.appendContext.push(arg.slice("--append-context=".length));
+      i += 1;
+      continue;
+    }
+    if (arg === "--tag" && args[i + 1]) {
+      state.tags.push(...(args[i + 1] as string).split(","));
+      i += 2;
+      continue;
+    }
+    if (arg?.startsWith("--tag=")) {
+      state.tags.push(...arg.slice("--tag=".length).split(","));
+      i += 1;
+      continue;
+    }
     if (arg === "--dry-run") {
       state.dryRun = true;
       i += 1;
       continue;
     }
+    if (arg === "--no-entities" || arg === "--no-summary" || arg === "--no-embeddings") {
+      state.disabledTasks.add(arg.slice("--no-".length));
+      i += 1;
+      continue;
+    }
     if (arg === "--") {
       state.passthrough.push(...args.slice(i + 1));
       break;
@@ -198,6 +285,62 @@ async function checkDaemonAvailable(): Promise<boolean> {
   }
 }
 
+const SESSION_ID_ATTEMPTS = 5;
+
+/**
+ * Pick a random session id and claim it in the daemon so two launches can't
+ * share one. Falls back to an unclaimed random id when the daemon is down;
+ * the capture route creates the row on first use.
+ */
+async function reserveSessionId(personality: string | null, daemonAvailable: boolean) {
+  if (!daemonAvailable) {
+    return generateSessionId();
+  }
+  const daemonUrl = await resolveDaemonUrl();
+  for (let attempt = 0; attempt < SESSION_ID_ATTEMPTS; attempt += 1) {
+    const id = generateSessionId();
+    try {
+      const response = await fetch(`${daemonUrl}/sessions/create`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ id, working_dir: process.cwd(), personality, medium: "cli" }),
+        signal: AbortSignal.timeout(2000),
+      });
+      if (response.status === 409) {
+        continue;
+      }
+      return id;
+    } catch {
+      return id;
+    }
+  }
+  throw new Error(`Could not allocate a free session id after ${SESSION_ID_ATTEMPTS} attempts`);
+}
+
+/** Locate the dere plugin marketplace: next to the CLI, or in a parent of cwd. */
+export function findPluginsPath(): string | null {
+  const here = fileURLToPath(import.meta.url);
+  const repoCandidate = resolve(dirname(here), "..", "..", "plugins");
+  if (existsSync(join(repoCandidate, ".claude-plugin", "marketplace.json"))) {
+    return repoCandidate;
+  }
+
+  let current = resolve(process.cwd());
+  while (true) {
+    const candidate = resolve(current, "plugins");
+    if (existsSync(join(candidate, ".claude-plugin", "marketplace.json"))) {
+      return candidate;
+    }
+    const parent = dirname(current);
+    if (parent === current) {
+      break;
+    }
+    current = parent;
+  }
+
+  return null;
+}
+
 class SettingsBuilder {
   private readonly personality: string | null;
   private readonly outputStyle: string | null;
@@ -224,6 +367,13 @@ class SettingsBuilder {
     this.dangerouslySkipPermissions = args.dangerouslySkipPermissions ?? false;
   }
 
+  /** Remove the generated settings/MCP files; safe to call more than once. */
+  cleanupTempFiles(): void {
+    for (const file of this.tempFiles.splice(0)) {
+      rmSync(file, { force: true });
+    }
+  }
+
   async build(): Promise<ClaudeCodeSettings> {
     const settings: ClaudeCodeSettings = { hooks: {}, statusLine: {}, env: {} };
 
@@ -299,31 +449,8 @@ class SettingsBuilder {
     return false;
   }
 
-  private findPluginsPath(): string | null {
-    const here = fileURLToPath(import.meta.url);
-    const repoCandidate = resolve(dirname(here), "..", "..", "plugins");
-    if (existsSync(join(repoCandidate, ".claude-plugin", "marketplace.json"))) {
-      return repoCandidate;
-    }
-
-    let current = resolve(process.cwd());
-    while (true) {
-      const candidate = resolve(current, "plugins");
-      if (existsSync(join(candidate, ".claude-plugin", "marketplace.json"))) {
-        return candidate;
-      }
-      const parent = dirname(current);
-      if (parent === current) {
-        break;
-      }
-      current = parent;
-    }
-
-    return null;
-  }
-
   private async addDerePlugins(settings: ClaudeCodeSettings): Promise<void> {
-    const pluginsPath = this.findPluginsPath();
+    const pluginsPath = findPluginsPath();
     if (!pluginsPath) {
       return;
     }
@@ -365,7 +492,7 @@ class SettingsBuilder {
   }
 
   private addStatusLine(settings: ClaudeCodeSettings): void {
-    const pluginsPath = this.findPluginsPath();
+    const pluginsPath = findPluginsPath();
     if (!pluginsPath) {
       return;
     }
@@ -450,6 +577,408 @@ async function composeSystemPrompt(personalities: string[]): Promise<string> {
   return prompts.join("\n\n");
 }
 
+const CONTEXT_MARKER = "{{CONTEXT}}";
+
+type ContextPlacement = "before" | "after";
+
+async function loadContextPlacement(): Promise<ContextPlacement> {
+  try {
+    const config = await loadProjectConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    return contextConfig.injection_placement === "before" ? "before" : "after";
+  } catch {
+    return "after";
+  }
+}
+
+/** [context].chars_per_token, the ratio hooks use to estimate token counts. */
+async function loadCharsPerToken(): Promise<number | null> {
+  try {
+    const config = await loadProjectConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    const value = Number(contextConfig.chars_per_token);
+    return Number.isFinite(value) && value > 0 ? value : null;
+  } catch {
+    return null;
+  }
+}
+
+/** [context].refresh_minutes, how often the prompt hook rebuilds relevant memory. */
+async function loadContextRefreshMinutes(): Promise<number | null> {
+  try {
+    const config = await loadProjectConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    const value = Number(contextConfig.refresh_minutes);
+    return Number.isFinite(value) && value >= 0 ? value : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * The [context] table of the nearest .dere.toml. The daemon reads only the
+ * global config, so hooks forward this with their context builds.
+ */
+async function loadProjectContextOverrides(): Promise<Record<string, unknown> | null> {
+  const path = findProjectConfigPath();
+  if (!path) {
+    return null;
+  }
+  try {
+    const data = parseTomlString(await readFile(path, "utf-8")) as Record<string, unknown>;
+    const context = data.context;
+    return context && typeof context === "object" && !Array.isArray(context)
+      ? (context as Record<string, unknown>)
+      : null;
+  } catch {
+    return null;
+  }
+}
+
+// Linux rejects any single argv string over 128KiB (MAX_ARG_STRLEN), which is
+// where an oversized --append-system-prompt fails; stay safely under it.
+const DEFAULT_MAX_SYSTEM_PROMPT_BYTES = 120_000;
+
+/** [context].max_system_prompt_bytes, the cap on the composed system prompt. */
+async function loadMaxSystemPromptBytes(): Promise<number> {
+  try {
+    const config = await loadProjectConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    const value = Number(contextConfig.max_system_prompt_bytes);
+    return Number.isFinite(value) && value > 0 ? value : DEFAULT_MAX_SYSTEM_PROMPT_BYTES;
+  } catch {
+    return DEFAULT_MAX_SYSTEM_PROMPT_BYTES;
+  }
+}
+
+/** The longest prefix of `text` that fits in `maxBytes` of UTF-8, whole characters only. */
+function truncateUtf8(text: string, maxBytes: number): string {
+  const bytes = Buffer.from(text, "utf-8");
+  if (bytes.length <= maxBytes) {
+    return text;
+  }
+  let end = maxBytes;
+  // Back up past continuation bytes so no character is split.
+  while (end > 0 && ((bytes[end] ?? 0) & 0xc0) === 0x80) {
+    end -= 1;
+  }
+  return bytes.subarray(0, end).toString("utf-8");
+}
+
+/**
+ * Trim a system prompt to `maxBytes` of UTF-8, cutting at the last paragraph
+ * (or line) break that fits so no section is left half-written.
+ */
+function capSystemPrompt(prompt: string, maxBytes: number): string {
+  const size = Buffer.byteLength(prompt, "utf-8");
+  if (size <= maxBytes) {
+    return prompt;
+  }
+  let cut = truncateUtf8(prompt, maxBytes);
+  let boundary = cut.lastIndexOf("\n\n");
+  if (boundary < cut.length / 2) {
+    boundary = cut.lastIndexOf("\n");
+  }
+  if (boundary > cut.length / 2) {
+    cut = cut.slice(0, boundary);
+  }
+  console.warn(
+    `Warning: system prompt is ${size} bytes, over the ${maxBytes}-byte limit ` +
+      "([context].max_system_prompt_bytes); truncated",
+  );
+  return cut.trimEnd();
+}
+
+/**
+ * Place dere context in the system prompt. An explicit {{CONTEXT}} marker in
+ * the prompt wins; otherwise context goes before or after it per
+ * [context].injection_placement.
+ */
+function injectContext(
+  prompt: string,
+  context: string,
+  placement: ContextPlacement,
+): string {
+  if (prompt.includes(CONTEXT_MARKER)) {
+    return prompt.replace(CONTEXT_MARKER, context);
+  }
+  if (!context) {
+    return prompt;
+  }
+  if (!prompt) {
+    return context;
+  }
+  return placement === "before" ? `${context}\n\n${prompt}` : `${prompt}\n\n${context}`;
+}
+
+/**
+ * Resolve --append-context values into one block of ad-hoc context. A value
+ * starting with @ names a file to read instead.
+ */
+async function readAppendedContext(values: string[]): Promise<string> {
+  const parts: string[] = [];
+  for (const value of values) {
+    if (!value.startsWith("@")) {
+      parts.push(value.trim());
+      continue;
+    }
+    const path = resolve(value.slice(1));
+    try {
+      parts.push((await readFile(path, "utf-8")).trim());
+    } catch (error) {
+      if ((error as NodeJS.ErrnoException).code === "ENOENT") {
+        throw new Error(`--append-context file not found: ${path}`);
+      }
+      throw new Error(`Failed to read --append-context file ${path}: ${String(error)}`);
+    }
+  }
+  return parts.filter(Boolean).join("\n\n");
+}
+
+/** Pull a user-supplied --append-system-prompt out of the passthrough args. */
+function takeCustomPrompt(passthrough: string[]): string {
+  const index = passthrough.indexOf("--append-system-prompt");
+  if (index === -1 || passthrough[index + 1] === undefined) {
+    return "";
+  }
+  const [, value] = passthrough.splice(index, 2);
+  return value ?? "";
+}
+
+// Claude flags that take a value, so that value isn't mistaken for the prompt.
+const CLAUDE_VALUE_FLAGS = new Set([
+  "--model",
+  "--fallback-model",
+  "--agent",
+  "--permission-mode",
+  "--allowedTools",
+  "--allowed-tools",
+  "--disallowedTools",
+  "--disallowed-tools",
+  "--add-dir",
+  "--append-system-prompt",
+  "--mcp-config",
+  "--settings",
+  "-r",
+  "--resume",
+  "--output-format",
+  "--input-format",
+  "--max-turns",
+  "--system-prompt",
+  "--session-id",
+  "--agents",
+  "--json-schema",
+  "--max-budget-usd",
+  "--setting-sources",
+  "--plugin-dir",
+  "--tools",
+  "--betas",
+]);
+
+// A single argv string is capped at 128KiB on Linux.
+const MAX_PROMPT_ARG_BYTES = 120_000;
+
+/** Index of the positional prompt in the passthrough args, or -1. */
+function findPromptIndex(passthrough: string[]): number {
+  for (let i = passthrough.length - 1; i >= 0; i -= 1) {
+    const arg = passthrough[i] ?? "";
+    if (!arg.startsWith("-") && !CLAUDE_VALUE_FLAGS.has(passthrough[i - 1] ?? "")) {
+      return i;
+    }
+  }
+  return -1;
+}
+
+function isPrintMode(passthrough: string[]): boolean {
+  return passthrough.includes("-p") || passthrough.includes("--print");
+}
+
+/**
+ * Everything piped into dere, or null when stdin is a terminal or empty. Only
+ * pipes and redirected files are read; a socket or device inherited from a
+ * parent process may never reach EOF.
+ */
+async function readPipedStdin(): Promise<string | null> {
+  if (process.stdin.isTTY) {
+    return null;
+  }
+  try {
+    const info = fstatSync(0);
+    if (!info.isFIFO() && !info.isFile()) {
+      return null;
+    }
+  } catch {
+    return null;
+  }
+  const chunks: Buffer[] = [];
+  for await (const chunk of process.stdin) {
+    chunks.push(Buffer.from(chunk as Uint8Array));
+  }
+  const text = Buffer.concat(chunks).toString("utf-8").trimEnd();
+  return text.trim() ? text : null;
+}
+
+/** Keyboard input for an interactive session whose stdin was a pipe. */
+function openTerminal(): number | null {
+  try {
+    return openSync("/dev/tty", "r");
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Layer the system prompt: personalities, then the mode prompt, then any
+ * --append-system-prompt, with `context` placed by injectContext and the
+ * result capped to [context].max_system_prompt_bytes.
+ */
+async function buildSystemPrompt(
+  parsed: ParsedArgs,
+  modePrompt: string | null,
+  context: string,
+): Promise<string> {
+  let systemPrompt = "";
+  if (!parsed.bare && parsed.personalities.length > 0) {
+    systemPrompt = await composeSystemPrompt(parsed.personalities);
+  }
+  if (modePrompt) {
+    systemPrompt = systemPrompt ? `${systemPrompt}\n\n${modePrompt}` : modePrompt;
+  }
+  // Merge a custom prompt so there's one --append-system-prompt and its
+  // {{CONTEXT}} marker (if any) is honored.
+  const customPrompt = takeCustomPrompt(parsed.passthrough);
+  if (customPrompt) {
+    systemPrompt = systemPrompt ? `${systemPrompt}\n\n${customPrompt}` : customPrompt;
+  }
+  systemPrompt = injectContext(systemPrompt, context, await loadContextPlacement());
+  return capSystemPrompt(systemPrompt, await loadMaxSystemPromptBytes());
+}
+
+/**
+ * `dere prompt preview`: print the system prompt a launch with these flags
+ * would pass to Claude, without starting a session. With --context, a
+ * delimited placeholder shows where resumed history and --append-context
+ * notes would land.
+ */
+export async function previewSystemPrompt(rawArgs: string[]): Promise<void> {
+  const showContext = rawArgs.includes("--context");
+  const parsed = parseArgs(rawArgs.filter((arg) => arg !== "--context"));
+  const projectConfig = await loadProjectConfig();
+  applyProfile(parsed, projectConfig);
+  applyConfigDefaults(parsed, projectConfig);
+
+  const modeDefinition = parsed.mode ? await loadModeDefinition(parsed.mode) : null;
+  const appendedContext = await readAppendedContext(parsed.appendContext);
+  const context = showContext
+    ? [
+        "---------- dere context ----------",
+        parsed.resume ? `(history from session ${parsed.resume})` : "(resumed session history)",
+        appendedContext,
+        "---------- end dere context ----------",
+      ]
+        .filter(Boolean)
+        .join("\n")
+    : appendedContext;
+  const systemPrompt = await buildSystemPrompt(parsed, modeDefinition?.prompt ?? null, context);
+  console.log(systemPrompt || "(empty system prompt)");
+}
+
+const PICKER_SESSION_LIMIT = 15;
+const PICKER_SUMMARY_CHARS = 80;
+
+/**
+ * Numbered menu of this directory's recent resumable sessions, with their
+ * summaries to tell them apart. Returns the chosen Claude session id, or null
+ * when there is nothing to pick or the user cancels.
+ */
+async function pickSessionToResume(): Promise<string | null> {
+  const daemonUrl = await resolveDaemonUrl();
+  const params = new URLSearchParams({
+    limit: String(PICKER_SESSION_LIMIT),
+    working_dir: process.cwd(),
+    resumable: "true",
+  });
+  let sessions: Array<{
+    id: number;
+    name: string | null;
+    personality: string | null;
+    last_activity: string;
+    summary: string | null;
+    claude_session_id: string | null;
+  }>;
+  try {
+    const response = await fetch(`${daemonUrl}/sessions/list?${params.toString()}`, {
+      signal: AbortSignal.timeout(2000),
+    });
+    const data = (await response.json()) as { sessions?: typeof sessions };
+    sessions = (data.sessions ?? []).filter((session) => session.claude_session_id);
+  } catch {
+    console.error("Daemon is not running; pass a session id with -r instead");
+    return null;
+  }
+  if (sessions.length === 0) {
+    console.error("No resumable sessions in this directory");
+    return null;
+  }
+
+  sessions.forEach((session, index) => {
+    const when = new Date(session.last_activity).toLocaleString();
+    const label = session.name ? ` ${session.name}` : "";
+    const personality = session.personality ? ` [${session.personality}]` : "";
+    const summary = (session.summary ?? "(no summary yet)").replace(/\s+/g, " ").trim();
+    const snippet =
+      summary.length > PICKER_SUMMARY_CHARS
+        ? `${summary.slice(0, PICKER_SUMMARY_CHARS)}...`
+        : summary;
+    console.log(`${String(index + 1).padStart(2)}) #${session.id}${label}${personality}  ${when}`);
+    console.log(`    ${snippet}`);
+  });
+
+  const rl = createInterface({ input: process.stdin, output: process.stdout });
+  try {
+    const answer = (await rl.question(`Resume which session? [1-${sessions.length}] `)).trim();
+    const choice = sessions[Number(answer) - 1];
+    if (!answer || !choice) {
+      return null;
+    }
+    return choice.claude_session_id;
+  } finally {
+    rl.close();
+  }
+}
+
+async function fetchResumeContext(resumeId: string): Promise<string> {
+  const controller = new AbortController();
+  const timeout = setTimeout(() => controller.abort(), 2000);
+  try {
+    const daemonUrl = await resolveDaemonUrl();
+    const url = new URL("/sessions/resume_context", daemonUrl);
+    url.searchParams.set("resume_id", resumeId);
+    const response = await fetch(url, { signal: controller.signal });
+    if (!response.ok) {
+      return "";
+    }
+    const data = (await response.json()) as { found?: boolean; context?: string };
+    if (!data.found) {
+      console.warn(`Note: ${resumeId} is not a dere session; resuming without dere context`);
+      return "";
+    }
+    return typeof data.context === "string" ? data.context : "";
+  } catch {
+    console.warn("Note: daemon unavailable; resuming without dere context");
+    return "";
+  } finally {
+    clearTimeout(timeout);
+  }
+}
+
+function shellQuote(arg: string): string {
+  if (/^[\w@%+=:,./-]+$/.test(arg)) {
+    return arg;
+  }
+  return `'${arg.replace(/'/g, "'\\''")}'`;
+}
+
 async function writeTempJson(data: Record<string, unknown>): Promise<string> {
   const filePath = join(
     tmpdir(),
@@ -459,11 +988,92 @@ async function writeTempJson(data: Record<string, unknown>): Promise<string> {
   return filePath;
 }
 
+function readStringList(value: unknown): string[] {
+  const items = Array.isArray(value) ? value : typeof value === "string" ? value.split(",") : [];
+  return items
+    .filter((item): item is string => typeof item === "string")
+    .map((item) => item.trim())
+    .filter(Boolean);
+}
+
+function readProfileString(profile: Record<string, unknown>, key: string): string | null {
+  const value = profile[key];
+  return typeof value === "string" && value.trim() ? value.trim() : null;
+}
+
+/**
+ * Expand `--profile=NAME` from the `[profiles.NAME]` table. Like config
+ * defaults, a profile only fills in what wasn't passed explicitly.
+ */
+function applyProfile(parsed: ParsedArgs, config: DereConfig): void {
+  if (!parsed.profile) {
+    return;
+  }
+  const profiles = ((config as Record<string, unknown>).profiles ?? {}) as Record<string, unknown>;
+  const profile = profiles[parsed.profile];
+  if (!profile || typeof profile !== "object") {
+    const known = Object.keys(profiles);
+    console.error(
+      `Error: unknown profile '${parsed.profile}'` +
+        (known.length > 0 ? ` (available: ${known.join(", ")})` : " (none in [profiles])"),
+    );
+    process.exit(1);
+  }
+  const table = profile as Record<string, unknown>;
+
+  if (!parsed.bare && parsed.personalities.length === 0) {
+    parsed.personalities.push(...readStringList(table.personalities ?? table.personality));
+  }
+  parsed.model ??= readProfileString(table, "model");
+  parsed.mode ??= readProfileString(table, "mode");
+  parsed.outputStyle ??= readProfileString(table, "output_style");
+  parsed.permissionMode ??= readProfileString(table, "permission_mode");
+  if (parsed.mcpServers.length === 0) {
+    parsed.mcpServers.push(...readStringList(table.mcp));
+  }
+}
+
+/**
+ * Fill in anything the user didn't pass on the command line from the merged
+ * project/global config. CLI flags always win.
+ */
+function applyConfigDefaults(parsed: ParsedArgs, config: DereConfig): void {
+  const record = config as Record<string, unknown>;
+  if (!parsed.bare && parsed.personalities.length === 0) {
+    parsed.personalities.push(...readStringList(record.default_personality));
+  }
+  if (!parsed.model && typeof record.model === "string" && record.model.trim()) {
+    parsed.model = record.model.trim();
+  }
+  if (!parsed.mode && typeof record.mode === "string" && record.mode.trim()) {
+    parsed.mode = record.mode.trim();
+  }
+}
+
+/** A generated file's contents for --dry-run, or why it couldn't be read. */
+async function readForDryRun(path: string): Promise<string> {
+  try {
+    return await readFile(path, "utf-8");
+  } catch (error) {
+    return `  (unreadable: ${String(error)})`;
+  }
+}
+
+// The daemon's tag rule; it drops every launch tag if one fails it.
+const SESSION_TAG_PATTERN = /^[a-z0-9][a-z0-9._-]{0,63}$/;
+
 export async function runClaude(rawArgs: string[]): Promise<void> {
   const parsed = parseArgs(rawArgs);
+  const projectConfig = await loadProjectConfig();
+  applyProfile(parsed, projectConfig);
+  applyConfigDefaults(parsed, projectConfig);
+  if (parsed.listSessions && !parsed.resume && !parsed.continueConv) {
+    parsed.resume = await pickSessionToResume();
+    if (!parsed.resume) {
+      process.exit(1);
+    }
+  }
 
-  const sessionId = generateSessionId();
-  process.env.DERE_SESSION_ID = String(sessionId);
   if (parsed.mcpServers.length > 0) {
     process.env.DERE_MCP_SERVERS = parsed.mcpServers.join(",");
   }
@@ -473,16 +1083,73 @@ export async function runClaude(rawArgs: string[]): Promise<void> {
   if (parsed.mode) {
     process.env.DERE_MODE = parsed.mode;
   }
+  // Skip memory context entirely; capture and background tasks still run.
+  if (parsed.fast) {
+    process.env.DERE_CONTEXT_MODE = "none";
+  }
+  // Projects under [capture].exclude_paths leave nothing behind: no session
+  // row, no conversations, and no background processing.
+  const captureExcluded = isCaptureExcluded(process.cwd(), captureExcludePaths(projectConfig));
+  if (captureExcluded) {
+    process.env.DERE_NO_CAPTURE = "1";
+  }
+  // Bare sessions are throwaway by default; skip all background processing.
+  const disabledTasks = parsed.bare || captureExcluded
+    ? ["entities", "summary", "embeddings"]
+    : Array.from(parsed.disabledTasks);
+  if (disabledTasks.length > 0) {
+    process.env.DERE_DISABLED_TASKS = disabledTasks.join(",");
+  }
+  const tags = parsed.tags.map((tag) => tag.trim().toLowerCase()).filter(Boolean);
+  const invalidTags = tags.filter((tag) => !SESSION_TAG_PATTERN.test(tag));
+  if (invalidTags.length > 0) {
+    console.error(
+      `Error: invalid --tag ${invalidTags.map((tag) => `'${tag}'`).join(", ")}; tags are ` +
+        "letters, digits, '.', '_' and '-', up to 64 characters, starting with a letter or digit",
+    );
+    process.exit(1);
+  }
+  if (tags.length > 0) {
+    process.env.DERE_SESSION_TAGS = tags.join(",");
+  }
   process.env.DERE_SESSION_TYPE = parsed.continueConv
     ? "continue"
     : parsed.resume
       ? "resume"
       : "new";
+  const charsPerToken = await loadCharsPerToken();
+  if (charsPerToken !== null) {
+    process.env.DERE_CHARS_PER_TOKEN = String(charsPerToken);
+  }
+  const contextRefreshMinutes = await loadContextRefreshMinutes();
+  if (contextRefreshMinutes !== null) {
+    process.env.DERE_CONTEXT_REFRESH_MINUTES = String(contextRefreshMinutes);
+  }
+  const projectContext = await loadProjectContextOverrides();
+  if (projectContext) {
+    process.env.DERE_PROJECT_CONTEXT = JSON.stringify(projectContext);
+  }
+
+  // Capture, embeddings, and summaries all run in the daemon; without it the
+  // session leaves no memory, so say so instead of failing silently.
+  const daemonAvailable = await checkDaemonAvailable();
+  if (!parsed.bare && !parsed.dryRun && !captureExcluded && !daemonAvailable) {
+    console.warn(
+      "Note: daemon not running; this session won't be remembered " +
+        "(start it with `dere daemon start`)",
+    );
+  }
 
   if (!parsed.bare && parsed.personalities.length === 0) {
     parsed.personalities.push("tsun");
   }
 
+  const sessionId =
+    parsed.dryRun || captureExcluded
+      ? generateSessionId()
+      : await reserveSessionId(parsed.personalities[0] ?? null, daemonAvailable);
+  process.env.DERE_SESSION_ID = String(sessionId);
+
   let announcement: string | null = null;
   if (parsed.personalities.length > 0) {
     const loader = new PersonalityLoader();
@@ -515,6 +1182,8 @@ export async function runClaude(rawArgs: string[]): Promise<void> {
     companyAnnouncements: announcement ? [announcement] : null,
     dangerouslySkipPermissions: parsed.dangerouslySkipPermissions,
   });
+  // process.exit skips `finally`, so generated files are also removed on exit.
+  process.once("exit", () => builder.cleanupTempFiles());
 
   const settings = await builder.build();
   let settingsPath: string | null = null;
@@ -524,10 +1193,19 @@ export async function runClaude(rawArgs: string[]): Promise<void> {
   }
 
   try {
-    let systemPrompt = "";
-    if (!parsed.bare && parsed.personalities.length > 0) {
-      systemPrompt = await composeSystemPrompt(parsed.personalities);
+    const modeDefinition = parsed.mode ? await loadModeDefinition(parsed.mode) : null;
+    if (modeDefinition?.wellness) {
+      process.env.DERE_CONTEXT_HINT = "wellness";
     }
+    let resumeContext = "";
+    if (!parsed.bare && parsed.resume) {
+      resumeContext = await fetchResumeContext(parsed.resume);
+    }
+    // One-shot notes from --append-context ride along with history context,
+    // in --bare mode too.
+    const appendedContext = await readAppendedContext(parsed.appendContext);
+    const context = [resumeContext, appendedContext].filter(Boolean).join("\n\n");
+    const systemPrompt = await buildSystemPrompt(parsed, modeDefinition?.prompt ?? null, context);
 
     const effectivePermissionMode =
       parsed.permissionMode ?? (parsed.dangerouslySkipPermissions ? "bypassPermissions" : null);
@@ -569,10 +1247,12 @@ export async function runClaude(rawArgs: string[]): Promise<void> {
       cmd.push("--append-system-prompt", systemPrompt);
     }
 
+    // Only the config dere generated; a --mcp-config passed through is the user's.
+    let mcpConfigPath: string | null = null;
     if (parsed.mcpServers.length > 0) {
       try {
         const configDir = dirname(getConfigPath());
-        const mcpConfigPath = await buildMcpConfig(parsed.mcpServers, configDir);
+        mcpConfigPath = await buildMcpConfig(parsed.mcpServers, configDir);
         if (mcpConfigPath) {
           cmd.push("--mcp-config", mcpConfigPath);
           builder.tempFiles.push(mcpConfigPath);
@@ -583,22 +1263,60 @@ export async function runClaude(rawArgs: string[]): Promise<void> {
       }
     }
 
+    // `cat error.log | dere "explain this"`: the piped text joins the prompt so
+    // Claude and the capture hook both see it as one message. Print mode takes
+    // the prompt on stdin; an interactive session gets it as the opening
+    // prompt and reads the keyboard from the terminal.
+    let stdinPrompt: string | null = null;
+    let terminalFd: number | null = null;
+    // --dry-run only prints the command, so leave stdin for whoever comes next.
+    const piped =
+      parsed.dryRun || parsed.passthrough.includes("--input-format")
+        ? null
+        : await readPipedStdin();
+    if (piped !== null) {
+      const promptIndex = findPromptIndex(parsed.passthrough);
+      const [prompt] = promptIndex >= 0 ? parsed.passthrough.splice(promptIndex, 1) : [];
+      const combined = prompt ? `${prompt}\n\n${piped}` : piped;
+      terminalFd = isPrintMode(parsed.passthrough) ? null : openTerminal();
+      if (terminalFd === null) {
+        if (!isPrintMode(parsed.passthrough)) {
+          console.warn("Note: no terminal for an interactive session; running with -p");
+          parsed.passthrough.push("-p");
+        }
+        stdinPrompt = combined;
+      } else if (Buffer.byteLength(combined) > MAX_PROMPT_ARG_BYTES) {
+        console.warn("Warning: piped input is too large for an interactive prompt; use -p");
+        parsed.passthrough.push(truncateUtf8(combined, MAX_PROMPT_ARG_BYTES));
+      } else {
+        parsed.passthrough.push(combined);
+      }
+    }
+
     if (parsed.passthrough.length > 0) {
       cmd.push(...parsed.passthrough);
     }
 
     if (parsed.dryRun) {
-      console.log("Command:", cmd.join(" "));
+      console.log("Command:", cmd.map(shellQuote).join(" "));
       console.log("\nEnvironment:");
       for (const key of Object.keys(process.env).sort()) {
         if (key.startsWith("DERE_")) {
           console.log(`  ${key}=${process.env[key]}`);
         }
       }
+      console.log("\nSystem prompt:");
+      console.log(systemPrompt || "  (none)");
       if (settingsPath) {
         console.log(`\nSettings: ${settingsPath}`);
-        console.log(await readFile(settingsPath, "utf-8"));
+        console.log(await readForDryRun(settingsPath));
       }
+      if (mcpConfigPath) {
+        console.log(`\nMCP config: ${mcpConfigPath}`);
+        console.log(await readForDryRun(mcpConfigPath));
+      }
+      // Leave the generated files on disk for inspection.
+      builder.tempFiles.length = 0;
       return;
     }
 
@@ -606,7 +1324,15 @@ export async function runClaude(rawArgs: string[]): Promise<void> {
     if (!command) {
       throw new Error("No command provided to launch Claude CLI");
     }
-    const child = spawn(command, commandArgs, { stdio: "inherit" });
+    const child = spawn(command, commandArgs, {
+      stdio: [stdinPrompt !== null ? "pipe" : (terminalFd ?? "inherit"), "inherit", "inherit"],
+    });
+    if (stdinPrompt !== null) {
+      child.stdin?.end(stdinPrompt);
+    }
+    if (terminalFd !== null) {
+      closeSync(terminalFd);
+    }
 
     const forwardSignal = (signal: NodeJS.Signals) => {
       child.kill(signal);
@@ -615,24 +1341,23 @@ export async function runClaude(rawArgs: string[]): Promise<void> {
     process.on("SIGTERM", forwardSignal);
 
     const exitCode: number = await new Promise((resolve) => {
+      // A missing binary surfaces here, not as a thrown error.
+      child.on("error", (error: NodeJS.ErrnoException) => {
+        console.error(
+          error.code === "ENOENT"
+            ? "Error: 'claude' not found. Install Claude CLI."
+            : `Error: failed to start claude: ${error.message}`,
+        );
+        resolve(1);
+      });
       child.on("close", (code) => resolve(code ?? 0));
     });
 
     process.exit(exitCode);
   } catch (error) {
-    if (String(error).includes("ENOENT")) {
-      console.error("Error: 'claude' not found. Install Claude CLI.");
-    } else {
-      console.error(String(error));
-    }
+    console.error(String(error));
     process.exit(1);
   } finally {
-    for (const file of builder.tempFiles) {
-      try {
-        await unlink(file);
-      } catch {
-        // ignore
-      }
-    }
+    builder.cleanupTempFiles();
   }
 }
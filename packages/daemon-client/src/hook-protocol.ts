@@ -29,6 +29,8 @@ export interface GetSessionContextRequest {
   user_id: string;
   working_dir?: string;
   medium?: string; // 'cli' | 'ui' | 'matrix' | etc.
+  deadline_ms?: number; // Return "building" if not ready by then; result is cached for retry
+  context_config?: Record<string, unknown>; // Project [context] values, over the global config
 }
 
 export interface GetSessionContextResponse {
@@ -78,6 +80,7 @@ export interface CaptureConversationRequest {
 export interface CaptureConversationResponse {
   status: "captured" | "error";
   conversation_id?: number;
+  background?: string[]; // Background steps started, e.g. 'entities', 'embeddings'
 }
 
 // -----------------------------------------------------------------------------
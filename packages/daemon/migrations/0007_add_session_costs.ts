@@ -0,0 +1,28 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Cumulative per-session cost as reported by the Claude Code statusline
+  await sql`
+    CREATE TABLE IF NOT EXISTS session_costs (
+      session_id BIGINT PRIMARY KEY,
+      total_cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+      total_duration_ms BIGINT NOT NULL DEFAULT 0,
+      model TEXT,
+      working_dir TEXT,
+      personality TEXT,
+      created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+  `.execute(db);
+
+  await sql`
+    CREATE INDEX IF NOT EXISTS session_costs_updated_at_idx
+    ON session_costs (updated_at)
+  `.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`DROP TABLE IF EXISTS session_costs`.execute(db);
+}
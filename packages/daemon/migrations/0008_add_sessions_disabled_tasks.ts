@@ -0,0 +1,12 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Background task types (entities, summary, embeddings) the session opted out of
+  await sql`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS disabled_tasks text[] NOT NULL DEFAULT '{}'`.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`ALTER TABLE sessions DROP COLUMN IF EXISTS disabled_tasks`.execute(db);
+}
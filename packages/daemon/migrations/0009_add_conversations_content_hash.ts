@@ -0,0 +1,19 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Hash of (message_type, prompt, time bucket) set by the capture route so
+  // duplicate hook deliveries collide instead of storing the prompt twice
+  await sql`ALTER TABLE conversations ADD COLUMN IF NOT EXISTS content_hash text`.execute(db);
+  await sql`
+    CREATE UNIQUE INDEX IF NOT EXISTS conversations_session_content_hash_idx
+    ON conversations (session_id, content_hash)
+    WHERE content_hash IS NOT NULL
+  `.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`DROP INDEX IF EXISTS conversations_session_content_hash_idx`.execute(db);
+  await sql`ALTER TABLE conversations DROP COLUMN IF EXISTS content_hash`.execute(db);
+}
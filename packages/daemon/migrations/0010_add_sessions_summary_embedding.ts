@@ -0,0 +1,13 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Embedding of sessions.summary for `dere summaries search`; cleared whenever
+  // the summary changes and refilled by the embedding loop
+  await sql`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS summary_embedding vector(1536)`.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`ALTER TABLE sessions DROP COLUMN IF EXISTS summary_embedding`.execute(db);
+}
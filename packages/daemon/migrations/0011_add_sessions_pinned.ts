@@ -0,0 +1,12 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Pinned sessions are never removed by session cleanup
+  await sql`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS pinned boolean NOT NULL DEFAULT false`.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`ALTER TABLE sessions DROP COLUMN IF EXISTS pinned`.execute(db);
+}
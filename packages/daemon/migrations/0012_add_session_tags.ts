@@ -0,0 +1,21 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Free-form labels ("bugfix", "spike") for filtering sessions later
+  await sql`
+    CREATE TABLE IF NOT EXISTS session_tags (
+      session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+      tag TEXT NOT NULL,
+      created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      PRIMARY KEY (session_id, tag)
+    )
+  `.execute(db);
+
+  await sql`CREATE INDEX IF NOT EXISTS session_tags_tag_idx ON session_tags (tag)`.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`DROP TABLE IF EXISTS session_tags`.execute(db);
+}
@@ -0,0 +1,25 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Which model produced each vector, so searches only compare vectors from
+  // the current model and the backfill can re-embed the rest
+  await sql`ALTER TABLE conversation_blocks ADD COLUMN IF NOT EXISTS embedding_model text`.execute(db);
+  await sql`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS summary_embedding_model text`.execute(db);
+
+  // Everything embedded so far came from the only model dere has used
+  await sql`
+    UPDATE conversation_blocks SET embedding_model = 'text-embedding-3-small'
+    WHERE content_embedding IS NOT NULL AND embedding_model IS NULL
+  `.execute(db);
+  await sql`
+    UPDATE sessions SET summary_embedding_model = 'text-embedding-3-small'
+    WHERE summary_embedding IS NOT NULL AND summary_embedding_model IS NULL
+  `.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`ALTER TABLE conversation_blocks DROP COLUMN IF EXISTS embedding_model`.execute(db);
+  await sql`ALTER TABLE sessions DROP COLUMN IF EXISTS summary_embedding_model`.execute(db);
+}
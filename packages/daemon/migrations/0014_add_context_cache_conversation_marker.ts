@@ -0,0 +1,15 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Newest conversation timestamp when the cache was built; anything newer
+  // means the cached context no longer reflects the session
+  await sql`
+    ALTER TABLE context_cache ADD COLUMN IF NOT EXISTS max_conversation_timestamp integer
+  `.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`ALTER TABLE context_cache DROP COLUMN IF EXISTS max_conversation_timestamp`.execute(db);
+}
@@ -0,0 +1,27 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Extra summaries regenerated at another length or style; sessions.summary
+  // stays the canonical one used for context and search
+  await sql`
+    CREATE TABLE IF NOT EXISTS session_summary_variants (
+      id SERIAL PRIMARY KEY,
+      session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+      summary_type TEXT NOT NULL,
+      max_words INTEGER NOT NULL,
+      summary TEXT NOT NULL,
+      created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+  `.execute(db);
+
+  await sql`
+    CREATE INDEX IF NOT EXISTS session_summary_variants_session_idx
+    ON session_summary_variants (session_id)
+  `.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`DROP TABLE IF EXISTS session_summary_variants`.execute(db);
+}
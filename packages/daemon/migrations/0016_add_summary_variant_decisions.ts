@@ -0,0 +1,20 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // "Decided" and "To-do" parts of decisions-style summaries
+  await sql`
+    ALTER TABLE session_summary_variants
+    ADD COLUMN IF NOT EXISTS decisions TEXT,
+    ADD COLUMN IF NOT EXISTS next_steps TEXT
+  `.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`
+    ALTER TABLE session_summary_variants
+    DROP COLUMN IF EXISTS next_steps,
+    DROP COLUMN IF EXISTS decisions
+  `.execute(db);
+}
@@ -0,0 +1,29 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // Follow-ups ("add tests for X") pulled out of sessions when they're
+  // summarized; unrelated to the task_queue processing table
+  await sql`
+    CREATE TABLE IF NOT EXISTS action_items (
+      id SERIAL PRIMARY KEY,
+      session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+      working_dir TEXT NOT NULL,
+      text TEXT NOT NULL,
+      status TEXT NOT NULL DEFAULT 'open',
+      created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+      completed_at TIMESTAMPTZ,
+      UNIQUE (session_id, text)
+    )
+  `.execute(db);
+
+  await sql`
+    CREATE INDEX IF NOT EXISTS action_items_working_dir_status_idx
+    ON action_items (working_dir, status)
+  `.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`DROP TABLE IF EXISTS action_items`.execute(db);
+}
@@ -0,0 +1,27 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // What Claude did during a session (files read and edited, commands run),
+  // recorded by the PostToolUse hook alongside the conversation text
+  await sql`
+    CREATE TABLE IF NOT EXISTS tool_events (
+      id SERIAL PRIMARY KEY,
+      session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+      tool_name TEXT NOT NULL,
+      target TEXT,
+      command TEXT,
+      created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    )
+  `.execute(db);
+
+  await sql`
+    CREATE INDEX IF NOT EXISTS tool_events_session_id_idx
+    ON tool_events (session_id, created_at)
+  `.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`DROP TABLE IF EXISTS tool_events`.execute(db);
+}
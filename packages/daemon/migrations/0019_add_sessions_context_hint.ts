@@ -0,0 +1,13 @@
+import { sql, type Kysely } from "kysely";
+
+import type { Database } from "../src/db-types.js";
+
+export async function up(db: Kysely<Database>): Promise<void> {
+  // The entity extraction hint (coding, general, wellness) the session was
+  // captured with, so reprocessing extracts its messages the same way
+  await sql`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS context_hint text`.execute(db);
+}
+
+export async function down(db: Kysely<Database>): Promise<void> {
+  await sql`ALTER TABLE sessions DROP COLUMN IF EXISTS context_hint`.execute(db);
+}
@@ -1118,7 +1118,7 @@ export function registerAgentWebSocket(app: Hono) {
             sendError(ws, state, "Query already in progress", true);
             return;
           }
-          log.agent.info("Starting query", { sessionId: state.sessionId, promptPreview: prompt.slice(0, 50) });
+          log.agent.debug("Starting query", { sessionId: state.sessionId, promptPreview: prompt.slice(0, 50) });
 
           const sessionId = state.sessionId;
           const config = state.config;
@@ -1342,7 +1342,7 @@ export function registerAgentWebSocket(app: Hono) {
             if (config.output_style && config.output_style !== "default") {
               outputStyleSettingsPath = await writeOutputStyleSettingsFile(config.output_style);
             }
-            log.agent.info("SDK options", {
+            log.agent.debug("SDK options", {
               outputStyle: config.output_style,
               cwd: effectiveCwd,
               isVirtualPath,
@@ -14,17 +14,31 @@ import { registerSearchRoutes } from "./routes/search.js";
 import { registerMetricsRoutes } from "./metrics.js";
 import { registerAgentWebSocket, websocket as agentWebsocket } from "./agents/ws.js";
 import { registerConversationRoutes } from "./sessions/conversations.js";
+import { registerActionItemRoutes } from "./sessions/action-items.js";
+import { registerTopicRoutes } from "./sessions/topics.js";
+import { registerToolEventRoutes } from "./sessions/tool-events.js";
 import { registerQueueRoutes } from "./routes/queue.js";
 import { registerStatusRoutes } from "./routes/status.js";
+import { registerCostRoutes } from "./routes/costs.js";
 import { registerContextRoutes } from "./context/index.js";
+import { registerKnowledgeGraphRoutes } from "./knowledge-graph.js";
 import { registerPersonalityRoutes } from "./personalities/api.js";
 import { registerSystemRoutes } from "./routes/system.js";
 import { registerLlmRoutes } from "./routes/llm.js";
 import { registerSwarmRoutes } from "./swarm/index.js";
+import { errorResponse } from "./errors.js";
+import { log } from "./logger.js";
 
 export function createApp(): { app: Hono; websocket: typeof agentWebsocket } {
   const app = new Hono();
 
+  // Uncaught route errors carry an application error code so clients can
+  // decide between retrying and proceeding without the result.
+  app.onError((error, c) => {
+    log.daemon.warn("Request failed", { path: c.req.path, error: String(error) });
+    return errorResponse(c, error);
+  });
+
   // tRPC handler
   app.all("/trpc/*", async (c) => {
     return fetchRequestHandler({
@@ -46,9 +60,14 @@ export function createApp(): { app: Hono; websocket: typeof agentWebsocket } {
   registerSearchRoutes(app);
   registerMetricsRoutes(app);
   registerConversationRoutes(app);
+  registerActionItemRoutes(app);
+  registerTopicRoutes(app);
+  registerToolEventRoutes(app);
   registerQueueRoutes(app);
   registerStatusRoutes(app);
+  registerCostRoutes(app);
   registerContextRoutes(app);
+  registerKnowledgeGraphRoutes(app);
   registerPersonalityRoutes(app);
   registerLlmRoutes(app);
   registerSwarmRoutes(app);
@@ -20,14 +20,25 @@ import {
 import { getDb } from "../db.js";
 import {
   ensureSession,
+  getFreshContextCache,
   upsertContextCache,
   mergeContextCacheMetadata,
+  type SessionResult,
 } from "../db-utils.js";
 import { buildContextMetadata } from "./tracking.js";
 import { log } from "../logger.js";
+import { findContinuationParent, getSessionChain } from "../sessions/chain.js";
 
 const execFileAsync = promisify(execFile);
 
+// Age (in days) at which a context candidate's relevance is halved. Off by
+// default: a half-life replaces the reranker's order with similarity and age.
+const DEFAULT_RECENCY_HALF_LIFE_DAYS = 0;
+const DEFAULT_CONTEXT_CACHE_TTL_MINUTES = 30;
+// Per-prompt memory mix: entities follow context_depth; facts and events are
+// only capped by the search itself unless configured (0 = no separate cap).
+const DEFAULT_MEMORY_ENTITIES = 5;
+
 type JsonRecord = Record<string, unknown>;
 type WeatherContext = {
   temperature?: string;
@@ -189,22 +200,57 @@ async function getEmotionSummary(sessionId: number | null): Promise<string | nul
   return `Context: User showing signs of ${name}. ${guidance}`;
 }
 
-async function getConversationContext(sessionId: number): Promise<string | null> {
-  const maxAgeMinutes = 30;
-  const minTimestamp = new Date(Date.now() - maxAgeMinutes * 60 * 1000);
+/**
+ * `[context]` from the global config, with a launch's project values on top.
+ * The CLI reads the project's `.dere.toml` and its hooks forward that
+ * `[context]` table as `context_config`.
+ */
+async function loadContextConfig(overrides?: unknown): Promise<Record<string, unknown>> {
+  let contextConfig: Record<string, unknown> = {};
+  try {
+    const config = await loadConfig();
+    contextConfig = (config.context ?? {}) as Record<string, unknown>;
+  } catch {
+    // defaults apply
+  }
+  if (overrides && typeof overrides === "object" && !Array.isArray(overrides)) {
+    return { ...contextConfig, ...(overrides as Record<string, unknown>) };
+  }
+  return contextConfig;
+}
 
-  const db = await getDb();
-  const row = await db
-    .selectFrom("context_cache")
-    .select(["context_text"])
-    .where("session_id", "=", sessionId)
-    .where("created_at", ">=", minTimestamp)
-    .orderBy("created_at", "desc")
-    .limit(1)
-    .executeTakeFirst();
+/** Read `[context].cache_ttl_minutes`, how long a built context stays usable. */
+async function loadContextCacheTtlMinutes(overrides?: unknown): Promise<number> {
+  const contextConfig = await loadContextConfig(overrides);
+  const value = readNumber(contextConfig.cache_ttl_minutes);
+  return value !== null && value > 0 ? value : DEFAULT_CONTEXT_CACHE_TTL_MINUTES;
+}
 
-  const context = row?.context_text ?? "";
-  return context.trim() ? context : null;
+type MemoryCounts = { entities: number; facts: number; events: number };
+
+/** Read `[context].memory_entities`, `memory_facts`, and `memory_events`. */
+async function loadMemoryCounts(overrides?: unknown): Promise<MemoryCounts> {
+  const counts: MemoryCounts = { entities: DEFAULT_MEMORY_ENTITIES, facts: 0, events: 0 };
+  const contextConfig = await loadContextConfig(overrides);
+  const entities = readNumber(contextConfig.memory_entities);
+  const facts = readNumber(contextConfig.memory_facts);
+  const events = readNumber(contextConfig.memory_events);
+  if (entities !== null && entities > 0) {
+    counts.entities = Math.floor(entities);
+  }
+  if (facts !== null && facts >= 0) {
+    counts.facts = Math.floor(facts);
+  }
+  if (events !== null && events >= 0) {
+    counts.events = Math.floor(events);
+  }
+  return counts;
+}
+
+async function getConversationContext(sessionId: number): Promise<string | null> {
+  const db = await getDb();
+  const cached = await getFreshContextCache(db, sessionId, await loadContextCacheTtlMinutes());
+  return cached.context;
 }
 
 async function buildFullContextXml(args: { sessionId: number | null }): Promise<string> {
@@ -568,6 +614,178 @@ async function fetchFactRoles(factUuids: string[], groupId: string): Promise<Map
   return map;
 }
 
+type SessionStartResult = {
+  status: "ready" | "disabled";
+  context: string;
+  session_type?: "code" | "conversational";
+  project_name?: string | null;
+};
+
+// In-flight session-start builds keyed by session id, so a client that gave up
+// at its deadline and retries joins the running build instead of starting another.
+const sessionStartBuilds = new Map<number, Promise<SessionStartResult>>();
+
+/**
+ * Summaries from every ancestor this session continues, oldest first, so a
+ * long `-c` chain keeps its history instead of starting from the last hop.
+ * A positive `limit` keeps only the nearest ancestors. Without `framing` the
+ * sessions aren't labelled with their personality.
+ */
+async function buildSessionChainContext(
+  sessionId: number,
+  limit: number,
+  framing: boolean,
+): Promise<string> {
+  try {
+    const chain = await getSessionChain(sessionId);
+    const ancestors = chain.slice(limit > 0 ? Math.max(0, chain.length - 1 - limit) : 0, -1);
+    const parts = ancestors
+      .filter((session) => session.summary)
+      .map((session) =>
+        renderTextTag("session", session.summary ?? "", {
+          indent: 2,
+          attrs: framing
+            ? { id: session.id, personality: session.personality }
+            : { id: session.id },
+        }),
+      );
+    return parts.length > 0 ? renderTag("session_chain", parts.join("\n")) : "";
+  } catch (error) {
+    log.daemon.warn("Session chain lookup failed", { error: String(error) });
+    return "";
+  }
+}
+
+async function buildSessionStartContext(args: {
+  sessionId: number;
+  userId: string | null;
+  session: SessionResult;
+  contextOverrides?: unknown;
+}): Promise<SessionStartResult> {
+  const { sessionId, userId, session } = args;
+  const db = await getDb();
+
+  let sessionStartEnabled = true;
+  let sessionStartLimit = 5;
+  let sessionStartGitCommits = 5;
+  let sessionStartConversationalDays = 30;
+  let sessionStartCodeDays = 7;
+  let sessionChainSummaries = true;
+  let sessionChainLimit = 0;
+  let personalityFraming = true;
+
+  try {
+    const contextConfig = await loadContextConfig(args.contextOverrides);
+    if (typeof contextConfig.session_start_enabled === "boolean") {
+      sessionStartEnabled = contextConfig.session_start_enabled;
+    }
+    if (typeof contextConfig.session_start_limit === "number") {
+      sessionStartLimit = contextConfig.session_start_limit;
+    }
+    if (typeof contextConfig.session_start_git_commits === "number") {
+      sessionStartGitCommits = contextConfig.session_start_git_commits;
+    }
+    if (typeof contextConfig.session_start_conversational_days === "number") {
+      sessionStartConversationalDays = contextConfig.session_start_conversational_days;
+    }
+    if (typeof contextConfig.session_start_code_days === "number") {
+      sessionStartCodeDays = contextConfig.session_start_code_days;
+    }
+    if (typeof contextConfig.session_chain_summaries === "boolean") {
+      sessionChainSummaries = contextConfig.session_chain_summaries;
+    }
+    if (typeof contextConfig.session_chain_limit === "number") {
+      sessionChainLimit = contextConfig.session_chain_limit;
+    }
+    personalityFraming = contextConfig.personality_framing !== false;
+  } catch {
+    // defaults already set
+  }
+
+  if (!sessionStartEnabled) {
+    return { status: "disabled", context: "" };
+  }
+
+  const sessionType = await detectSessionType({
+    medium: session.medium,
+    working_dir: session.working_dir,
+  });
+  let contextText = "";
+  let projectName: string | null = null;
+
+  try {
+    if (await graphAvailable()) {
+      if (sessionType === "code") {
+        projectName = extractProjectName(session.working_dir);
+        const query = projectName ? `recent work in ${projectName}` : "recent code work";
+        const cutoff = new Date(Date.now() - sessionStartCodeDays * 24 * 60 * 60 * 1000);
+        const filters: SearchFilters = {
+          created_at: { operator: "greater_than_equal", value: cutoff },
+        };
+
+        const results = await searchGraph({
+          query,
+          groupId: userId ?? session.user_id ?? "default",
+          limit: sessionStartLimit,
+          rerankMethod: "episode_mentions",
+          filters,
+        });
+
+        const combined = [...results.nodes, ...results.facts];
+        const commits = await getRecentGitCommits(session.working_dir, sessionStartGitCommits);
+        contextText = buildCodeSessionContext(projectName, combined, commits, sessionStartLimit);
+      } else {
+        const query = "recent conversations and entities discussed";
+        const cutoff = new Date(Date.now() - sessionStartConversationalDays * 24 * 60 * 60 * 1000);
+        const filters: SearchFilters = {
+          created_at: { operator: "greater_than_equal", value: cutoff },
+        };
+
+        const results = await searchGraph({
+          query,
+          groupId: userId ?? session.user_id ?? "default",
+          limit: sessionStartLimit,
+          rerankMethod: "recency",
+          filters,
+        });
+
+        const combined = [...results.nodes, ...results.facts];
+        contextText = buildConversationalContext(combined, sessionStartLimit);
+      }
+    }
+  } catch (error) {
+    log.daemon.warn("Session-start context build failed", { error: String(error) });
+    contextText = `<session_start_context type="${sessionType}"><error>Context unavailable</error></session_start_context>`;
+  }
+
+  if (sessionChainSummaries) {
+    const chainText = await buildSessionChainContext(
+      sessionId,
+      sessionChainLimit,
+      personalityFraming,
+    );
+    if (chainText) {
+      contextText = contextText ? `${chainText}\n\n${contextText}` : chainText;
+    }
+  }
+
+  const cacheMetadata = {
+    session_start_queried: true,
+    session_start_results: contextText,
+    session_type: sessionType,
+    query_timestamp: nowSeconds(),
+  };
+
+  await mergeContextCacheMetadata(db, sessionId, cacheMetadata);
+
+  return {
+    status: "ready",
+    context: contextText,
+    session_type: sessionType,
+    project_name: projectName,
+  };
+}
+
 export function registerContextRoutes(app: Hono): void {
   app.post("/context/build", async (c) => {
     const payload = await parseJson<Record<string, unknown>>(c.req.raw);
@@ -578,20 +796,47 @@ export function registerContextRoutes(app: Hono): void {
     const sessionId = typeof payload.session_id === "number" ? payload.session_id : null;
     const projectPath = typeof payload.project_path === "string" ? payload.project_path : "";
     const userId = typeof payload.user_id === "string" ? payload.user_id : null;
-    const contextDepth = toNumber(payload.context_depth, 5);
+    const memoryCounts = await loadMemoryCounts(payload.context_config);
+    const contextDepth = toNumber(payload.context_depth, memoryCounts.entities);
+    const factCount = toNumber(payload.fact_count, memoryCounts.facts);
+    const eventCount = toNumber(payload.event_count, memoryCounts.events);
     const includeCitations = payload.include_citations !== false;
     const citationLimitPerEdge = toNumber(payload.citation_limit_per_edge, 2);
     const citationMaxChars = toNumber(payload.citation_max_chars, 160);
     const currentPrompt = typeof payload.current_prompt === "string" ? payload.current_prompt : "";
+    let recencyHalfLifeDays = DEFAULT_RECENCY_HALF_LIFE_DAYS;
+    const contextConfig = await loadContextConfig(payload.context_config);
+    if (typeof contextConfig.recency_half_life_days === "number") {
+      recencyHalfLifeDays = contextConfig.recency_half_life_days;
+    }
+    recencyHalfLifeDays = toNumber(payload.recency_half_life_days, recencyHalfLifeDays);
 
     if (!sessionId || !currentPrompt.trim()) {
       return c.json({ error: "session_id and current_prompt are required" }, 400);
     }
 
     const db = await getDb();
-    await ensureSession(db, { id: sessionId, workingDir: projectPath, userId, medium: null });
+    const session = await ensureSession(db, {
+      id: sessionId,
+      workingDir: projectPath,
+      userId,
+      medium: null,
+    });
+    if (!session) {
+      return c.json({ status: "excluded", context: "" });
+    }
     const groupId = userId ?? "default";
 
+    // reuse_cache skips the graph search while nothing has happened in the
+    // session since the last build.
+    if (payload.reuse_cache === true) {
+      const ttlMinutes = await loadContextCacheTtlMinutes(payload.context_config);
+      const cached = await getFreshContextCache(db, sessionId, ttlMinutes);
+      if (cached.context !== null) {
+        return c.json({ status: "ready", context: cached.context, cached: true });
+      }
+    }
+
     if (!(await graphAvailable())) {
       return c.json({ status: "unavailable", context: "" });
     }
@@ -600,14 +845,21 @@ export function registerContextRoutes(app: Hono): void {
       const searchResults = await searchGraph({
         query: currentPrompt,
         groupId,
-        limit: contextDepth * 2,
+        limit: Math.max(contextDepth * 2, factCount, eventCount),
         rerankMethod: "episode_mentions",
         rerankAlpha: 0.5,
         recencyWeight: 0.3,
+        recencyHalfLifeDays,
       });
       if (searchResults.nodes.length > contextDepth) {
         searchResults.nodes = searchResults.nodes.slice(0, contextDepth);
       }
+      if (factCount > 0 && searchResults.edges.length > factCount) {
+        searchResults.edges = searchResults.edges.slice(0, factCount);
+      }
+      if (eventCount > 0 && searchResults.facts.length > eventCount) {
+        searchResults.facts = searchResults.facts.slice(0, eventCount);
+      }
 
       if (searchResults.nodes.length > 0) {
         await trackEntityRetrievals(searchResults.nodes.map((node) => node.uuid));
@@ -725,14 +977,17 @@ export function registerContextRoutes(app: Hono): void {
       }
 
       const contextText = contextParts.join("\n");
-      const metadata = buildContextMetadata(searchResults.nodes, searchResults.edges);
+      const metadata = buildContextMetadata(searchResults.nodes, searchResults.edges, {
+        scores: searchResults.nodeScores,
+        recencyHalfLifeDays,
+      });
 
       await upsertContextCache(db, sessionId, {
         contextText,
         contextMetadata: metadata,
       });
 
-      return c.json({ status: "ready", context: contextText });
+      return c.json({ status: "ready", context: contextText, cached: false });
     } catch (error) {
       log.daemon.warn("Context build failed", { error: String(error) });
       return c.json({ status: "error", context: "", error: String(error) });
@@ -746,26 +1001,18 @@ export function registerContextRoutes(app: Hono): void {
     }
 
     const sessionId = typeof payload.session_id === "number" ? payload.session_id : null;
-    const maxAgeMinutes = toNumber(payload.max_age_minutes, 30);
+    const maxAgeMinutes = toNumber(payload.max_age_minutes, await loadContextCacheTtlMinutes());
     if (!sessionId) {
       return c.json({ error: "session_id is required" }, 400);
     }
 
-    const maxAgeSeconds = maxAgeMinutes * 60;
-    const minTimestamp = new Date(Date.now() - maxAgeSeconds * 1000);
-
     const db = await getDb();
-    const row = await db
-      .selectFrom("context_cache")
-      .select(["context_text"])
-      .where("session_id", "=", sessionId)
-      .where("created_at", ">=", minTimestamp)
-      .orderBy("created_at", "desc")
-      .limit(1)
-      .executeTakeFirst();
-
-    const context = row?.context_text ?? "";
-    return c.json({ found: Boolean(row), context });
+    const cached = await getFreshContextCache(db, sessionId, maxAgeMinutes);
+    return c.json({
+      found: cached.context !== null,
+      context: cached.context ?? "",
+      stale: cached.stale,
+    });
   });
 
   app.post("/context/build_session_start", async (c) => {
@@ -782,9 +1029,28 @@ export function registerContextRoutes(app: Hono): void {
     const userId = typeof payload.user_id === "string" ? payload.user_id : null;
     const workingDir = typeof payload.working_dir === "string" ? payload.working_dir : "";
     const medium = typeof payload.medium === "string" ? payload.medium : null;
+    const deadlineMs = readNumber(payload.deadline_ms);
+    const continuedFrom =
+      payload.session_type === "continue"
+        ? await findContinuationParent(workingDir, sessionId)
+        : null;
 
     const db = await getDb();
-    const session = await ensureSession(db, { id: sessionId, workingDir, userId, medium });
+    const session = await ensureSession(db, {
+      id: sessionId,
+      workingDir,
+      userId,
+      medium,
+      continuedFrom,
+    });
+    if (!session) {
+      return c.json({ status: "excluded", context: "" });
+    }
+    // context_mode=none (dere --fast): the session row is all the caller needs.
+    if (payload.context_mode === "none") {
+      return c.json({ status: "ready", context: "" });
+    }
+
     const existingCache = await db
       .selectFrom("context_cache")
       .select(["context_metadata"])
@@ -801,106 +1067,74 @@ export function registerContextRoutes(app: Hono): void {
       }
     }
 
-    let sessionStartEnabled = true;
-    let sessionStartLimit = 5;
-    let sessionStartGitCommits = 5;
-    let sessionStartConversationalDays = 30;
-    let sessionStartCodeDays = 7;
-
-    try {
-      const config = await loadConfig();
-      const contextConfig = (config.context ?? {}) as Record<string, unknown>;
-      if (typeof contextConfig.session_start_enabled === "boolean") {
-        sessionStartEnabled = contextConfig.session_start_enabled;
-      }
-      if (typeof contextConfig.session_start_limit === "number") {
-        sessionStartLimit = contextConfig.session_start_limit;
-      }
-      if (typeof contextConfig.session_start_git_commits === "number") {
-        sessionStartGitCommits = contextConfig.session_start_git_commits;
-      }
-      if (typeof contextConfig.session_start_conversational_days === "number") {
-        sessionStartConversationalDays = contextConfig.session_start_conversational_days;
-      }
-      if (typeof contextConfig.session_start_code_days === "number") {
-        sessionStartCodeDays = contextConfig.session_start_code_days;
-      }
-    } catch {
-      // defaults already set
+    let build = sessionStartBuilds.get(sessionId);
+    if (!build) {
+      build = buildSessionStartContext({
+        sessionId,
+        userId,
+        session,
+        contextOverrides: payload.context_config,
+      }).finally(() => {
+        sessionStartBuilds.delete(sessionId);
+      });
+      sessionStartBuilds.set(sessionId, build);
     }
 
-    if (!sessionStartEnabled) {
-      return c.json({ status: "disabled", context: "" });
+    if (deadlineMs === null || deadlineMs <= 0) {
+      return c.json(await build);
     }
 
-    const sessionType = await detectSessionType({
-      medium: session.medium,
-      working_dir: session.working_dir,
+    // Return as soon as the build finishes, or report "building" at the deadline.
+    // The build keeps running and its result lands in the cache for the next call.
+    let timer: ReturnType<typeof setTimeout> | null = null;
+    const deadline = new Promise<null>((resolvePending) => {
+      timer = setTimeout(() => resolvePending(null), deadlineMs);
     });
-    let contextText = "";
-    let projectName: string | null = null;
-
-    try {
-      if (await graphAvailable()) {
-        if (sessionType === "code") {
-          projectName = extractProjectName(session.working_dir);
-          const query = projectName ? `recent work in ${projectName}` : "recent code work";
-          const cutoff = new Date(Date.now() - sessionStartCodeDays * 24 * 60 * 60 * 1000);
-          const filters: SearchFilters = {
-            created_at: { operator: "greater_than_equal", value: cutoff },
-          };
-
-          const results = await searchGraph({
-            query,
-            groupId: userId ?? session.user_id ?? "default",
-            limit: sessionStartLimit,
-            rerankMethod: "episode_mentions",
-            filters,
-          });
-
-          const combined = [...results.nodes, ...results.facts];
-          const commits = await getRecentGitCommits(session.working_dir, sessionStartGitCommits);
-          contextText = buildCodeSessionContext(projectName, combined, commits, sessionStartLimit);
-        } else {
-          const query = "recent conversations and entities discussed";
-          const cutoff = new Date(
-            Date.now() - sessionStartConversationalDays * 24 * 60 * 60 * 1000,
-          );
-          const filters: SearchFilters = {
-            created_at: { operator: "greater_than_equal", value: cutoff },
-          };
-
-          const results = await searchGraph({
-            query,
-            groupId: userId ?? session.user_id ?? "default",
-            limit: sessionStartLimit,
-            rerankMethod: "recency",
-            filters,
-          });
-
-          const combined = [...results.nodes, ...results.facts];
-          contextText = buildConversationalContext(combined, sessionStartLimit);
-        }
-      }
-    } catch (error) {
-      log.daemon.warn("Session-start context build failed", { error: String(error) });
-      contextText = `<session_start_context type="${sessionType}"><error>Context unavailable</error></session_start_context>`;
+    const result = await Promise.race([build, deadline]);
+    if (timer) {
+      clearTimeout(timer);
+    }
+    if (!result) {
+      return c.json({ status: "building", context: "" });
     }
+    return c.json(result);
+  });
 
-    const cacheMetadata = {
-      session_start_queried: true,
-      session_start_results: contextText,
-      session_type: sessionType,
-      query_timestamp: nowSeconds(),
-    };
+  // Debugging view of what was built for a session: the cached prompt context,
+  // its sources, and the session-start context, plus whether it is still fresh.
+  app.get("/context/cache/:session_id", async (c) => {
+    const sessionId = Number(c.req.param("session_id"));
+    if (!Number.isFinite(sessionId)) {
+      return c.json({ error: "Invalid session_id" }, 400);
+    }
 
-    await mergeContextCacheMetadata(db, sessionId, cacheMetadata);
+    const db = await getDb();
+    const row = await db
+      .selectFrom("context_cache")
+      .select([
+        "context_text",
+        "context_metadata",
+        "max_conversation_timestamp",
+        "created_at",
+        "updated_at",
+      ])
+      .where("session_id", "=", sessionId)
+      .executeTakeFirst();
+    if (!row) {
+      return c.json({ error: "No context cached for this session" }, 404);
+    }
 
+    const ttlMinutes = await loadContextCacheTtlMinutes();
+    const { stale } = await getFreshContextCache(db, sessionId, ttlMinutes);
     return c.json({
-      status: "ready",
-      context: contextText,
-      session_type: sessionType,
-      project_name: projectName,
+      session_id: sessionId,
+      context: row.context_text,
+      metadata: row.context_metadata ?? {},
+      max_conversation_timestamp: row.max_conversation_timestamp,
+      created_at: row.created_at,
+      updated_at: row.updated_at,
+      ttl_minutes: ttlMinutes,
+      stale,
     });
   });
 
@@ -1,6 +1,7 @@
 export type ContextMetadata = {
-  entities: Array<{ uuid: string; name: string }>;
+  entities: Array<{ uuid: string; name: string; relevance?: number }>;
   edges: string[];
+  recency_half_life_days?: number;
 };
 
 function escapeRegExp(value: string): string {
@@ -10,11 +11,21 @@ function escapeRegExp(value: string): string {
 export function buildContextMetadata(
   nodes: Array<{ uuid: string; name: string }>,
   edges: Array<{ uuid: string }>,
+  options: { scores?: Record<string, number>; recencyHalfLifeDays?: number } = {},
 ): ContextMetadata {
-  return {
-    entities: nodes.map((node) => ({ uuid: node.uuid, name: node.name })),
+  const metadata: ContextMetadata = {
+    entities: nodes.map((node) => {
+      const relevance = options.scores?.[node.uuid];
+      return typeof relevance === "number"
+        ? { uuid: node.uuid, name: node.name, relevance }
+        : { uuid: node.uuid, name: node.name };
+    }),
     edges: edges.map((edge) => edge.uuid),
   };
+  if (options.recencyHalfLifeDays) {
+    metadata.recency_half_life_days = options.recencyHalfLifeDays;
+  }
+  return metadata;
 }
 
 export function extractCitedEntityUuids(
@@ -0,0 +1,185 @@
+/**
+ * Startup checks that turn "the daemon won't start" into something actionable:
+ * why the database can't be reached, waiting out crash recovery, and finding
+ * transactions a dead daemon left open that can hold locks on dere's tables.
+ */
+
+import { sql } from "kysely";
+
+import { loadConfig } from "@dere/shared-config";
+
+import { DB_APPLICATION_NAME, getDb } from "./db.js";
+import { log } from "./logger.js";
+
+/** How long to wait for a server that is still recovering from a crash. */
+const RECOVERY_WAIT_MS = 60_000;
+const RECOVERY_POLL_MS = 2000;
+const DEFAULT_STALE_TRANSACTION_MINUTES = 10;
+
+type PgError = { code?: unknown; message?: unknown };
+
+function errorCode(error: unknown): string {
+  const code = (error as PgError | null)?.code;
+  return typeof code === "string" ? code : "";
+}
+
+/**
+ * A one-line explanation of a connection or query failure, with what to do
+ * about it. Falls back to the driver's own message for anything unrecognized.
+ */
+export function describeDatabaseError(error: unknown): string {
+  switch (errorCode(error)) {
+    case "ECONNREFUSED":
+      return "PostgreSQL is not accepting connections; check it is running at [database].url";
+    case "ENOTFOUND":
+    case "EAI_AGAIN":
+      return "The database host in [database].url could not be resolved";
+    case "ETIMEDOUT":
+      return "Timed out connecting to the database; check the host and any firewall";
+    case "28P01":
+    case "28000":
+      return "The database rejected the credentials; check [database].url and its password";
+    case "3D000":
+      return "The database in [database].url does not exist; create it and run migrations";
+    case "57P03":
+      return "PostgreSQL is still recovering from an unclean shutdown; try again shortly";
+    case "53300":
+      return "PostgreSQL has no free connections; close other clients or raise max_connections";
+    case "XX001":
+      return "PostgreSQL reported corrupted table data; restore from a backup before continuing";
+    case "XX002":
+      return "PostgreSQL reported a corrupted index; rebuild it with REINDEX TABLE <table>";
+    default: {
+      const message = (error as PgError | null)?.message;
+      return typeof message === "string" && message ? message : String(error);
+    }
+  }
+}
+
+async function ping(): Promise<void> {
+  const db = await getDb();
+  await sql`SELECT 1`.execute(db);
+}
+
+/** Connect, waiting while the server replays its log after a crash. */
+async function connectWithRecoveryWait(): Promise<void> {
+  const deadline = Date.now() + RECOVERY_WAIT_MS;
+  let announced = false;
+  while (true) {
+    try {
+      await ping();
+      return;
+    } catch (error) {
+      if (errorCode(error) !== "57P03" || Date.now() > deadline) {
+        throw error;
+      }
+      if (!announced) {
+        log.daemon.warn("Database is recovering after an unclean shutdown; waiting", {
+          timeoutMs: RECOVERY_WAIT_MS,
+        });
+        announced = true;
+      }
+      await new Promise((resolve) => setTimeout(resolve, RECOVERY_POLL_MS));
+    }
+  }
+}
+
+async function loadStaleSettings(): Promise<{ minutes: number; terminate: boolean }> {
+  try {
+    const config = await loadConfig();
+    const dbConfig = (config.database ?? {}) as Record<string, unknown>;
+    const minutes = Number(dbConfig.stale_transaction_minutes);
+    return {
+      minutes:
+        Number.isFinite(minutes) && minutes > 0 ? minutes : DEFAULT_STALE_TRANSACTION_MINUTES,
+      terminate: dbConfig.terminate_stale_transactions === true,
+    };
+  } catch {
+    return { minutes: DEFAULT_STALE_TRANSACTION_MINUTES, terminate: false };
+  }
+}
+
+type StaleBackend = {
+  pid: number;
+  client_addr: string | null;
+  state: string;
+  idle_seconds: number;
+  locks: number;
+};
+
+/**
+ * Connections from an earlier dere that sit idle inside an open transaction.
+ * A daemon killed mid-transaction on another machine, or behind a dropped
+ * network link, leaves these holding row and table locks until the server
+ * notices. Only connections tagged with dere's application_name are listed.
+ */
+async function findStaleTransactions(minutes: number): Promise<StaleBackend[]> {
+  const db = await getDb();
+  const result = await sql<StaleBackend>`
+    SELECT a.pid,
+           host(a.client_addr) AS client_addr,
+           a.state,
+           extract(epoch FROM now() - a.state_change)::int AS idle_seconds,
+           (SELECT count(*)::int FROM pg_locks l WHERE l.pid = a.pid AND l.granted) AS locks
+    FROM pg_stat_activity a
+    WHERE a.datname = current_database()
+      AND a.pid <> pg_backend_pid()
+      AND a.application_name = ${DB_APPLICATION_NAME}
+      AND a.state IN ('idle in transaction', 'idle in transaction (aborted)')
+      AND a.state_change < now() - make_interval(mins => ${minutes})
+    ORDER BY a.state_change
+  `.execute(db);
+  return result.rows;
+}
+
+async function handleStaleTransactions(): Promise<void> {
+  const { minutes, terminate } = await loadStaleSettings();
+  const stale = await findStaleTransactions(minutes);
+  if (stale.length === 0) {
+    return;
+  }
+  for (const backend of stale) {
+    log.daemon.warn("Found a stale dere transaction holding database locks", {
+      pid: backend.pid,
+      client: backend.client_addr ?? "local",
+      idleMinutes: Math.round(backend.idle_seconds / 60),
+      locks: backend.locks,
+    });
+  }
+  if (!terminate) {
+    const pids = stale.map((backend) => backend.pid);
+    log.daemon.warn(
+      "Release them with SELECT pg_terminate_backend(pid), or set " +
+        "[database].terminate_stale_transactions = true",
+      { pids },
+    );
+    return;
+  }
+  const db = await getDb();
+  for (const backend of stale) {
+    await sql`SELECT pg_terminate_backend(${backend.pid})`.execute(db);
+    log.daemon.info("Terminated stale dere transaction", { pid: backend.pid });
+  }
+}
+
+/**
+ * Check the database before the daemon starts serving. Returns false, after
+ * logging why, when it can't be used; stale-transaction cleanup problems are
+ * only warned about.
+ */
+export async function checkDatabaseOnStartup(): Promise<boolean> {
+  try {
+    await connectWithRecoveryWait();
+  } catch (error) {
+    log.daemon.error(`Cannot use the database: ${describeDatabaseError(error)}`, {
+      code: errorCode(error) || undefined,
+    });
+    return false;
+  }
+  try {
+    await handleStaleTransactions();
+  } catch (error) {
+    log.daemon.warn("Stale transaction check failed", { error: describeDatabaseError(error) });
+  }
+  return true;
+}
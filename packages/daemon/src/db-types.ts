@@ -75,6 +75,11 @@ export interface SessionsTable {
   created_at: Timestamp;
   summary: string | null;
   summary_updated_at: Timestamp;
+  summary_embedding: number[] | null;
+  summary_embedding_model: string | null;
+  disabled_tasks: Generated<string[]>;
+  pinned: Generated<boolean>;
+  context_hint: string | null;
 }
 
 export interface ConversationsTable {
@@ -91,6 +96,7 @@ export interface ConversationsTable {
   thinking_ms: number | null;
   tool_uses: number | null;
   tool_names: StringArray;
+  content_hash: string | null;
   created_at: Timestamp;
 }
 
@@ -105,6 +111,7 @@ export interface ConversationBlocksTable {
   tool_input: JsonValue;
   is_error: boolean | null;
   content_embedding: number[] | null;
+  embedding_model: string | null;
   created_at: Timestamp;
 }
 
@@ -127,6 +134,7 @@ export interface ContextCacheTable {
   session_id: number;
   context_text: string;
   context_metadata: JsonValue;
+  max_conversation_timestamp: number | null;
   created_at: Timestamp;
   updated_at: Timestamp;
 }
@@ -403,6 +411,53 @@ export interface DaemonStateTable {
   updated_at: Timestamp;
 }
 
+export interface SessionCostsTable {
+  session_id: number;
+  total_cost_usd: number;
+  total_duration_ms: number;
+  model: string | null;
+  working_dir: string | null;
+  personality: string | null;
+  created_at: Timestamp;
+  updated_at: Timestamp;
+}
+
+export interface SessionTagsTable {
+  session_id: number;
+  tag: string;
+  created_at: Generated<Timestamp>;
+}
+
+export interface SessionSummaryVariantsTable {
+  id: Generated<number>;
+  session_id: number;
+  summary_type: string;
+  max_words: number;
+  summary: string;
+  decisions: string | null;
+  next_steps: string | null;
+  created_at: Generated<Timestamp>;
+}
+
+export interface ActionItemsTable {
+  id: Generated<number>;
+  session_id: number;
+  working_dir: string;
+  text: string;
+  status: Generated<string>;
+  created_at: Generated<Timestamp>;
+  completed_at: Timestamp | null;
+}
+
+export interface ToolEventsTable {
+  id: Generated<number>;
+  session_id: number;
+  tool_name: string;
+  target: string | null;
+  command: string | null;
+  created_at: Generated<Timestamp>;
+}
+
 export interface Database {
   missions: MissionsTable;
   mission_executions: MissionExecutionsTable;
@@ -429,4 +484,9 @@ export interface Database {
   swarm_scratchpad: SwarmScratchpadTable;
   contradiction_reviews: ContradictionReviewsTable;
   daemon_state: DaemonStateTable;
+  session_costs: SessionCostsTable;
+  session_tags: SessionTagsTable;
+  session_summary_variants: SessionSummaryVariantsTable;
+  action_items: ActionItemsTable;
+  tool_events: ToolEventsTable;
 }
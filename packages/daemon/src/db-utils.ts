@@ -5,6 +5,7 @@
 
 import { sql, type Kysely } from "kysely";
 import type { Database } from "./db-types.js";
+import { isExcludedWorkingDir, normalizeWorkingDir } from "./utils/working-dir.js";
 
 // ============================================================================
 // Session Utilities
@@ -35,12 +36,16 @@ export type SessionResult = {
 
 /**
  * Ensure a session exists. Creates if missing, returns existing if present.
- * Uses INSERT ON CONFLICT DO NOTHING to handle race conditions.
+ * Uses INSERT ON CONFLICT to handle race conditions. Returns null, writing
+ * nothing, for a working directory under [capture].exclude_paths.
  */
 export async function ensureSession(
   db: Kysely<Database>,
   session: SessionInsert,
-): Promise<SessionResult> {
+): Promise<SessionResult | null> {
+  if (await isExcludedWorkingDir(session.workingDir)) {
+    return null;
+  }
   const now = new Date();
   const nowSeconds = Math.floor(Date.now() / 1000);
 
@@ -49,7 +54,7 @@ export async function ensureSession(
     .values({
       id: session.id,
       name: session.name ?? null,
-      working_dir: session.workingDir,
+      working_dir: await normalizeWorkingDir(session.workingDir),
       start_time: nowSeconds,
       end_time: null,
       last_activity: now,
@@ -69,7 +74,14 @@ export async function ensureSession(
       summary: null,
       summary_updated_at: null,
     })
-    .onConflict((oc) => oc.column("id").doNothing())
+    // The CLI reserves the row at launch, before it knows the parent (-c) or
+    // user; fill those in if they're still unset rather than dropping them.
+    .onConflict((oc) =>
+      oc.column("id").doUpdateSet({
+        continued_from: sql`coalesce(sessions.continued_from, excluded.continued_from)`,
+        user_id: sql`coalesce(sessions.user_id, excluded.user_id)`,
+      }),
+    )
     .execute();
 
   // Fetch the session (either we just created it or it already existed)
@@ -113,9 +125,31 @@ export type ContextCacheValues = {
   contextMetadata: Record<string, unknown>;
 };
 
+export type ContextCacheStaleness = "missing" | "expired" | "new_conversations";
+
+export type ContextCacheLookup = {
+  context: string | null;
+  /** Why no context was returned; null when the cache was fresh */
+  stale: ContextCacheStaleness | null;
+};
+
+async function latestConversationTimestamp(
+  db: Kysely<Database>,
+  sessionId: number,
+): Promise<number | null> {
+  const row = await db
+    .selectFrom("conversations")
+    .select((eb) => eb.fn.max("timestamp").as("latest"))
+    .where("session_id", "=", sessionId)
+    .executeTakeFirst();
+  return row?.latest ?? null;
+}
+
 /**
  * Upsert context cache with full replacement.
  * Uses INSERT ON CONFLICT DO UPDATE to handle race conditions.
+ * Records the session's newest conversation so later reads can tell whether
+ * the session has moved on since this build.
  */
 export async function upsertContextCache(
   db: Kysely<Database>,
@@ -123,6 +157,7 @@ export async function upsertContextCache(
   values: ContextCacheValues,
 ): Promise<void> {
   const now = new Date();
+  const maxConversationTimestamp = await latestConversationTimestamp(db, sessionId);
 
   await db
     .insertInto("context_cache")
@@ -130,6 +165,7 @@ export async function upsertContextCache(
       session_id: sessionId,
       context_text: values.contextText,
       context_metadata: values.contextMetadata,
+      max_conversation_timestamp: maxConversationTimestamp,
       created_at: now,
       updated_at: now,
     })
@@ -137,12 +173,49 @@ export async function upsertContextCache(
       oc.column("session_id").doUpdateSet({
         context_text: values.contextText,
         context_metadata: values.contextMetadata,
+        max_conversation_timestamp: maxConversationTimestamp,
+        // A full rebuild restarts the TTL
+        created_at: now,
         updated_at: now,
       }),
     )
     .execute();
 }
 
+/**
+ * Cached context for a session, if it was built within `maxAgeMinutes` and no
+ * conversations have been added to the session since.
+ */
+export async function getFreshContextCache(
+  db: Kysely<Database>,
+  sessionId: number,
+  maxAgeMinutes: number,
+): Promise<ContextCacheLookup> {
+  const row = await db
+    .selectFrom("context_cache")
+    .select(["context_text", "max_conversation_timestamp", "created_at"])
+    .where("session_id", "=", sessionId)
+    .executeTakeFirst();
+  if (!row || !row.context_text.trim()) {
+    return { context: null, stale: "missing" };
+  }
+  const builtAt = row.created_at ? new Date(row.created_at).getTime() : 0;
+  if (builtAt < Date.now() - maxAgeMinutes * 60 * 1000) {
+    return { context: null, stale: "expired" };
+  }
+  const latest = await latestConversationTimestamp(db, sessionId);
+  // Conversation timestamps are whole seconds, so one stamped in the second the
+  // cache was built may have arrived after the build; count it as new too.
+  const builtSecond = Math.floor(builtAt / 1000);
+  if (
+    latest !== null &&
+    (latest > (row.max_conversation_timestamp ?? 0) || latest >= builtSecond)
+  ) {
+    return { context: null, stale: "new_conversations" };
+  }
+  return { context: row.context_text, stale: null };
+}
+
 /**
  * Upsert context cache with JSONB metadata merge.
  * Existing metadata fields are preserved, new fields are added/updated.
@@ -1,11 +1,14 @@
 import { Kysely, PostgresDialect } from "kysely";
-import { Pool } from "pg";
+import { Pool, type PoolConfig } from "pg";
 
 import { loadConfig } from "@dere/shared-config";
 
 import type { Database } from "./db-types.js";
 
-let db: Kysely<Database> | null = null;
+let dbPromise: Promise<Kysely<Database>> | null = null;
+
+/** Tags dere's connections in pg_stat_activity so its own leftovers can be found. */
+export const DB_APPLICATION_NAME = "dere";
 
 async function resolveDatabaseUrl(): Promise<string> {
   const envUrl = process.env.DERE_DATABASE_URL ?? process.env.DATABASE_URL;
@@ -22,22 +25,63 @@ async function resolveDatabaseUrl(): Promise<string> {
   throw new Error("Database URL not configured. Set DERE_DATABASE_URL or config.database.url");
 }
 
+/**
+ * Pool settings for a hosted database shared across machines: a password kept
+ * out of the URL (DERE_DATABASE_PASSWORD or [database].password) and TLS via
+ * [database].ssl. A password or sslmode already in the URL takes precedence.
+ */
+async function applyRemoteSettings(databaseUrl: string): Promise<PoolConfig> {
+  let dbConfig: Record<string, unknown> = {};
+  try {
+    const config = await loadConfig();
+    dbConfig = (config.database ?? {}) as Record<string, unknown>;
+  } catch {
+    // URL came from the environment; there may be no config file
+  }
+
+  let connectionString = databaseUrl;
+  const password = process.env.DERE_DATABASE_PASSWORD ?? dbConfig.password;
+  if (typeof password === "string" && password) {
+    // pg lets the connection string override explicit options, so put it there.
+    const url = new URL(databaseUrl);
+    if (!url.password) {
+      url.password = password;
+      connectionString = url.toString();
+    }
+  }
+  return dbConfig.ssl === true ? { connectionString, ssl: true } : { connectionString };
+}
+
 export async function createDb(): Promise<{ db: Kysely<Database>; pool: Pool }> {
   const databaseUrl = await resolveDatabaseUrl();
-  const pool = new Pool({ connectionString: databaseUrl });
+  const pool = new Pool({
+    ...(await applyRemoteSettings(databaseUrl)),
+    application_name: DB_APPLICATION_NAME,
+  });
   const instance = new Kysely<Database>({
     dialect: new PostgresDialect({ pool }),
   });
   return { db: instance, pool };
 }
 
+/**
+ * The daemon's shared connection pool. Every route, loop, and activity goes
+ * through this one pool; Postgres handles concurrent writers with row-level
+ * locking, so there is no separate write path. Hooks and the CLI never open
+ * the database themselves, they go through the daemon's HTTP API.
+ *
+ * The pending promise is cached so concurrent first callers share one pool
+ * instead of each creating their own.
+ */
 export async function getDb(): Promise<Kysely<Database>> {
-  if (db) {
-    return db;
+  if (!dbPromise) {
+    dbPromise = createDb().then(
+      (created) => created.db,
+      (error: unknown) => {
+        dbPromise = null;
+        throw error;
+      },
+    );
   }
-
-  const created = await createDb();
-  db = created.db;
-
-  return db;
+  return dbPromise;
 }
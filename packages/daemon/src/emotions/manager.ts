@@ -208,7 +208,7 @@ export class EmotionManager {
     });
 
     const client = getClient();
-    const appraisal = await client.generate(prompt, AppraisalOutputSchema, {
+    const appraisal = await client.generateChat(prompt.system, prompt.user, AppraisalOutputSchema, {
       schemaName: "appraisal_output",
     });
 
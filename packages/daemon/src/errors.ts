@@ -0,0 +1,79 @@
+/**
+ * Application-level error codes returned in HTTP error bodies as
+ * `{ error, code, retryable }`, so clients can tell a transient failure
+ * (retry) from a permanent one (proceed without).
+ */
+
+import type { Context } from "hono";
+
+import { AuthenticationError, StructuredOutputError } from "@dere/shared-llm";
+
+export const ErrorCode = {
+  DB_UNAVAILABLE: "db_unavailable",
+  MODEL_UNAVAILABLE: "model_unavailable",
+  SESSION_NOT_FOUND: "session_not_found",
+  INTERNAL: "internal",
+} as const;
+
+export type ErrorCode = (typeof ErrorCode)[keyof typeof ErrorCode];
+
+const ERROR_DEFAULTS: Record<ErrorCode, { status: 404 | 500 | 503; retryable: boolean }> = {
+  db_unavailable: { status: 503, retryable: true },
+  model_unavailable: { status: 503, retryable: true },
+  session_not_found: { status: 404, retryable: false },
+  internal: { status: 500, retryable: false },
+};
+
+export class DaemonError extends Error {
+  readonly code: ErrorCode;
+
+  constructor(code: ErrorCode, message: string, cause?: unknown) {
+    super(message);
+    this.name = "DaemonError";
+    this.code = code;
+    if (cause !== undefined) {
+      (this as { cause?: unknown }).cause = cause;
+    }
+  }
+}
+
+// Connection-level failures from pg; SQLSTATE class 08 is connection exception,
+// 57P0x is admin/crash shutdown.
+const DB_ERROR_CODES = new Set(["ECONNREFUSED", "ECONNRESET", "ETIMEDOUT", "ENOTFOUND"]);
+const DB_ERROR_PATTERNS = [
+  "connection terminated",
+  "database url not configured",
+  "too many clients",
+  "the database system is starting up",
+];
+
+function isDbUnavailable(error: unknown): boolean {
+  const code = (error as { code?: unknown })?.code;
+  if (typeof code === "string" && (DB_ERROR_CODES.has(code) || /^(08|57P0)/.test(code))) {
+    return true;
+  }
+  const msg = (error instanceof Error ? error.message : String(error)).toLowerCase();
+  return DB_ERROR_PATTERNS.some((pattern) => msg.includes(pattern));
+}
+
+export function classifyError(error: unknown): ErrorCode {
+  if (error instanceof DaemonError) {
+    return error.code;
+  }
+  if (error instanceof AuthenticationError || error instanceof StructuredOutputError) {
+    return ErrorCode.MODEL_UNAVAILABLE;
+  }
+  if (isDbUnavailable(error)) {
+    return ErrorCode.DB_UNAVAILABLE;
+  }
+  return ErrorCode.INTERNAL;
+}
+
+export function errorResponse(c: Context, error: unknown): Response {
+  const code = classifyError(error);
+  const { status } = ERROR_DEFAULTS[code];
+  // An expired login won't fix itself; everything else in the class is transient.
+  const retryable = ERROR_DEFAULTS[code].retryable && !(error instanceof AuthenticationError);
+  const message = error instanceof Error ? error.message : String(error);
+  return c.json({ error: message, code, retryable }, status);
+}
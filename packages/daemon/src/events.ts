@@ -96,6 +96,12 @@ export type AmbientExploreEvent = {
   description: string;
 };
 
+export type TaskCompleteEvent = {
+  taskId: number;
+  taskType: string;
+  status: "completed" | "failed";
+};
+
 // ============================================================================
 // Integration Events (Phase 1: fact-checker)
 // ============================================================================
@@ -150,6 +156,7 @@ export interface DaemonEvents {
   "memory:consolidate": MemoryConsolidateEvent;
   "recall:embed": RecallEmbedEvent;
   "ambient:explore": AmbientExploreEvent;
+  "task:complete": TaskCompleteEvent;
   "integration:contradiction_detected": ContradictionDetectedEvent;
   "integration:fact_superseded": FactSupersededEvent;
   "planning:exploration_queued": ExplorationQueuedEvent;
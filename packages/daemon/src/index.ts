@@ -1,14 +1,25 @@
 import * as Sentry from "@sentry/bun";
-import { existsSync, mkdirSync, writeFileSync, unlinkSync } from "node:fs";
+import {
+  closeSync,
+  existsSync,
+  mkdirSync,
+  openSync,
+  readFileSync,
+  statSync,
+  unlinkSync,
+  writeFileSync,
+} from "node:fs";
 import { join } from "node:path";
 import { homedir } from "node:os";
 
 import { getDaemonUrlFromConfig, loadConfig } from "@dere/shared-config";
 
 import { createApp } from "./app.js";
+import { checkDatabaseOnStartup } from "./db-health.js";
 import { startAmbientMonitor } from "./ambient/monitor.js";
 import { startEngagementKickoff } from "./engagement-kickoff.js";
 import { initMissionRuntime } from "./missions/runtime.js";
+import { startSessionCleanupLoop } from "./sessions/cleanup.js";
 import { startSessionSummaryLoop } from "./sessions/summary.js";
 import { startEmotionLoop } from "./emotions/runtime.js";
 import { startMemoryConsolidationLoop } from "./memory/consolidation.js";
@@ -17,7 +28,8 @@ import { startPresenceCleanupLoop } from "./routes/presence.js";
 import { cleanupOrphanedSwarms } from "./swarm/index.js";
 import { initEventHandlers } from "./event-handlers.js";
 import { cleanupStaleTasks } from "./temporal/cleanup.js";
-import { log } from "./logger.js";
+import { checkSchemaVersion, SchemaTooNewError } from "./schema.js";
+import { log, setLogLevel } from "./logger.js";
 
 // Sentry error tracking (optional)
 const sentryDsn = process.env.DERE_SENTRY_DSN;
@@ -41,17 +53,106 @@ function getPidPath(): string {
   return join(dataDir, "daemon.pid");
 }
 
+const PID_WRITE_GRACE_MS = 5000;
+
+function isProcessAlive(pid: number): boolean {
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    // EPERM: the process exists but belongs to another user.
+    return (error as NodeJS.ErrnoException).code === "EPERM";
+  }
+}
+
+/**
+ * Take the PID file as a lock so only one daemon runs against the socket and
+ * database. The file is created exclusively; if it already exists and names a
+ * live process, startup is refused, and a file left behind by a crashed
+ * daemon is replaced.
+ */
+function acquirePidLock(path: string): void {
+  const createLock = () => {
+    const fd = openSync(path, "wx");
+    writeFileSync(fd, String(process.pid));
+    closeSync(fd);
+  };
+  const refuse = (pid: number | null): never => {
+    log.daemon.error("Another daemon is already running; stop it with: dere daemon stop", {
+      pid,
+      pidPath: path,
+    });
+    process.exit(1);
+  };
+
+  for (let attempt = 0; attempt < 3; attempt += 1) {
+    try {
+      createLock();
+      return;
+    } catch (error) {
+      if ((error as NodeJS.ErrnoException).code !== "EEXIST") {
+        throw error;
+      }
+    }
+    let contents: string;
+    let age: number;
+    try {
+      contents = readFileSync(path, "utf-8").trim();
+      age = Date.now() - statSync(path).mtimeMs;
+    } catch {
+      // Removed between the open and the read; try again.
+      continue;
+    }
+    const holder = Number(contents);
+    const validPid = Number.isInteger(holder) && holder > 0 && holder !== process.pid;
+    // A file without a PID yet, written in the last few seconds, is another
+    // daemon still starting up.
+    const starting = !validPid && age < PID_WRITE_GRACE_MS;
+    if (starting || (validPid && isProcessAlive(holder))) {
+      refuse(validPid ? holder : null);
+    }
+
+    // Only remove the file we judged stale. If it changed since, another
+    // daemon has already replaced it with its own lock.
+    try {
+      if (readFileSync(path, "utf-8").trim() !== contents) {
+        continue;
+      }
+      log.daemon.warn("Removing stale PID file", { pid: holder, pidPath: path });
+      unlinkSync(path);
+    } catch {
+      // Already gone; fall through and try to take it.
+    }
+    try {
+      createLock();
+      return;
+    } catch (error) {
+      if ((error as NodeJS.ErrnoException).code !== "EEXIST") {
+        throw error;
+      }
+      // Another daemon cleared the same stale file and got there first.
+      refuse(null);
+    }
+  }
+  log.daemon.error("Could not acquire PID file", { pidPath: path });
+  process.exit(1);
+}
+
 const pidPath = getPidPath();
-writeFileSync(pidPath, String(process.pid));
+acquirePidLock(pidPath);
 
 function cleanup(): void {
   try {
-    unlinkSync(pidPath);
+    // Only remove the file while it is still ours.
+    if (readFileSync(pidPath, "utf-8").trim() === String(process.pid)) {
+      unlinkSync(pidPath);
+    }
   } catch {
     // ignore
   }
 }
 
+process.on("exit", cleanup);
 process.on("SIGINT", () => {
   cleanup();
   process.exit(0);
@@ -84,7 +185,39 @@ async function resolveDaemonPort(): Promise<number> {
   return port;
 }
 
+async function applyConfiguredLogLevel(): Promise<void> {
+  try {
+    const config = await loadConfig();
+    const level = config.log_level;
+    if (typeof level === "string" && !setLogLevel(level)) {
+      log.daemon.warn("Ignoring unknown log_level", { level });
+    }
+  } catch {
+    // keep the environment/default level
+  }
+}
+
 async function main(): Promise<void> {
+  await applyConfiguredLogLevel();
+
+  // Explain an unreachable database up front instead of failing on first query
+  if (!(await checkDatabaseOnStartup())) {
+    cleanup();
+    process.exit(1);
+  }
+
+  // Refuse to touch a database written by a newer dere
+  try {
+    await checkSchemaVersion();
+  } catch (error) {
+    if (error instanceof SchemaTooNewError) {
+      log.daemon.error(`${error.message}; upgrade dere before starting the daemon`);
+      cleanup();
+      process.exit(1);
+    }
+    log.daemon.warn("Schema version check failed", { error: String(error) });
+  }
+
   // Initialize event handlers before anything else
   initEventHandlers();
 
@@ -111,6 +244,7 @@ async function main(): Promise<void> {
 
   initMissionRuntime();
   startSessionSummaryLoop();
+  startSessionCleanupLoop();
   startEmotionLoop();
   startMemoryConsolidationLoop();
   startRecallEmbeddingLoop();
@@ -1,6 +1,9 @@
-import { describe, expect, test } from "bun:test";
+import { afterAll, describe, expect, test } from "bun:test";
+import { sql } from "kysely";
 
 import { createApp } from "./app.js";
+import { getDb } from "./db.js";
+import { cleanupOldSessions, purgeSession } from "./sessions/cleanup.js";
 
 describe("daemon integration", () => {
   test("/health returns ok", async () => {
@@ -39,3 +42,192 @@ graphTest("graph /kg/stats responds with summary fields", async () => {
     }),
   );
 });
+
+// A migrated scratch database; these tests insert and delete sessions.
+const databaseUrl = process.env.DERE_DATABASE_TEST_URL;
+const databaseTest = databaseUrl ? test : test.skip;
+if (databaseUrl) {
+  process.env.DERE_DATABASE_URL = databaseUrl;
+}
+
+const DAY_MS = 24 * 60 * 60 * 1000;
+const seededSessions: number[] = [];
+
+/** A session with a row in each table that belongs to it. */
+async function seedSession(options: { daysIdle?: number; pinned?: boolean } = {}): Promise<number> {
+  const db = await getDb();
+  const lastActivity = new Date(Date.now() - (options.daysIdle ?? 0) * DAY_MS);
+  const now = Math.floor(Date.now() / 1000);
+  const session = await sql<{ id: number }>`
+    INSERT INTO sessions (working_dir, start_time, last_activity, pinned)
+    VALUES ('/tmp/dere-cleanup-test', ${now}, ${lastActivity}, ${options.pinned === true})
+    RETURNING id
+  `.execute(db);
+  const sessionId = session.rows[0]!.id;
+  seededSessions.push(sessionId);
+
+  const conversation = await sql<{ id: number }>`
+    INSERT INTO conversations (session_id, prompt, timestamp)
+    VALUES (${sessionId}, 'hello', ${now})
+    RETURNING id
+  `.execute(db);
+  const conversationId = conversation.rows[0]!.id;
+  await sql`
+    INSERT INTO conversation_blocks (conversation_id, ordinal, block_type, text)
+    VALUES (${conversationId}, 0, 'text', 'hello')
+  `.execute(db);
+  await sql`
+    INSERT INTO entities
+      (session_id, conversation_id, entity_type, entity_value, normalized_value, confidence)
+    VALUES (${sessionId}, ${conversationId}, 'tool', 'Bun', 'bun', 1)
+  `.execute(db);
+  const block = await sql<{ id: number }>`
+    INSERT INTO core_memory_blocks (session_id, block_type, content)
+    VALUES (${sessionId}, 'persona', 'notes')
+    RETURNING id
+  `.execute(db);
+  await sql`
+    INSERT INTO core_memory_versions (block_id, version, content)
+    VALUES (${block.rows[0]!.id}, 1, 'notes')
+  `.execute(db);
+  await sql`
+    INSERT INTO context_cache (session_id, context_text) VALUES (${sessionId}, 'context')
+  `.execute(db);
+  await sql`
+    INSERT INTO emotion_states (session_id, primary_emotion) VALUES (${sessionId}, 'joy')
+  `.execute(db);
+  await sql`
+    INSERT INTO stimulus_history (session_id, stimulus_type, valence, intensity, timestamp)
+    VALUES (${sessionId}, 'praise', 1, 1, ${Date.now()})
+  `.execute(db);
+  await sql`INSERT INTO session_costs (session_id) VALUES (${sessionId})`.execute(db);
+  await sql`INSERT INTO session_tags (session_id, tag) VALUES (${sessionId}, 'test')`.execute(db);
+  await sql`
+    INSERT INTO session_summary_variants (session_id, summary_type, max_words, summary)
+    VALUES (${sessionId}, 'brief', 20, 'summary')
+  `.execute(db);
+  await sql`
+    INSERT INTO action_items (session_id, working_dir, text)
+    VALUES (${sessionId}, '/tmp/dere-cleanup-test', 'write tests')
+  `.execute(db);
+  await sql`
+    INSERT INTO tool_events (session_id, tool_name, target) VALUES (${sessionId}, 'Edit', 'a.ts')
+  `.execute(db);
+  await sql`
+    INSERT INTO task_queue (task_type, model_name, content, session_id)
+    VALUES ('summarization', 'test', 'hello', ${sessionId})
+  `.execute(db);
+  await sql`
+    INSERT INTO summary_context (summary, session_ids)
+    VALUES ('rolling', ARRAY[${sessionId}::bigint])
+  `.execute(db);
+  return sessionId;
+}
+
+const SESSION_TABLES = [
+  "sessions",
+  "conversations",
+  "conversation_blocks",
+  "entities",
+  "core_memory_blocks",
+  "core_memory_versions",
+  "context_cache",
+  "emotion_states",
+  "stimulus_history",
+  "session_costs",
+  "session_tags",
+  "session_summary_variants",
+  "action_items",
+  "tool_events",
+  "task_queue",
+  "summary_context",
+] as const;
+
+/** Rows per table that belong to `sessionId`. */
+async function countSessionRows(sessionId: number): Promise<Record<string, number>> {
+  const db = await getDb();
+  const counts: Record<string, number> = {};
+  for (const table of SESSION_TABLES) {
+    const where =
+      table === "sessions"
+        ? sql`id = ${sessionId}`
+        : table === "conversation_blocks"
+          ? sql`conversation_id IN (SELECT id FROM conversations WHERE session_id = ${sessionId})`
+          : table === "core_memory_versions"
+            ? sql`block_id IN (SELECT id FROM core_memory_blocks WHERE session_id = ${sessionId})`
+            : table === "summary_context"
+              ? sql`${sessionId} = any(session_ids)`
+              : sql`session_id = ${sessionId}`;
+    const result = await sql<{ count: number }>`
+      SELECT count(*)::int AS count FROM ${sql.table(table)} WHERE ${where}
+    `.execute(db);
+    counts[table] = result.rows[0]?.count ?? 0;
+  }
+  return counts;
+}
+
+function emptyCounts(): Record<string, number> {
+  return Object.fromEntries(SESSION_TABLES.map((table) => [table, 0]));
+}
+
+describe("session cleanup", () => {
+  afterAll(async () => {
+    if (!databaseUrl || seededSessions.length === 0) {
+      return;
+    }
+    for (const sessionId of seededSessions) {
+      await purgeSession(sessionId);
+    }
+    // Cleanup leaves queued tasks and rolling summaries behind; purge only
+    // reaches them while the session row still exists.
+    const db = await getDb();
+    await db.deleteFrom("task_queue").where("session_id", "in", seededSessions).execute();
+    await db
+      .deleteFrom("summary_context")
+      .where(sql<boolean>`session_ids && ${seededSessions}::bigint[]`)
+      .execute();
+    await db.destroy();
+  });
+
+  databaseTest("purgeSession removes every row recorded under the session", async () => {
+    const sessionId = await seedSession();
+    const result = await purgeSession(sessionId);
+    expect(result?.sessions).toBe(1);
+    expect(result?.tables.session_tags).toBe(1);
+    expect(result?.tables.tool_events).toBe(1);
+    expect(await countSessionRows(sessionId)).toEqual(emptyCounts());
+  });
+
+  databaseTest("purgeSession dry run counts rows but leaves them in place", async () => {
+    const sessionId = await seedSession();
+    const before = await countSessionRows(sessionId);
+    const result = await purgeSession(sessionId, { dryRun: true });
+    expect(result?.dry_run).toBe(true);
+    expect(result?.sessions).toBe(1);
+    expect(result?.conversations).toBe(1);
+    expect(result?.tables.action_items).toBe(1);
+    expect(await countSessionRows(sessionId)).toEqual(before);
+  });
+
+  databaseTest("cleanupOldSessions keeps pinned and recent sessions", async () => {
+    const old = await seedSession({ daysIdle: 90 });
+    const pinned = await seedSession({ daysIdle: 90, pinned: true });
+    const recent = await seedSession({ daysIdle: 1 });
+    const result = await cleanupOldSessions({ olderThanDays: 30 });
+    expect(result.sessions).toBeGreaterThanOrEqual(1);
+    // Queued tasks and rolling summaries are left for purge
+    const remaining = await countSessionRows(old);
+    expect({ ...remaining, task_queue: 0, summary_context: 0 }).toEqual(emptyCounts());
+    expect((await countSessionRows(pinned)).sessions).toBe(1);
+    expect((await countSessionRows(recent)).sessions).toBe(1);
+  });
+
+  databaseTest("cleanupOldSessions dry run leaves the database unchanged", async () => {
+    const old = await seedSession({ daysIdle: 90 });
+    const before = await countSessionRows(old);
+    const result = await cleanupOldSessions({ olderThanDays: 30, dryRun: true });
+    expect(result.dry_run).toBe(true);
+    expect(result.sessions).toBeGreaterThanOrEqual(1);
+    expect(await countSessionRows(old)).toEqual(before);
+  });
+});
@@ -9,6 +9,10 @@ import {
   toNumber,
   toStringArray,
   hybridFactSearch,
+  linkCoOccurringEntities,
+  correctEntity,
+  deleteEntity,
+  verifyEntity,
   searchGraph,
   type SearchFilters,
 } from "@dere/graph";
@@ -27,6 +31,7 @@ type EntitySummary = {
   summary: string;
   mention_count: number;
   retrieval_quality: number;
+  verified: boolean;
   last_mentioned: string | null;
   created_at: string;
 };
@@ -103,6 +108,66 @@ function parseOffset(value: string | null, fallback = 0): number {
   return Math.max(0, parsed);
 }
 
+const GRAPH_MAX_DEPTH = 5;
+const GRAPH_NODE_FIELDS =
+  "n.uuid AS uuid, n.name AS name, labels(n) AS labels, n.retrieval_quality AS retrieval_quality";
+
+/**
+ * Breadth-first walk over live relationships from the entity named `center`,
+ * `depth` hops out, stopping once `limit` entities are collected.
+ */
+async function graphNeighborhood(
+  groupId: string,
+  center: string,
+  depth: number,
+  labelFilter: string,
+  labels: string[],
+  limit: number,
+): Promise<Array<Record<string, unknown>>> {
+  const start = await queryGraph(
+    `
+      MATCH (n:Entity {group_id: $group_id})
+      WHERE toLower(n.name) = toLower($center)
+      RETURN ${GRAPH_NODE_FIELDS}
+      ORDER BY n.mention_count DESC
+      LIMIT 1
+    `,
+    { group_id: groupId, center },
+  );
+  const records = [...start];
+  const seen = new Set(start.map((record) => String(record.uuid ?? "")));
+  let frontier = Array.from(seen);
+  for (let hop = 0; hop < depth && frontier.length > 0 && records.length < limit; hop += 1) {
+    const next = await queryGraph(
+      `
+        MATCH (a:Entity)-[r:RELATES_TO]-(n:Entity {group_id: $group_id})
+        WHERE a.uuid IN $frontier AND NOT n.uuid IN $seen AND r.invalid_at IS NULL
+          ${labelFilter}
+        RETURN DISTINCT ${GRAPH_NODE_FIELDS}, n.mention_count AS mention_count
+        ORDER BY mention_count DESC
+        LIMIT $remaining
+      `,
+      {
+        group_id: groupId,
+        frontier,
+        seen: Array.from(seen),
+        labels,
+        remaining: limit - records.length,
+      },
+    );
+    frontier = [];
+    for (const record of next) {
+      const uuid = String(record.uuid ?? "");
+      if (!seen.has(uuid)) {
+        seen.add(uuid);
+        frontier.push(uuid);
+        records.push(record);
+      }
+    }
+  }
+  return records;
+}
+
 function toEntitySummary(record: Record<string, unknown>): EntitySummary {
   return {
     uuid: String(record.uuid ?? ""),
@@ -111,6 +176,7 @@ function toEntitySummary(record: Record<string, unknown>): EntitySummary {
     summary: typeof record.summary === "string" ? record.summary : "",
     mention_count: toNumber(record.mention_count, 1),
     retrieval_quality: toNumber(record.retrieval_quality, 1),
+    verified: record.verified === true,
     last_mentioned: toIsoString(record.last_mentioned),
     created_at: toIsoString(record.created_at) ?? "",
   };
@@ -414,7 +480,8 @@ export function registerKnowledgeGraphRoutes(app: Hono): void {
           WHERE true ${labelFilter}
           RETURN n.uuid AS uuid, n.name AS name, labels(n) AS labels, n.summary AS summary,
                  n.mention_count AS mention_count, n.retrieval_quality AS retrieval_quality,
-                 n.last_mentioned AS last_mentioned, n.created_at AS created_at
+                 n.verified AS verified, n.last_mentioned AS last_mentioned,
+                 n.created_at AS created_at
           ORDER BY n.${sortKey} ${sortOrder}
           SKIP $offset
           LIMIT $limit
@@ -434,6 +501,227 @@ export function registerKnowledgeGraphRoutes(app: Hono): void {
     }
   });
 
+  app.post("/kg/entities/link", async (c) => {
+    const url = new URL(c.req.url);
+    const groupId = getGroupId(url);
+    const minCountParam = Number(url.searchParams.get("min_count"));
+    const minCount = Number.isFinite(minCountParam) && minCountParam > 0 ? minCountParam : 3;
+    const limitParam = Number(url.searchParams.get("limit"));
+    const limit = Number.isFinite(limitParam) && limitParam > 0 ? limitParam : 100;
+
+    if (!(await graphAvailable())) {
+      return c.json({ error: "Knowledge graph not available" }, 503);
+    }
+
+    try {
+      const linked = await linkCoOccurringEntities({ groupId, minCount, limit });
+      return c.json({ linked, min_count: minCount });
+    } catch (error) {
+      log.kg.warn("Co-occurrence linking failed", { error: String(error) });
+      return c.json({ error: String(error) }, 500);
+    }
+  });
+
+  app.patch("/kg/entities/:uuid", async (c) => {
+    const uuid = c.req.param("uuid");
+    if (!(await graphAvailable())) {
+      return c.json({ error: "Knowledge graph not available" }, 503);
+    }
+
+    let payload: Record<string, unknown>;
+    try {
+      payload = (await c.req.json()) as Record<string, unknown>;
+    } catch {
+      return c.json({ error: "Invalid JSON payload" }, 400);
+    }
+    const type = typeof payload.type === "string" ? payload.type.trim() : "";
+    const name = typeof payload.name === "string" ? payload.name.trim() : "";
+    if (!type && !name) {
+      return c.json({ error: "type or name is required" }, 400);
+    }
+
+    try {
+      const found = await correctEntity(uuid, {
+        type: type || undefined,
+        name: name || undefined,
+      });
+      if (!found) {
+        return c.json({ error: "Entity not found" }, 404);
+      }
+      return c.json({ uuid, updated: true });
+    } catch (error) {
+      log.kg.warn("Entity correction failed", { uuid, error: String(error) });
+      return c.json({ error: String(error) }, 500);
+    }
+  });
+
+  app.delete("/kg/entities/:uuid", async (c) => {
+    const uuid = c.req.param("uuid");
+    if (!(await graphAvailable())) {
+      return c.json({ error: "Knowledge graph not available" }, 503);
+    }
+
+    try {
+      if (!(await deleteEntity(uuid))) {
+        return c.json({ error: "Entity not found" }, 404);
+      }
+      return c.json({ uuid, deleted: true });
+    } catch (error) {
+      log.kg.warn("Entity delete failed", { uuid, error: String(error) });
+      return c.json({ error: String(error) }, 500);
+    }
+  });
+
+  app.post("/kg/entities/:uuid/verify", async (c) => {
+    const uuid = c.req.param("uuid");
+    if (!(await graphAvailable())) {
+      return c.json({ error: "Knowledge graph not available" }, 503);
+    }
+
+    try {
+      if (!(await verifyEntity(uuid))) {
+        return c.json({ error: "Entity not found" }, 404);
+      }
+      return c.json({ uuid, verified: true });
+    } catch (error) {
+      log.kg.warn("Entity verify failed", { uuid, error: String(error) });
+      return c.json({ error: String(error) }, 500);
+    }
+  });
+
+  // Most-mentioned entities and the current relationships among them, shaped
+  // for graph-viz tools: nodes {id, value, type, confidence}, edges {from, to,
+  // type, confidence}.
+  app.get("/kg/graph", async (c) => {
+    const url = new URL(c.req.url);
+    const groupId = getGroupId(url);
+    const labels = parseLabels(url);
+    const limit = parseLimit(url.searchParams.get("limit"), 100);
+    const center = url.searchParams.get("center")?.trim() ?? "";
+    const depth = Math.min(parseLimit(url.searchParams.get("depth"), 2), GRAPH_MAX_DEPTH);
+
+    try {
+      if (!(await graphAvailable())) {
+        return c.json({ nodes: [], edges: [] });
+      }
+      const labelFilter =
+        labels.length > 0 ? "AND ANY(label IN labels(n) WHERE label IN $labels)" : "";
+      const nodeRecords = center
+        ? await graphNeighborhood(groupId, center, depth, labelFilter, labels, limit)
+        : await queryGraph(
+            `
+              MATCH (n:Entity {group_id: $group_id})
+              WHERE true ${labelFilter}
+              RETURN ${GRAPH_NODE_FIELDS}
+              ORDER BY n.mention_count DESC
+              LIMIT $limit
+            `,
+            { group_id: groupId, labels, limit },
+          );
+      if (center && nodeRecords.length === 0) {
+        return c.json({ error: `Entity not found: ${center}` }, 404);
+      }
+      const nodes = nodeRecords.map((record) => ({
+        id: String(record.uuid ?? ""),
+        value: String(record.name ?? ""),
+        type: toStringArray(record.labels).find((label) => label !== "Entity") ?? "Entity",
+        confidence: toNumber(record.retrieval_quality, 1),
+      }));
+
+      const uuids = nodes.map((node) => node.id);
+      const edgeRecords =
+        uuids.length > 0
+          ? await queryGraph(
+              `
+                MATCH (a:Entity)-[r:RELATES_TO]->(b:Entity)
+                WHERE a.uuid IN $uuids AND b.uuid IN $uuids AND r.invalid_at IS NULL
+                RETURN a.uuid AS source, b.uuid AS target, r.name AS relation,
+                       r.strength AS strength
+              `,
+              { uuids },
+            )
+          : [];
+      const edges = edgeRecords.map((record) => ({
+        from: String(record.source ?? ""),
+        to: String(record.target ?? ""),
+        type: String(record.relation ?? "RELATED_TO"),
+        confidence: toNumber(record.strength, 1),
+      }));
+
+      return c.json({ nodes, edges });
+    } catch (error) {
+      log.kg.warn("Graph export failed", { error: String(error) });
+      return c.json({ nodes: [], edges: [] });
+    }
+  });
+
+  // Distribution of extraction confidence in 0.1 bands, overall and per type.
+  // Entities extracted before confidence was recorded are counted as unscored.
+  app.get("/kg/calibration", async (c) => {
+    const url = new URL(c.req.url);
+    const groupId = getGroupId(url);
+    const empty = { bands: [], by_type: {}, unscored: 0, total: 0 };
+
+    try {
+      if (!(await graphAvailable())) {
+        return c.json(empty);
+      }
+      const records = await queryGraph(
+        `
+          MATCH (n:Entity {group_id: $group_id})
+          WITH labels(n) AS labels,
+               CASE WHEN n.extraction_confidence IS NULL THEN -1
+                    ELSE toInteger(floor(n.extraction_confidence * 10)) END AS band,
+               CASE WHEN n.verified = true THEN 1 ELSE 0 END AS verified
+          RETURN labels, band, count(*) AS count, sum(verified) AS verified
+        `,
+        { group_id: groupId },
+      );
+
+      const bands = new Map<number, { count: number; verified: number }>();
+      const byType: Record<string, Record<string, number>> = {};
+      let unscored = 0;
+      let total = 0;
+      for (const record of records) {
+        const count = toNumber(record.count, 0);
+        total += count;
+        const rawBand = toNumber(record.band, -1);
+        if (rawBand < 0) {
+          unscored += count;
+          continue;
+        }
+        // A confidence of exactly 1.0 belongs in the top band.
+        const band = Math.min(rawBand, 9);
+        const entry = bands.get(band) ?? { count: 0, verified: 0 };
+        entry.count += count;
+        entry.verified += toNumber(record.verified, 0);
+        bands.set(band, entry);
+
+        const type = toStringArray(record.labels).find((label) => label !== "Entity") ?? "Entity";
+        const key = (band / 10).toFixed(1);
+        const typeBands = (byType[type] ??= {});
+        typeBands[key] = (typeBands[key] ?? 0) + count;
+      }
+
+      return c.json({
+        bands: Array.from(bands.entries())
+          .sort(([a], [b]) => a - b)
+          .map(([band, entry]) => ({
+            min: band / 10,
+            max: (band + 1) / 10,
+            count: entry.count,
+            verified: entry.verified,
+          })),
+        by_type: byType,
+        unscored,
+        total,
+      });
+    } catch (error) {
+      log.kg.warn("Calibration report failed", { error: String(error) });
+      return c.json(empty);
+    }
+  });
+
   app.get("/kg/search", async (c) => {
     const url = new URL(c.req.url);
     const groupId = getGroupId(url);
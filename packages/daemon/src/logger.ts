@@ -63,7 +63,30 @@ const DIM = "\x1b[2m";
 
 // Environment detection
 const IS_PRODUCTION = process.env.NODE_ENV === "production";
-const MIN_LEVEL = LOG_LEVELS[process.env.LOG_LEVEL as LogLevel] ?? LOG_LEVELS.debug;
+
+function parseLogLevel(value: string | undefined): LogLevel | null {
+  const level = value?.trim().toLowerCase();
+  return level && Object.hasOwn(LOG_LEVELS, level) ? (level as LogLevel) : null;
+}
+
+// DERE_LOG_LEVEL (or LOG_LEVEL) wins over config; production defaults to info.
+const ENV_LEVEL = parseLogLevel(process.env.DERE_LOG_LEVEL ?? process.env.LOG_LEVEL);
+let minLevel = LOG_LEVELS[ENV_LEVEL ?? (IS_PRODUCTION ? "info" : "debug")];
+
+/**
+ * Apply the configured level unless the environment already set one.
+ * Returns false for an unrecognized level name.
+ */
+export function setLogLevel(value: string): boolean {
+  const level = parseLogLevel(value);
+  if (!level) {
+    return false;
+  }
+  if (!ENV_LEVEL) {
+    minLevel = LOG_LEVELS[level];
+  }
+  return true;
+}
 
 // ============================================================================
 // Formatting
@@ -142,7 +165,7 @@ class Logger {
   }
 
   private log(level: LogLevel, msg: string, context?: LogContext): void {
-    if (LOG_LEVELS[level] < MIN_LEVEL) {
+    if (LOG_LEVELS[level] < minLevel) {
       return;
     }
 
@@ -1,6 +1,9 @@
+import { loadConfig } from "@dere/shared-config";
 import { ClaudeAgentTransport, TextResponseClient } from "@dere/shared-llm";
+import { sql } from "kysely";
 
 import { getDb } from "../db.js";
+import { daemonEvents } from "../events.js";
 import { updateCoreMemoryFromSummary } from "../sessions/summary.js";
 import { log } from "../logger.js";
 import { insertConversation } from "../utils/conversations.js";
@@ -9,6 +12,7 @@ import {
   invalidateStaleFacts,
   invalidateLowQualityFacts,
   mergeDuplicateEntities,
+  linkCoOccurringEntities,
   buildCommunities,
 } from "@dere/graph";
 
@@ -24,6 +28,8 @@ const SUMMARY_SESSION_LIMIT = 5;
 const SUMMARY_BLOCK_LIMIT = 60;
 const SUMMARY_MIN_BLOCKS = 8;
 
+const DEFAULT_QUEUE_AGING_MINUTES = 60;
+
 let consolidationTimer: ReturnType<typeof setInterval> | null = null;
 let running = false;
 
@@ -91,14 +97,39 @@ async function processQueue(): Promise<void> {
   }
 }
 
+/** Read `[database].queue_aging_minutes`; 0 disables priority aging. */
+async function loadQueueAgingMinutes(): Promise<number> {
+  try {
+    const config = await loadConfig();
+    const dbConfig = (config.database ?? {}) as Record<string, unknown>;
+    const value = Number(dbConfig.queue_aging_minutes);
+    return Number.isFinite(value) && value >= 0 ? value : DEFAULT_QUEUE_AGING_MINUTES;
+  } catch {
+    return DEFAULT_QUEUE_AGING_MINUTES;
+  }
+}
+
+/**
+ * Priority plus one level per `agingMinutes` spent waiting, so old
+ * low-priority tasks eventually outrank a steady stream of new ones.
+ */
+function effectivePriority(agingMinutes: number) {
+  if (agingMinutes <= 0) {
+    return sql<number>`priority`;
+  }
+  const waitedSeconds = sql<number>`extract(epoch from (now() - created_at))`;
+  return sql<number>`priority + floor(${waitedSeconds} / ${agingMinutes * 60})`;
+}
+
 async function claimNextTask() {
   const db = await getDb();
+  const agingMinutes = await loadQueueAgingMinutes();
   const pending = await db
     .selectFrom("task_queue")
     .selectAll()
     .where("task_type", "=", "memory_consolidation")
     .where("status", "=", "pending")
-    .orderBy("priority", "desc")
+    .orderBy(effectivePriority(agingMinutes), "desc")
     .orderBy("created_at", "asc")
     .limit(1)
     .executeTakeFirst();
@@ -174,6 +205,7 @@ async function runConsolidationTask(task: {
       // Defaults: minAgeDays=14, explorationGraceDays=60
     });
     const mergedEntities = await mergeDuplicateEntities(groupId, ENTITY_MERGE_LIMIT);
+    const linkedEntities = await linkCoOccurringEntities({ groupId });
     const communities = await buildCommunities(groupId, communityResolution);
 
     let coreMemoryUpdates = 0;
@@ -187,6 +219,7 @@ async function runConsolidationTask(task: {
       pruned_facts: prunedFacts,
       pruned_low_quality_facts: prunedLowQualityFacts,
       merged_entities: mergedEntities,
+      linked_entities: linkedEntities,
       core_memory_updates: coreMemoryUpdates,
       communities,
     };
@@ -210,7 +243,12 @@ async function runConsolidationTask(task: {
       .where("id", "=", task.id)
       .execute();
 
-    log.memory.info("Consolidation completed", { taskId: task.id });
+    daemonEvents.emit("task:complete", {
+      taskId: task.id,
+      taskType: task.task_type,
+      status: "completed",
+    });
+    log.memory.debug("Consolidation completed", { taskId: task.id });
   } catch (error) {
     const message = error instanceof Error ? error.message : String(error);
     await db
@@ -233,6 +271,11 @@ async function runConsolidationTask(task: {
       .where("id", "=", task.id)
       .execute();
 
+    daemonEvents.emit("task:complete", {
+      taskId: task.id,
+      taskType: task.task_type,
+      status: "failed",
+    });
     log.memory.error("Consolidation failed", { taskId: task.id, error: message });
   }
 }
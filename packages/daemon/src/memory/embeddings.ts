@@ -1,22 +1,163 @@
 import { sql } from "kysely";
 
+import { loadConfig } from "@dere/shared-config";
+
 import { getDb } from "../db.js";
-import { OpenAIEmbedder } from "@dere/graph";
+import { createEmbedder, type Embedder } from "@dere/graph";
 import { log } from "../logger.js";
 
 const RECALL_EMBEDDING_CHECK_INTERVAL_MS = 120_000;
 const RECALL_EMBEDDING_BATCH_SIZE = 50;
+const RECALL_EMBEDDING_CHUNK_SIZE = 10;
+const DEFAULT_EMBEDDING_CONCURRENCY = 4;
 
 let recallEmbeddingTimer: ReturnType<typeof setInterval> | null = null;
 let recallEmbeddingRunning = false;
-let recallEmbedderPromise: Promise<OpenAIEmbedder | null> | null = null;
+let recallEmbedderPromise: Promise<Embedder | null> | null = null;
 let lastEmbedderError: string | null = null;
 
 export function vectorLiteral(embedding: number[]): string {
   return `[${embedding.map((value) => value.toFixed(6)).join(",")}]`;
 }
 
-export async function getRecallEmbedder(): Promise<OpenAIEmbedder | null> {
+export type VectorMetric = "cosine" | "l2";
+
+const VECTOR_INDEX_NAME = "idx_conversation_blocks_embedding";
+const VECTOR_OPS: Record<VectorMetric, string> = {
+  cosine: "vector_cosine_ops",
+  l2: "vector_l2_ops",
+};
+
+/** Read `[dere_graph].vector_metric`, defaulting to cosine. */
+export async function loadVectorMetric(): Promise<VectorMetric> {
+  try {
+    const config = await loadConfig();
+    const graphConfig = (config.dere_graph ?? {}) as Record<string, unknown>;
+    return graphConfig.vector_metric === "l2" ? "l2" : "cosine";
+  } catch {
+    return "cosine";
+  }
+}
+
+/** Read `[dere_graph].embedding_concurrency`, the number of embed requests in flight. */
+async function loadEmbeddingConcurrency(): Promise<number> {
+  try {
+    const config = await loadConfig();
+    const graphConfig = (config.dere_graph ?? {}) as Record<string, unknown>;
+    const value = Number(graphConfig.embedding_concurrency);
+    return Number.isFinite(value) && value >= 1 ? Math.floor(value) : DEFAULT_EMBEDDING_CONCURRENCY;
+  } catch {
+    return DEFAULT_EMBEDDING_CONCURRENCY;
+  }
+}
+
+/**
+ * Embed texts in chunks with at most `concurrency` requests in flight.
+ * Results line up with the input; a failed chunk yields empty vectors so
+ * those blocks are retried on the next pass.
+ */
+async function embedConcurrently(
+  embedder: Embedder,
+  texts: string[],
+  concurrency: number,
+): Promise<number[][]> {
+  const chunks: { start: number; texts: string[] }[] = [];
+  for (let start = 0; start < texts.length; start += RECALL_EMBEDDING_CHUNK_SIZE) {
+    chunks.push({ start, texts: texts.slice(start, start + RECALL_EMBEDDING_CHUNK_SIZE) });
+  }
+
+  const results: number[][] = new Array(texts.length).fill([]);
+  let next = 0;
+  const worker = async () => {
+    while (next < chunks.length) {
+      const chunk = chunks[next];
+      next += 1;
+      if (!chunk) {
+        continue;
+      }
+      try {
+        const embeddings = await embedder.createBatch(chunk.texts);
+        embeddings.forEach((embedding, i) => {
+          results[chunk.start + i] = embedding;
+        });
+      } catch (error) {
+        log.recall.warn("Embedding chunk failed", {
+          size: chunk.texts.length,
+          error: String(error),
+        });
+      }
+    }
+  };
+
+  const workers = Math.max(1, Math.min(concurrency, chunks.length));
+  await Promise.all(Array.from({ length: workers }, worker));
+  return results;
+}
+
+/** Distance between stored block embeddings and a query vector literal. */
+export function vectorDistance(metric: VectorMetric, vector: string) {
+  return metric === "l2"
+    ? sql<number>`cb.content_embedding <-> ${vector}::vector`
+    : sql<number>`cb.content_embedding <=> ${vector}::vector`;
+}
+
+/** Similarity score in (0, 1], higher is closer, for either metric. */
+export function vectorScore(metric: VectorMetric, vector: string) {
+  return metric === "l2"
+    ? sql<number>`1 / (1 + (cb.content_embedding <-> ${vector}::vector))`
+    : sql<number>`1 - (cb.content_embedding <=> ${vector}::vector)`;
+}
+
+async function getIndexedVectorMetric(): Promise<VectorMetric | null> {
+  const db = await getDb();
+  const result = await sql<{ indexdef: string }>`
+    SELECT indexdef FROM pg_indexes WHERE indexname = ${VECTOR_INDEX_NAME}
+  `.execute(db);
+  const indexdef = result.rows[0]?.indexdef ?? "";
+  if (indexdef.includes(VECTOR_OPS.l2)) {
+    return "l2";
+  }
+  if (indexdef.includes(VECTOR_OPS.cosine)) {
+    return "cosine";
+  }
+  return null;
+}
+
+/**
+ * Warn when the vector index was built for a different metric than the one
+ * configured; queries still work but can't use the index.
+ */
+export async function checkVectorIndexMetric(): Promise<void> {
+  try {
+    const configured = await loadVectorMetric();
+    const indexed = await getIndexedVectorMetric();
+    if (indexed && indexed !== configured) {
+      log.recall.warn("Vector index metric does not match config; run `dere embeddings reindex`", {
+        indexed,
+        configured,
+      });
+    }
+  } catch (error) {
+    log.recall.warn("Vector index check failed", { error: String(error) });
+  }
+}
+
+/** Drop and recreate the vector index using the configured metric. */
+export async function reindexVectorIndex(): Promise<VectorMetric> {
+  const metric = await loadVectorMetric();
+  const db = await getDb();
+  await sql`DROP INDEX IF EXISTS ${sql.raw(VECTOR_INDEX_NAME)}`.execute(db);
+  await sql`
+    CREATE INDEX ${sql.raw(VECTOR_INDEX_NAME)}
+    ON conversation_blocks
+    USING ivfflat (content_embedding ${sql.raw(VECTOR_OPS[metric])})
+    WITH (lists = 100)
+  `.execute(db);
+  log.recall.info("Vector index rebuilt", { metric });
+  return metric;
+}
+
+export async function getRecallEmbedder(): Promise<Embedder | null> {
   if (recallEmbedderPromise) {
     const cached = await recallEmbedderPromise;
     if (cached) {
@@ -26,7 +167,7 @@ export async function getRecallEmbedder(): Promise<OpenAIEmbedder | null> {
 
   recallEmbedderPromise = (async () => {
     try {
-      return await OpenAIEmbedder.fromConfig();
+      return await createEmbedder();
     } catch (error) {
       const message = String(error);
       if (message !== lastEmbedderError) {
@@ -44,87 +185,242 @@ export async function getRecallEmbedder(): Promise<OpenAIEmbedder | null> {
   return resolved;
 }
 
-async function backfillConversationBlocks(): Promise<void> {
-  if (recallEmbeddingRunning) {
-    return;
+// Skip slash-command invocations (noise in the vector space) and conversations
+// from sessions that opted out of embeddings.
+const embeddingsEnabled = sql<boolean>`c.message_type <> 'command' and not exists (
+  select 1 from sessions s
+  where s.id = c.session_id and 'embeddings' = any(s.disabled_tasks)
+)`;
+
+// Blocks with no vector, or one from a model other than the current one; after
+// a model switch the backfill gradually re-embeds everything.
+function needsEmbedding(model: string) {
+  return sql<boolean>`(cb.content_embedding is null
+    or cb.embedding_model is distinct from ${model})`;
+}
+
+export type EmbeddingBackfillResult = {
+  seeded: number;
+  embedded: number;
+  skipped: number;
+  remaining: number;
+};
+
+async function countMissingEmbeddings(model: string): Promise<number> {
+  const db = await getDb();
+  const unseeded = await db
+    .selectFrom("conversations as c")
+    .leftJoin("conversation_blocks as cb", "cb.conversation_id", "c.id")
+    .select(sql<number>`count(*)::int`.as("count"))
+    .where("cb.id", "is", null)
+    .where("c.prompt", "is not", null)
+    .where(sql<boolean>`c.prompt <> ''`)
+    .where(embeddingsEnabled)
+    .executeTakeFirst();
+  const unembedded = await db
+    .selectFrom("conversation_blocks as cb")
+    .innerJoin("conversations as c", "c.id", "cb.conversation_id")
+    .select(sql<number>`count(*)::int`.as("count"))
+    .where(needsEmbedding(model))
+    .where("cb.block_type", "=", "text")
+    .where("cb.text", "is not", null)
+    .where(sql<boolean>`cb.text <> ''`)
+    .where(embeddingsEnabled)
+    .executeTakeFirst();
+  return Number(unseeded?.count ?? 0) + Number(unembedded?.count ?? 0);
+}
+
+async function backfillBatch(
+  embedder: Embedder,
+  batchSize: number,
+): Promise<Omit<EmbeddingBackfillResult, "remaining">> {
+  const db = await getDb();
+  const now = new Date();
+
+  const missingConversations = await db
+    .selectFrom("conversations as c")
+    .leftJoin("conversation_blocks as cb", "cb.conversation_id", "c.id")
+    .select(["c.id as conversation_id", "c.prompt as prompt"])
+    .where("cb.id", "is", null)
+    .where("c.prompt", "is not", null)
+    .where(sql<boolean>`c.prompt <> ''`)
+    .where(embeddingsEnabled)
+    .limit(batchSize)
+    .execute();
+
+  if (missingConversations.length > 0) {
+    await db
+      .insertInto("conversation_blocks")
+      .values(
+        missingConversations.map((row) => ({
+          conversation_id: row.conversation_id as number,
+          ordinal: 0,
+          block_type: "text",
+          text: String(row.prompt ?? ""),
+          tool_use_id: null,
+          tool_name: null,
+          tool_input: null,
+          is_error: null,
+          content_embedding: null,
+          created_at: now,
+        })),
+      )
+      .execute();
   }
-  recallEmbeddingRunning = true;
 
+  const blocks = await db
+    .selectFrom("conversation_blocks as cb")
+    .innerJoin("conversations as c", "c.id", "cb.conversation_id")
+    .select(["cb.id as block_id", "cb.text as text"])
+    .where(needsEmbedding(embedder.model))
+    .where("cb.block_type", "=", "text")
+    .where("cb.text", "is not", null)
+    .where(sql<boolean>`cb.text <> ''`)
+    .where(embeddingsEnabled)
+    .limit(batchSize)
+    .execute();
+
+  if (blocks.length === 0) {
+    return { seeded: missingConversations.length, embedded: 0, skipped: 0 };
+  }
+
+  const texts = blocks.map((block) => String(block.text ?? "").replace(/\n/g, " "));
+  const concurrency = await loadEmbeddingConcurrency();
+  const embeddings = await embedConcurrently(embedder, texts, concurrency);
+
+  // Writes stay sequential; only the embed requests run in parallel.
+  let embedded = 0;
+  let skipped = 0;
+  for (let i = 0; i < blocks.length; i += 1) {
+    const block = blocks[i];
+    if (!block) {
+      continue;
+    }
+    const embedding = embeddings[i];
+    if (!embedding || embedding.length === 0) {
+      skipped += 1;
+      continue;
+    }
+    const vector = vectorLiteral(embedding);
+    await db
+      .updateTable("conversation_blocks")
+      .set({ content_embedding: sql`${vector}::vector`, embedding_model: embedder.model })
+      .where("id", "=", block.block_id as number)
+      .execute();
+    embedded += 1;
+  }
+
+  return { seeded: missingConversations.length, embedded, skipped };
+}
+
+/**
+ * Embed a session summary right after it's stored. Failures are left for the
+ * background loop, which picks up any summary without an embedding.
+ */
+export async function embedSessionSummary(sessionId: number, summary: string): Promise<void> {
   try {
     const embedder = await getRecallEmbedder();
     if (!embedder) {
       return;
     }
-
+    const embedding = await embedder.create(summary.replace(/\n/g, " "));
     const db = await getDb();
-    const now = new Date();
-
-    const missingConversations = await db
-      .selectFrom("conversations as c")
-      .leftJoin("conversation_blocks as cb", "cb.conversation_id", "c.id")
-      .select(["c.id as conversation_id", "c.prompt as prompt"])
-      .where("cb.id", "is", null)
-      .where("c.prompt", "is not", null)
-      .where(sql<boolean>`c.prompt <> ''`)
-      .limit(RECALL_EMBEDDING_BATCH_SIZE)
+    await db
+      .updateTable("sessions")
+      .set({
+        summary_embedding: sql`${vectorLiteral(embedding)}::vector`,
+        summary_embedding_model: embedder.model,
+      })
+      .where("id", "=", sessionId)
+      .where("summary", "=", summary)
       .execute();
+  } catch (error) {
+    log.recall.warn("Summary embedding failed", { sessionId, error: String(error) });
+  }
+}
 
-    if (missingConversations.length > 0) {
-      await db
-        .insertInto("conversation_blocks")
-        .values(
-          missingConversations.map((row) => ({
-            conversation_id: row.conversation_id as number,
-            ordinal: 0,
-            block_type: "text",
-            text: String(row.prompt ?? ""),
-            tool_use_id: null,
-            tool_name: null,
-            tool_input: null,
-            is_error: null,
-            content_embedding: null,
-            created_at: now,
-          })),
-        )
-        .execute();
-    }
+async function backfillSummaryEmbeddings(embedder: Embedder, batchSize: number): Promise<number> {
+  const db = await getDb();
+  const sessions = await db
+    .selectFrom("sessions")
+    .select(["id", "summary"])
+    .where("summary", "is not", null)
+    .where(sql<boolean>`summary <> ''`)
+    .where(
+      sql<boolean>`(summary_embedding is null
+        or summary_embedding_model is distinct from ${embedder.model})`,
+    )
+    .limit(batchSize)
+    .execute();
+  if (sessions.length === 0) {
+    return 0;
+  }
 
-    const blocks = await db
-      .selectFrom("conversation_blocks as cb")
-      .innerJoin("conversations as c", "c.id", "cb.conversation_id")
-      .select(["cb.id as block_id", "cb.text as text"])
-      .where("cb.content_embedding", "is", null)
-      .where("cb.block_type", "=", "text")
-      .where("cb.text", "is not", null)
-      .where(sql<boolean>`cb.text <> ''`)
-      .limit(RECALL_EMBEDDING_BATCH_SIZE)
+  const texts = sessions.map((session) => String(session.summary ?? "").replace(/\n/g, " "));
+  const embeddings = await embedder.createBatch(texts);
+  let embedded = 0;
+  for (let i = 0; i < sessions.length; i += 1) {
+    const session = sessions[i];
+    const embedding = embeddings[i];
+    if (!session || !embedding || embedding.length === 0) {
+      continue;
+    }
+    await db
+      .updateTable("sessions")
+      .set({
+        summary_embedding: sql`${vectorLiteral(embedding)}::vector`,
+        summary_embedding_model: embedder.model,
+      })
+      .where("id", "=", session.id)
+      .where("summary", "=", String(session.summary))
       .execute();
+    embedded += 1;
+  }
+  return embedded;
+}
+
+async function backfillConversationBlocks(): Promise<void> {
+  if (recallEmbeddingRunning) {
+    return;
+  }
+  recallEmbeddingRunning = true;
 
-    if (blocks.length === 0) {
+  try {
+    const embedder = await getRecallEmbedder();
+    if (!embedder) {
       return;
     }
+    await backfillBatch(embedder, RECALL_EMBEDDING_BATCH_SIZE);
+    await backfillSummaryEmbeddings(embedder, RECALL_EMBEDDING_BATCH_SIZE);
+  } catch (error) {
+    log.recall.warn("Embedding backfill failed", { error: String(error) });
+  } finally {
+    recallEmbeddingRunning = false;
+  }
+}
 
-    const texts = blocks.map((block) => String(block.text ?? "").replace(/\n/g, " "));
-    const embeddings = await embedder.createBatch(texts);
+/**
+ * Run a single on-demand backfill pass of up to `limit` blocks. Returns null
+ * when the background loop is mid-batch so callers can retry. Progress is
+ * persisted per block, so re-running simply picks up where the last pass
+ * stopped.
+ */
+export async function runEmbeddingBackfill(limit: number): Promise<EmbeddingBackfillResult | null> {
+  if (recallEmbeddingRunning) {
+    return null;
+  }
+  recallEmbeddingRunning = true;
 
-    for (let i = 0; i < blocks.length; i += 1) {
-      const block = blocks[i];
-      if (!block) {
-        continue;
-      }
-      const embedding = embeddings[i];
-      if (!embedding || embedding.length === 0) {
-        continue;
-      }
-      const vector = vectorLiteral(embedding);
-      await db
-        .updateTable("conversation_blocks")
-        .set({ content_embedding: sql`${vector}::vector` })
-        .where("id", "=", block.block_id as number)
-        .execute();
+  try {
+    const embedder = await getRecallEmbedder();
+    if (!embedder) {
+      throw new Error("Embedder unavailable");
     }
-  } catch (error) {
-    log.recall.warn("Embedding backfill failed", { error: String(error) });
+    const batchSize = Math.max(1, Math.min(limit, RECALL_EMBEDDING_BATCH_SIZE));
+    const result = await backfillBatch(embedder, batchSize);
+    const remaining = await countMissingEmbeddings(embedder.model);
+    log.recall.info("Embedding backfill pass complete", { ...result, remaining });
+    return { ...result, remaining };
   } finally {
     recallEmbeddingRunning = false;
   }
@@ -137,6 +433,7 @@ export function startRecallEmbeddingLoop(): void {
   recallEmbeddingTimer = setInterval(() => {
     void backfillConversationBlocks();
   }, RECALL_EMBEDDING_CHECK_INTERVAL_MS);
+  void checkVectorIndexMetric();
 
   log.recall.info("Embedding backfill loop started", { intervalMs: RECALL_EMBEDDING_CHECK_INTERVAL_MS });
 }
@@ -2,7 +2,13 @@ import type { Hono } from "hono";
 import { sql } from "kysely";
 
 import { getDb } from "../db.js";
-import { getRecallEmbedder, vectorLiteral } from "./embeddings.js";
+import {
+  getRecallEmbedder,
+  loadVectorMetric,
+  vectorDistance,
+  vectorLiteral,
+  vectorScore,
+} from "./embeddings.js";
 import { log } from "../logger.js";
 
 type RecallResult = {
@@ -20,8 +26,87 @@ type RecallResult = {
   finding_id?: number | null;
   task_id?: number | null;
   confidence?: number | null;
+  /** Surrounding turns when `context_window` was requested */
+  context?: ConversationTurn[];
 };
 
+/** Most surrounding turns a caller can ask for on each side of a hit. */
+export const MAX_CONTEXT_WINDOW = 5;
+
+export type ConversationTurn = {
+  conversation_id: number;
+  message_type: string;
+  prompt: string;
+  timestamp: number;
+};
+
+/** Clamp a requested context window to 0..MAX_CONTEXT_WINDOW. */
+export function parseContextWindow(value: unknown): number {
+  const parsed = Number(value);
+  if (!Number.isFinite(parsed) || parsed <= 0) {
+    return 0;
+  }
+  return Math.min(Math.floor(parsed), MAX_CONTEXT_WINDOW);
+}
+
+/**
+ * The `window` user/assistant turns before and after each hit in its own
+ * session, oldest first with the hit included, so a match like "now fix it"
+ * comes back with the exchange that gives it meaning. Keyed by conversation id.
+ */
+export async function fetchConversationWindows(
+  hits: Array<{ conversation_id: number; session_id: number; timestamp: number }>,
+  window: number,
+): Promise<Map<number, ConversationTurn[]>> {
+  const windows = new Map<number, ConversationTurn[]>();
+  if (window <= 0) {
+    return windows;
+  }
+  const db = await getDb();
+  for (const hit of hits) {
+    if (windows.has(hit.conversation_id)) {
+      continue;
+    }
+    const base = db
+      .selectFrom("conversations")
+      .select(["id as conversation_id", "message_type", "prompt", "timestamp"])
+      .where("session_id", "=", hit.session_id)
+      .where("message_type", "in", ["user", "assistant"])
+      .where("id", "<>", hit.conversation_id);
+    const [before, current, after] = await Promise.all([
+      base
+        .where((eb) =>
+          eb.or([
+            eb("timestamp", "<", hit.timestamp),
+            eb.and([eb("timestamp", "=", hit.timestamp), eb("id", "<", hit.conversation_id)]),
+          ]),
+        )
+        .orderBy("timestamp", "desc")
+        .orderBy("id", "desc")
+        .limit(window)
+        .execute(),
+      db
+        .selectFrom("conversations")
+        .select(["id as conversation_id", "message_type", "prompt", "timestamp"])
+        .where("id", "=", hit.conversation_id)
+        .execute(),
+      base
+        .where((eb) =>
+          eb.or([
+            eb("timestamp", ">", hit.timestamp),
+            eb.and([eb("timestamp", "=", hit.timestamp), eb("id", ">", hit.conversation_id)]),
+          ]),
+        )
+        .orderBy("timestamp", "asc")
+        .orderBy("id", "asc")
+        .limit(window)
+        .execute(),
+    ]);
+    windows.set(hit.conversation_id, [...before.reverse(), ...current, ...after]);
+  }
+  return windows;
+}
+
 function rrfScores(resultLists: string[][], rankConst = 60): Record<string, number> {
   const scores: Record<string, number> = {};
   for (const results of resultLists) {
@@ -59,6 +144,7 @@ export function registerRecallRoutes(app: Hono): void {
     const daysBack = c.req.query("days_back");
     const sessionId = c.req.query("session_id");
     const userId = c.req.query("user_id");
+    const contextWindow = parseContextWindow(c.req.query("context_window"));
 
     const cutoffSeconds =
       daysBack && Number(daysBack) > 0
@@ -117,6 +203,7 @@ export function registerRecallRoutes(app: Hono): void {
       try {
         const queryEmbedding = await embedder.create(query.replace(/\n/g, " "));
         const vector = vectorLiteral(queryEmbedding);
+        const metric = await loadVectorMetric();
 
         let vectorQuery = db
           .selectFrom("conversation_blocks as cb")
@@ -130,14 +217,15 @@ export function registerRecallRoutes(app: Hono): void {
             "c.timestamp as timestamp",
             "c.medium as medium",
             "c.user_id as user_id",
-            sql<number>`1 - (cb.content_embedding <=> ${vector}::vector)`.as("score"),
+            vectorScore(metric, vector).as("score"),
           ])
           .where("cb.block_type", "=", "text")
           .where("cb.text", "is not", null)
           .where(sql<boolean>`cb.text <> ''`)
           .where("c.message_type", "in", ["user", "assistant", "system"])
           .where("cb.content_embedding", "is not", null)
-          .orderBy(sql`cb.content_embedding <=> ${vector}::vector`)
+          .where("cb.embedding_model", "=", embedder.model)
+          .orderBy(vectorDistance(metric, vector))
           .limit(limit * 2);
 
         if (sessionId && Number.isFinite(Number(sessionId))) {
@@ -251,6 +339,26 @@ export function registerRecallRoutes(app: Hono): void {
       }
     }
 
+    if (contextWindow > 0) {
+      const hits = results.flatMap((result) =>
+        result.conversation_id && result.session_id
+          ? [
+              {
+                conversation_id: result.conversation_id,
+                session_id: result.session_id,
+                timestamp: result.timestamp,
+              },
+            ]
+          : [],
+      );
+      const windows = await fetchConversationWindows(hits, contextWindow);
+      for (const result of results) {
+        if (result.conversation_id) {
+          result.context = windows.get(result.conversation_id) ?? [];
+        }
+      }
+    }
+
     return c.json({ query, results });
   });
 
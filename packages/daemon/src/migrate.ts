@@ -1,26 +1,11 @@
-import { promises as fs } from "node:fs";
-import path from "node:path";
-import { fileURLToPath } from "node:url";
-
-import { FileMigrationProvider, Migrator } from "kysely";
-
 import { createDb } from "./db.js";
 import { log } from "./logger.js";
-
-const here = path.dirname(fileURLToPath(import.meta.url));
-const migrationsDir = path.join(here, "..", "migrations");
+import { createMigrator } from "./schema.js";
 
 async function main(): Promise<void> {
   const { db } = await createDb();
 
-  const migrator = new Migrator({
-    db,
-    provider: new FileMigrationProvider({
-      fs,
-      path,
-      migrationFolder: migrationsDir,
-    }),
-  });
+  const migrator = createMigrator(db);
 
   const { error, results } = await migrator.migrateToLatest();
 
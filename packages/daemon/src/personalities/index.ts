@@ -12,6 +12,8 @@ export interface Personality {
   avatar?: string;
   prompt_content: string;
   announcement?: string;
+  /** Extra instructions for how session summaries should read under this personality */
+  summary_tone?: string;
   occ_goals: unknown[];
   occ_standards: unknown[];
   occ_attitudes: unknown[];
@@ -39,6 +41,9 @@ interface PersonalityDoc {
   prompt?: {
     content?: string;
   };
+  summary?: {
+    tone?: string;
+  };
   occ?: {
     goals?: unknown[];
     standards?: unknown[];
@@ -64,6 +69,7 @@ function parsePersonality(data: string): Personality {
   const metadata = parsed.metadata ?? {};
   const display = parsed.display ?? {};
   const prompt = parsed.prompt ?? {};
+  const summary = parsed.summary ?? {};
   const occ = parsed.occ ?? {};
 
   const personality: Personality = {
@@ -83,6 +89,9 @@ function parsePersonality(data: string): Personality {
   if (display.announcement) {
     personality.announcement = display.announcement;
   }
+  if (summary.tone?.trim()) {
+    personality.summary_tone = summary.tone.trim();
+  }
   return personality;
 }
 
@@ -140,14 +149,38 @@ export async function listPersonalityInfos(): Promise<PersonalityInfo[]> {
   return results;
 }
 
+function answersTo(personality: Personality, name: string): boolean {
+  const wanted = name.toLowerCase();
+  return [personality.name, personality.short_name, ...personality.aliases].some(
+    (candidate) => candidate.toLowerCase() === wanted,
+  );
+}
+
+/**
+ * Load by file name, then by name, short name, or alias. User definitions in
+ * <config dir>/personalities are checked before the built-ins at every step, so
+ * a user file can replace a built-in or add a new personality.
+ */
 export async function loadPersonality(name: string): Promise<Personality> {
-  const embeddedDir = defaultEmbeddedDir();
-  const userDir = defaultUserDir();
-  const userPath = join(userDir, `${name}.toml`);
-  try {
-    return await loadPersonalityFromFile(userPath);
-  } catch {
-    const embeddedPath = join(embeddedDir, `${name}.toml`);
-    return await loadPersonalityFromFile(embeddedPath);
+  const dirs = [defaultUserDir(), defaultEmbeddedDir()];
+  for (const dir of dirs) {
+    try {
+      return await loadPersonalityFromFile(join(dir, `${name}.toml`));
+    } catch {
+      // fall through to the next directory
+    }
+  }
+  for (const dir of dirs) {
+    for (const candidate of await listTomlNames(dir)) {
+      try {
+        const personality = await loadPersonalityFromFile(join(dir, `${candidate}.toml`));
+        if (answersTo(personality, name)) {
+          return personality;
+        }
+      } catch {
+        // skip unreadable definitions
+      }
+    }
   }
+  throw new Error(`Personality not found: ${name}`);
 }
@@ -0,0 +1,125 @@
+import type { Hono } from "hono";
+import { sql } from "kysely";
+
+import { getDb } from "../db.js";
+import { log } from "../logger.js";
+import { sessionIdsTagged } from "../sessions/tags.js";
+import { isExcludedWorkingDir, normalizeWorkingDir } from "../utils/working-dir.js";
+
+const DEFAULT_STATS_DAYS = 30;
+
+function nowDate(): Date {
+  return new Date();
+}
+
+async function parseJson<T>(req: Request): Promise<T | null> {
+  try {
+    return (await req.json()) as T;
+  } catch {
+    return null;
+  }
+}
+
+function readOptionalString(value: unknown): string | null {
+  return typeof value === "string" && value.trim() ? value.trim() : null;
+}
+
+export function registerCostRoutes(app: Hono): void {
+  app.post("/costs/record", async (c) => {
+    const payload = await parseJson<Record<string, unknown>>(c.req.raw);
+    if (!payload) {
+      return c.json({ error: "Invalid JSON payload" }, 400);
+    }
+
+    const sessionId = payload.session_id;
+    const totalCost = payload.total_cost_usd;
+    if (typeof sessionId !== "number" || typeof totalCost !== "number") {
+      return c.json({ error: "session_id and total_cost_usd are required" }, 400);
+    }
+    const totalDuration =
+      typeof payload.total_duration_ms === "number" ? Math.round(payload.total_duration_ms) : 0;
+
+    // Claude reports running totals, so each report replaces the previous one.
+    const now = nowDate();
+    const workingDir = readOptionalString(payload.working_dir);
+    if (workingDir && (await isExcludedWorkingDir(workingDir))) {
+      return c.json({ status: "excluded" });
+    }
+    const values = {
+      total_cost_usd: totalCost,
+      total_duration_ms: totalDuration,
+      model: readOptionalString(payload.model),
+      working_dir: workingDir ? await normalizeWorkingDir(workingDir) : null,
+      personality: readOptionalString(payload.personality),
+      updated_at: now,
+    };
+
+    try {
+      const db = await getDb();
+      await db
+        .insertInto("session_costs")
+        .values({ session_id: sessionId, created_at: now, ...values })
+        .onConflict((oc) => oc.column("session_id").doUpdateSet(values))
+        .execute();
+    } catch (error) {
+      log.session.warn("Failed to record session cost", { error: String(error) });
+      return c.json({ error: "Failed to record cost" }, 500);
+    }
+
+    return c.json({ status: "recorded" });
+  });
+
+  app.get("/costs/stats", async (c) => {
+    const daysParam = Number(c.req.query("days"));
+    const days = Number.isFinite(daysParam) && daysParam > 0 ? daysParam : DEFAULT_STATS_DAYS;
+    const since = new Date(Date.now() - days * 24 * 60 * 60 * 1000);
+    const tag = c.req.query("tag");
+
+    const db = await getDb();
+    // Every breakdown shares the window and optional tag filter.
+    const recent = db.selectFrom("session_costs").where("updated_at", ">=", since);
+    const costs = tag ? recent.where("session_id", "in", sessionIdsTagged(db, tag)) : recent;
+    const totals = await costs
+      .select([
+        sql<number>`coalesce(sum(total_cost_usd), 0)`.as("total_cost_usd"),
+        sql<number>`count(*)::int`.as("sessions"),
+      ])
+      .executeTakeFirst();
+
+    const byProject = await costs
+      .select([
+        sql<string>`coalesce(working_dir, 'unknown')`.as("key"),
+        sql<number>`sum(total_cost_usd)`.as("total_cost_usd"),
+        sql<number>`count(*)::int`.as("sessions"),
+      ])
+      .groupBy(sql`coalesce(working_dir, 'unknown')`)
+      .orderBy(sql`sum(total_cost_usd)`, "desc")
+      .execute();
+
+    const byPersonality = await costs
+      .select([
+        sql<string>`coalesce(personality, 'none')`.as("key"),
+        sql<number>`sum(total_cost_usd)`.as("total_cost_usd"),
+        sql<number>`count(*)::int`.as("sessions"),
+      ])
+      .groupBy(sql`coalesce(personality, 'none')`)
+      .orderBy(sql`sum(total_cost_usd)`, "desc")
+      .execute();
+
+    const normalize = (rows: Array<{ key: string; total_cost_usd: number; sessions: number }>) =>
+      rows.map((row) => ({
+        key: row.key,
+        total_cost_usd: Number(row.total_cost_usd ?? 0),
+        sessions: Number(row.sessions ?? 0),
+      }));
+
+    return c.json({
+      days,
+      tag: tag ?? null,
+      total_cost_usd: Number(totals?.total_cost_usd ?? 0),
+      sessions: Number(totals?.sessions ?? 0),
+      by_project: normalize(byProject),
+      by_personality: normalize(byPersonality),
+    });
+  });
+}
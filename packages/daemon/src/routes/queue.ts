@@ -1,8 +1,15 @@
 import type { Hono } from "hono";
+import { sql } from "kysely";
 
 import { getDb } from "../db.js";
+import { daemonEvents } from "../events.js";
 
 const STATUSES = ["pending", "processing", "completed", "failed"] as const;
+const TERMINAL_STATUSES = new Set(["completed", "failed"]);
+const TASKS_DEFAULT_LIMIT = 20;
+const TASKS_MAX_LIMIT = 500;
+const WAIT_DEFAULT_MS = 30_000;
+const WAIT_MAX_MS = 300_000;
 
 function nowDate(): Date {
   return new Date();
@@ -82,4 +89,102 @@ export function registerQueueRoutes(app: Hono): void {
 
     return c.json(stats);
   });
+  // Polled by `dere queue tail`: the latest tasks, or with `since` every task
+  // created or picked up since then, oldest change first.
+  app.get("/queue/tasks", async (c) => {
+    const limitRaw = Number(c.req.query("limit") ?? TASKS_DEFAULT_LIMIT);
+    const limit = Number.isFinite(limitRaw) && limitRaw > 0 ? Math.floor(limitRaw) : 0;
+    if (limit === 0) {
+      return c.json({ error: "Invalid limit" }, 400);
+    }
+    const sinceRaw = c.req.query("since");
+    const since = sinceRaw ? new Date(sinceRaw) : null;
+    if (since && Number.isNaN(since.getTime())) {
+      return c.json({ error: "Invalid since timestamp" }, 400);
+    }
+    const taskType = c.req.query("type");
+
+    const now = nowDate();
+    const db = await getDb();
+    let query = db
+      .selectFrom("task_queue")
+      .select([
+        "id",
+        "task_type",
+        "status",
+        "priority",
+        "session_id",
+        "created_at",
+        "processed_at",
+        "retry_count",
+        "error_message",
+      ])
+      .limit(Math.min(limit, TASKS_MAX_LIMIT));
+    if (taskType) {
+      query = query.where("task_type", "=", taskType);
+    }
+    if (since) {
+      query = query
+        .where((eb) => eb.or([eb("created_at", ">=", since), eb("processed_at", ">=", since)]))
+        .orderBy(sql`coalesce(processed_at, created_at)`, "asc");
+    } else {
+      query = query.orderBy("id", "desc");
+    }
+    const tasks = await query.execute();
+
+    return c.json({ tasks: since ? tasks : tasks.reverse(), now: now.toISOString() });
+  });
+
+  // Long-poll: answers as soon as the task completes or fails, or with its
+  // current state once timeout_ms passes.
+  app.get("/queue/tasks/:task_id/wait", async (c) => {
+    const taskId = Number(c.req.param("task_id"));
+    if (!Number.isInteger(taskId)) {
+      return c.json({ error: "Invalid task_id" }, 400);
+    }
+    const timeoutRaw = Number(c.req.query("timeout_ms") ?? WAIT_DEFAULT_MS);
+    const timeoutMs =
+      Number.isFinite(timeoutRaw) && timeoutRaw >= 0
+        ? Math.min(timeoutRaw, WAIT_MAX_MS)
+        : WAIT_DEFAULT_MS;
+    // Bun closes connections idle for 10s (and idleTimeout caps out below
+    // WAIT_MAX_MS), so lift the limit for this request; c.env is Bun's server.
+    const server = c.env as { timeout?: (request: Request, seconds: number) => void } | undefined;
+    server?.timeout?.(c.req.raw, 0);
+
+    const db = await getDb();
+    const readTask = () =>
+      db
+        .selectFrom("task_queue")
+        .select(["id", "task_type", "status", "processed_at", "error_message"])
+        .where("id", "=", taskId)
+        .executeTakeFirst();
+
+    // Subscribe before the first read so a completion in between isn't missed.
+    let finished!: () => void;
+    const done = new Promise<void>((resolve) => {
+      finished = resolve;
+    });
+    const unsubscribe = daemonEvents.on("task:complete", (event) => {
+      if (event.taskId === taskId) {
+        finished();
+      }
+    });
+    const timer = setTimeout(finished, timeoutMs);
+    try {
+      const task = await readTask();
+      if (!task) {
+        return c.json({ error: "Task not found" }, 404);
+      }
+      if (TERMINAL_STATUSES.has(task.status)) {
+        return c.json({ task, timed_out: false });
+      }
+      await done;
+      const latest = (await readTask()) ?? task;
+      return c.json({ task: latest, timed_out: !TERMINAL_STATUSES.has(latest.status) });
+    } finally {
+      clearTimeout(timer);
+      unsubscribe();
+    }
+  });
 }
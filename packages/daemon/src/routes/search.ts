@@ -1,15 +1,30 @@
 import type { Hono } from "hono";
+import { sql } from "kysely";
 
 import {
   graphAvailable,
   toDate,
-  OpenAIEmbedder,
+  createEmbedder,
   type SearchFilters,
   hybridNodeSearch,
   searchGraph,
 } from "@dere/graph";
 
+import { getDb } from "../db.js";
 import { log } from "../logger.js";
+import {
+  getRecallEmbedder,
+  loadVectorMetric,
+  reindexVectorIndex,
+  runEmbeddingBackfill,
+  vectorDistance,
+  vectorLiteral,
+  vectorScore,
+} from "../memory/embeddings.js";
+import { fetchConversationWindows, parseContextWindow } from "../memory/recall.js";
+import { normalizeWorkingDir } from "../utils/working-dir.js";
+
+const MAX_EMBEDDING_BATCH = 100;
 
 function parseLimit(value: unknown, fallback: number): number {
   const parsed = typeof value === "number" ? value : Number(value);
@@ -36,7 +51,7 @@ async function parseJson<T>(req: Request): Promise<T | null> {
 }
 
 async function createEmbedding(text: string): Promise<number[]> {
-  const embedder = await OpenAIEmbedder.fromConfig();
+  const embedder = await createEmbedder();
   return embedder.create(text);
 }
 
@@ -174,6 +189,120 @@ export function registerSearchRoutes(app: Hono): void {
     }
   });
 
+  app.post("/search/conversations", async (c) => {
+    const payload = await parseJson<{
+      query?: string;
+      limit?: number;
+      working_dir?: string | null;
+      min_similarity?: number | null;
+      message_type?: string | null;
+      context_window?: number | null;
+    }>(c.req.raw);
+    if (!payload?.query) {
+      return c.json({ results: [] }, 400);
+    }
+    const contextWindow = parseContextWindow(payload.context_window);
+    const messageType = payload.message_type ?? null;
+    if (messageType !== null && messageType !== "user" && messageType !== "assistant") {
+      return c.json({ error: "message_type must be user or assistant", results: [] }, 400);
+    }
+    const limit = parseLimit(payload.limit, 10);
+    const workingDir =
+      typeof payload.working_dir === "string"
+        ? await normalizeWorkingDir(payload.working_dir)
+        : null;
+    const minSimilarity =
+      typeof payload.min_similarity === "number" ? payload.min_similarity : null;
+
+    try {
+      const embedder = await getRecallEmbedder();
+      if (!embedder) {
+        return c.json({ error: "Embedder unavailable", results: [] }, 503);
+      }
+      const vector = vectorLiteral(await embedder.create(payload.query.replace(/\n/g, " ")));
+      const metric = await loadVectorMetric();
+      const db = await getDb();
+      let query = db
+        .selectFrom("conversation_blocks as cb")
+        .innerJoin("conversations as c", "c.id", "cb.conversation_id")
+        .innerJoin("sessions as s", "s.id", "c.session_id")
+        .select([
+          "cb.text as text",
+          "c.id as conversation_id",
+          "c.session_id as session_id",
+          "c.message_type as message_type",
+          "c.timestamp as timestamp",
+          "s.working_dir as working_dir",
+          vectorScore(metric, vector).as("similarity"),
+        ])
+        .where("cb.block_type", "=", "text")
+        .where("cb.content_embedding", "is not", null)
+        // Vectors from another model aren't comparable; the backfill replaces them
+        .where("cb.embedding_model", "=", embedder.model)
+        .orderBy(vectorDistance(metric, vector))
+        .limit(limit);
+      if (workingDir) {
+        query = query.where("s.working_dir", "=", workingDir);
+      }
+      if (minSimilarity !== null) {
+        query = query.where(vectorScore(metric, vector), ">=", minSimilarity);
+      }
+      if (messageType) {
+        query = query.where("c.message_type", "=", messageType);
+      }
+      const rows = await query.execute();
+      if (contextWindow === 0) {
+        return c.json({ results: rows });
+      }
+      const windows = await fetchConversationWindows(rows, contextWindow);
+      return c.json({
+        results: rows.map((row) => ({
+          ...row,
+          context: windows.get(row.conversation_id) ?? [],
+        })),
+      });
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      log.recall.warn("Conversation search failed", { error: message });
+      return c.json({ error: message, results: [] }, 503);
+    }
+  });
+
+  app.post("/search/summaries", async (c) => {
+    const payload = await parseJson<{ query?: string; limit?: number }>(c.req.raw);
+    if (!payload?.query) {
+      return c.json({ results: [] }, 400);
+    }
+    const limit = parseLimit(payload.limit, 10);
+
+    try {
+      const embedder = await createEmbedder();
+      const vector = vectorLiteral(await embedder.create(payload.query.replace(/\n/g, " ")));
+      const db = await getDb();
+      const rows = await db
+        .selectFrom("sessions")
+        .select([
+          "id",
+          "name",
+          "working_dir",
+          "personality",
+          "start_time",
+          "summary",
+          sql<number>`1 - (summary_embedding <=> ${vector}::vector)`.as("similarity"),
+        ])
+        .where("summary_embedding", "is not", null)
+        .where("summary_embedding_model", "=", embedder.model)
+        .orderBy(sql`summary_embedding <=> ${vector}::vector`)
+        .limit(limit)
+        .execute();
+      return c.json({ results: rows });
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      log.recall.warn("Summary search failed", { error: message });
+      return c.json({ error: message, results: [] }, 503);
+    }
+  });
+
   app.post("/embeddings/generate", async (c) => {
     const url = new URL(c.req.url);
     const queryText = url.searchParams.get("text");
@@ -189,10 +318,64 @@ export function registerSearchRoutes(app: Hono): void {
     }
 
     try {
-      const embedding = await createEmbedding(text);
-      return c.json({ embedding, model: "text-embedding-3-small" });
+      const embedder = await createEmbedder();
+      const embedding = await embedder.create(text);
+      return c.json({ embedding, model: embedder.model });
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      return c.json({ error: message }, 503);
+    }
+  });
+
+  app.post("/embeddings/batch", async (c) => {
+    const payload = await parseJson<{ texts?: unknown }>(c.req.raw);
+    const texts = Array.isArray(payload?.texts)
+      ? payload.texts.filter((text): text is string => typeof text === "string")
+      : [];
+    if (texts.length === 0) {
+      return c.json({ error: "texts must be a non-empty array of strings" }, 400);
+    }
+    if (texts.length > MAX_EMBEDDING_BATCH) {
+      return c.json({ error: `at most ${MAX_EMBEDDING_BATCH} texts per batch` }, 400);
+    }
+
+    try {
+      // One provider request for the whole batch instead of one per text.
+      const embedder = await createEmbedder();
+      const embeddings = await embedder.createBatch(
+        texts.map((text) => text.replace(/\n/g, " ").trim()),
+      );
+      return c.json({ embeddings, model: embedder.model });
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      return c.json({ error: message }, 503);
+    }
+  });
+
+  app.post("/embeddings/backfill", async (c) => {
+    const payload = await parseJson<{ limit?: number }>(c.req.raw);
+    const limit = parseLimit(payload?.limit, 50);
+
+    try {
+      const result = await runEmbeddingBackfill(limit);
+      if (!result) {
+        return c.json({ error: "Backfill already in progress" }, 409);
+      }
+      return c.json(result);
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      log.recall.warn("Embedding backfill request failed", { error: message });
+      return c.json({ error: message }, 503);
+    }
+  });
+
+  app.post("/embeddings/reindex", async (c) => {
+    try {
+      const metric = await reindexVectorIndex();
+      return c.json({ status: "ok", metric });
     } catch (error) {
       const message = error instanceof Error ? error.message : String(error);
+      log.recall.warn("Vector reindex request failed", { error: message });
       return c.json({ error: message }, 503);
     }
   });
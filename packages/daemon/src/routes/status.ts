@@ -32,7 +32,19 @@ export function registerStatusRoutes(app: Hono): void {
       queueStats[status] ??= 0;
     }
 
-    const status: Record<string, unknown> = { daemon: "running", queue: queueStats };
+    const lastProcessed = await db
+      .selectFrom("task_queue")
+      .select(db.fn.max("processed_at").as("processed_at"))
+      .executeTakeFirst();
+    const lastProcessedAt = lastProcessed?.processed_at
+      ? new Date(lastProcessed.processed_at as Date | string).toISOString()
+      : null;
+
+    const status: Record<string, unknown> = {
+      daemon: "running",
+      queue: queueStats,
+      last_processed_at: lastProcessedAt,
+    };
 
     if (typeof payload.personality === "string") {
       status.personality = payload.personality;
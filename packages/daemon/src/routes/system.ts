@@ -4,6 +4,9 @@ import { isAuthFailed, resetAuthState } from "@dere/shared-llm";
 
 import { graphAvailable } from "@dere/graph";
 
+import { errorResponse } from "../errors.js";
+import { getSchemaStatus } from "../schema.js";
+
 export function registerSystemRoutes(app: Hono): void {
   app.get("/health", async (c) => {
     const dereGraph = (await graphAvailable()) ? "available" : "unavailable";
@@ -15,6 +18,16 @@ export function registerSystemRoutes(app: Hono): void {
     });
   });
 
+  // Kept off /health, which is polled on every launch.
+  app.get("/health/database", async (c) => {
+    try {
+      const status = await getSchemaStatus();
+      return c.json({ database: "ok", ...status });
+    } catch (error) {
+      return errorResponse(c, error);
+    }
+  });
+
   app.post("/auth/reset", async (c) => {
     resetAuthState();
     return c.json({ status: "ok", message: "Auth state reset. LLM features re-enabled." });
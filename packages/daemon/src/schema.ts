@@ -0,0 +1,78 @@
+import { promises as fs } from "node:fs";
+import path from "node:path";
+import { fileURLToPath } from "node:url";
+
+import { FileMigrationProvider, Migrator, sql, type Kysely } from "kysely";
+
+import type { Database } from "./db-types.js";
+import { getDb } from "./db.js";
+import { log } from "./logger.js";
+
+const here = path.dirname(fileURLToPath(import.meta.url));
+const migrationsDir = path.join(here, "..", "migrations");
+
+function migrationProvider(): FileMigrationProvider {
+  return new FileMigrationProvider({ fs, path, migrationFolder: migrationsDir });
+}
+
+/** Migrator over migrations/, applied in filename order, one transaction per run. */
+export function createMigrator(db: Kysely<Database>): Migrator {
+  return new Migrator({ db, provider: migrationProvider() });
+}
+
+export class SchemaTooNewError extends Error {
+  constructor(readonly unknownMigrations: string[]) {
+    const names = unknownMigrations.join(", ");
+    super(`Database schema is newer than this dere (unknown migrations: ${names})`);
+    this.name = "SchemaTooNewError";
+  }
+}
+
+async function appliedMigrations(db: Kysely<Database>): Promise<string[]> {
+  try {
+    const result = await sql<{ name: string }>`SELECT name FROM kysely_migration`.execute(db);
+    return result.rows.map((row) => row.name);
+  } catch (error) {
+    // Fresh database: the migration table doesn't exist yet
+    if ((error as { code?: unknown })?.code === "42P01") {
+      return [];
+    }
+    throw error;
+  }
+}
+
+export type SchemaStatus = {
+  /** Shipped with this build but not yet applied */
+  pending: string[];
+  /** Applied to the database but unknown to this build */
+  unknown: string[];
+};
+
+/** Compare the database's applied migrations with the ones this build ships. */
+export async function getSchemaStatus(): Promise<SchemaStatus> {
+  const db = await getDb();
+  const known = new Set(Object.keys(await migrationProvider().getMigrations()));
+  const applied = await appliedMigrations(db);
+  const appliedSet = new Set(applied);
+  return {
+    pending: [...known].filter((name) => !appliedSet.has(name)).sort(),
+    unknown: applied.filter((name) => !known.has(name)),
+  };
+}
+
+/**
+ * Throws SchemaTooNewError when the database has migrations we don't know
+ * about (written by a newer dere) so we don't run against a schema we can't
+ * understand; warns when migrations are pending.
+ */
+export async function checkSchemaVersion(): Promise<void> {
+  const { pending, unknown } = await getSchemaStatus();
+  if (unknown.length > 0) {
+    throw new SchemaTooNewError(unknown);
+  }
+  if (pending.length > 0) {
+    log.daemon.warn("Database migrations pending; run `bun run migrate` in packages/daemon", {
+      pending,
+    });
+  }
+}
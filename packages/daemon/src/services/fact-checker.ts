@@ -15,7 +15,8 @@ import {
   hybridNodeSearch,
   addFact,
   cosineSimilarity,
-  OpenAIEmbedder,
+  createEmbedder,
+  type Embedder,
   type EntityNode,
   type FactNode,
 } from "@dere/graph";
@@ -25,11 +26,11 @@ import { daemonEvents } from "../events.js";
 const log = getLogger("fact-checker");
 
 // Lazy-initialized embedder singleton
-let embedderInstance: OpenAIEmbedder | null = null;
+let embedderInstance: Embedder | null = null;
 
-async function getEmbedder(): Promise<OpenAIEmbedder> {
+async function getEmbedder(): Promise<Embedder> {
   if (!embedderInstance) {
-    embedderInstance = await OpenAIEmbedder.fromConfig();
+    embedderInstance = await createEmbedder();
   }
   return embedderInstance;
 }
@@ -0,0 +1,185 @@
+/**
+ * Follow-ups ("add tests for X", "refactor Y") pulled out of sessions when
+ * they're summarized, kept per project so `dere todos` can list what's still
+ * open across sessions. Separate from task_queue, which is background work.
+ */
+
+import type { Hono } from "hono";
+
+import { loadConfig, loadPromptTemplate } from "@dere/shared-config";
+import {
+  ActionItemsResultSchema,
+  ClaudeAgentTransport,
+  StructuredOutputClient,
+} from "@dere/shared-llm";
+
+import { getDb } from "../db.js";
+import { log } from "../logger.js";
+import { buildRecentTranscript } from "../utils/summary.js";
+import { normalizeWorkingDir } from "../utils/working-dir.js";
+
+const DEFAULT_ACTION_ITEM_MODEL = "claude-haiku-4-5";
+const ACTION_ITEM_TRANSCRIPT_CHARS = 8000;
+const MAX_ACTION_ITEMS_PER_PASS = 10;
+const LIST_DEFAULT_LIMIT = 50;
+
+export const ACTION_ITEM_STATUSES = ["open", "done"] as const;
+export type ActionItemStatus = (typeof ACTION_ITEM_STATUSES)[number];
+
+function isActionItemStatus(value: unknown): value is ActionItemStatus {
+  return typeof value === "string" && (ACTION_ITEM_STATUSES as readonly string[]).includes(value);
+}
+
+function getClient(): StructuredOutputClient {
+  const transport = new ClaudeAgentTransport({
+    workingDirectory: process.env.DERE_TS_LLM_CWD ?? "/tmp/dere-llm-sessions",
+  });
+  return new StructuredOutputClient({
+    transport,
+    model: process.env.DERE_ACTION_ITEM_MODEL ?? DEFAULT_ACTION_ITEM_MODEL,
+  });
+}
+
+/** `[context].action_items`; on unless set to false. */
+async function actionItemsEnabled(): Promise<boolean> {
+  try {
+    const config = await loadConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    return contextConfig.action_items !== false;
+  } catch {
+    return true;
+  }
+}
+
+/**
+ * Extract follow-ups from a session's latest turns and store the new ones.
+ * Items already recorded for the session are shown to the model so a
+ * periodic re-summary doesn't add them again in other words. Returns how
+ * many were added.
+ */
+export async function extractActionItems(sessionId: number): Promise<number> {
+  if (!(await actionItemsEnabled())) {
+    return 0;
+  }
+  const db = await getDb();
+  const session = await db
+    .selectFrom("sessions")
+    .select(["working_dir"])
+    .where("id", "=", sessionId)
+    .executeTakeFirst();
+  if (!session?.working_dir) {
+    return 0;
+  }
+
+  const rows = await db
+    .selectFrom("conversations")
+    .select(["prompt", "message_type"])
+    .where("session_id", "=", sessionId)
+    .where("message_type", "in", ["user", "assistant"])
+    .orderBy("timestamp", "desc")
+    .limit(200)
+    .execute();
+  if (rows.length === 0) {
+    return 0;
+  }
+  const existing = await db
+    .selectFrom("action_items")
+    .select(["text"])
+    .where("session_id", "=", sessionId)
+    .execute();
+
+  const { text: content } = buildRecentTranscript(rows, ACTION_ITEM_TRANSCRIPT_CHARS);
+  const prompt = await loadPromptTemplate(
+    "action-items",
+    `List the concrete follow-ups from this conversation that are still left to do: work the user or assistant said should happen later ("add tests for X", "refactor Y"). Skip anything finished within the conversation and general advice. Write each as one short imperative sentence. Leave out anything already recorded. Return an empty list when there are none.
+
+Already recorded:
+{{existing}}
+
+{{content}}`,
+    {
+      content,
+      existing: existing.length > 0 ? existing.map((item) => `- ${item.text}`).join("\n") : "none",
+    },
+  );
+  const result = await getClient().generate(prompt, ActionItemsResultSchema, {
+    schemaName: "action_items_result",
+  });
+
+  const items = Array.from(
+    new Set(result.action_items.map((item) => item.replace(/\s+/g, " ").trim()).filter(Boolean)),
+  ).slice(0, MAX_ACTION_ITEMS_PER_PASS);
+  if (items.length === 0) {
+    return 0;
+  }
+  const inserted = await db
+    .insertInto("action_items")
+    .values(
+      items.map((text) => ({ session_id: sessionId, working_dir: session.working_dir, text })),
+    )
+    .onConflict((oc) => oc.columns(["session_id", "text"]).doNothing())
+    .returning(["id"])
+    .execute();
+  log.summary.debug("Extracted action items", { sessionId, added: inserted.length });
+  return inserted.length;
+}
+
+async function parseJson<T>(req: Request): Promise<T | null> {
+  try {
+    return (await req.json()) as T;
+  } catch {
+    return null;
+  }
+}
+
+export function registerActionItemRoutes(app: Hono): void {
+  app.get("/action-items", async (c) => {
+    const status = c.req.query("status") ?? "open";
+    if (status !== "all" && !isActionItemStatus(status)) {
+      return c.json({ error: "status must be open, done, or all" }, 400);
+    }
+    const limitRaw = Number(c.req.query("limit") ?? LIST_DEFAULT_LIMIT);
+    const limit = Number.isFinite(limitRaw) && limitRaw > 0 ? Math.floor(limitRaw) : 0;
+    if (limit === 0) {
+      return c.json({ error: "Invalid limit" }, 400);
+    }
+    const workingDir = c.req.query("working_dir");
+
+    const db = await getDb();
+    let query = db
+      .selectFrom("action_items")
+      .select(["id", "session_id", "working_dir", "text", "status", "created_at", "completed_at"])
+      .orderBy("created_at", "desc")
+      .limit(limit);
+    if (status !== "all") {
+      query = query.where("status", "=", status);
+    }
+    if (workingDir) {
+      query = query.where("working_dir", "=", await normalizeWorkingDir(workingDir));
+    }
+    return c.json({ items: await query.execute() });
+  });
+
+  app.post("/action-items/:id/status", async (c) => {
+    const id = Number(c.req.param("id"));
+    if (!Number.isInteger(id) || id <= 0) {
+      return c.json({ error: "Invalid action item id" }, 400);
+    }
+    const payload = await parseJson<{ status?: unknown }>(c.req.raw);
+    const status = payload?.status;
+    if (!isActionItemStatus(status)) {
+      return c.json({ error: "status must be open or done" }, 400);
+    }
+
+    const db = await getDb();
+    const result = await db
+      .updateTable("action_items")
+      .set({ status, completed_at: status === "done" ? new Date() : null })
+      .where("id", "=", id)
+      .executeTakeFirst();
+    if (Number(result.numUpdatedRows ?? 0) === 0) {
+      return c.json({ error: "Action item not found" }, 404);
+    }
+    return c.json({ id, status });
+  });
+}
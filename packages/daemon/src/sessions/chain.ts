@@ -0,0 +1,75 @@
+import { getDb } from "../db.js";
+import { normalizeWorkingDir } from "../utils/working-dir.js";
+
+// Guards against cycles or runaway chains from bad continued_from data.
+const MAX_CHAIN_DEPTH = 50;
+
+export type ChainSession = {
+  id: number;
+  name: string | null;
+  working_dir: string;
+  personality: string | null;
+  start_time: number;
+  end_time: number | null;
+  summary: string | null;
+  continued_from: number | null;
+};
+
+/**
+ * Follow continued_from back to the root session. Returns the chain ordered
+ * oldest first, ending with the requested session; empty if it doesn't exist.
+ */
+export async function getSessionChain(sessionId: number): Promise<ChainSession[]> {
+  const db = await getDb();
+  const chain: ChainSession[] = [];
+  const seen = new Set<number>();
+  let currentId: number | null = sessionId;
+
+  while (currentId !== null && !seen.has(currentId) && chain.length < MAX_CHAIN_DEPTH) {
+    seen.add(currentId);
+    const session: ChainSession | undefined = await db
+      .selectFrom("sessions")
+      .select([
+        "id",
+        "name",
+        "working_dir",
+        "personality",
+        "start_time",
+        "end_time",
+        "summary",
+        "continued_from",
+      ])
+      .where("id", "=", currentId)
+      .executeTakeFirst();
+    if (!session) {
+      break;
+    }
+    chain.push(session);
+    currentId = session.continued_from;
+  }
+
+  return chain.reverse();
+}
+
+/**
+ * Find the session a `-c` continuation picks up from: the most recent other
+ * session in the same working directory.
+ */
+export async function findContinuationParent(
+  workingDir: string,
+  sessionId: number,
+): Promise<number | null> {
+  if (!workingDir) {
+    return null;
+  }
+  const db = await getDb();
+  const row = await db
+    .selectFrom("sessions")
+    .select(["id"])
+    .where("working_dir", "=", await normalizeWorkingDir(workingDir))
+    .where("id", "!=", sessionId)
+    .orderBy("start_time", "desc")
+    .limit(1)
+    .executeTakeFirst();
+  return row?.id ?? null;
+}
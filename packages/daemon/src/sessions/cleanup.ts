@@ -0,0 +1,364 @@
+import { sql, type Transaction } from "kysely";
+
+import { countSessionEpisodes, deleteSessionEpisodes } from "@dere/graph";
+import { loadConfig } from "@dere/shared-config";
+
+import { getDb } from "../db.js";
+import type { Database } from "../db-types.js";
+import { log } from "../logger.js";
+
+const CLEANUP_INTERVAL_MS = 24 * 60 * 60 * 1000;
+// Each batch is its own transaction so a large cleanup never holds locks on
+// every old session at once.
+const CLEANUP_BATCH_SIZE = 200;
+
+let cleanupTimer: ReturnType<typeof setInterval> | null = null;
+let cleanupRunning = false;
+
+export type SessionCleanupResult = {
+  dry_run: boolean;
+  older_than_days: number;
+  sessions: number;
+  conversations: number;
+  blocks: number;
+  entities: number;
+  other_rows: number;
+  /** Estimated size of the deleted conversation text, before vacuum reclaims it */
+  freed_bytes: number;
+};
+
+class DryRunRollback extends Error {}
+
+/** Read `[database].session_retention_days`; 0 (the default) keeps everything. */
+export async function loadSessionRetentionDays(): Promise<number> {
+  try {
+    const config = await loadConfig();
+    const dbConfig = (config.database ?? {}) as Record<string, unknown>;
+    const value = Number(dbConfig.session_retention_days);
+    return Number.isFinite(value) && value > 0 ? value : 0;
+  } catch {
+    return 0;
+  }
+}
+
+type DeletionCounts = Omit<SessionCleanupResult, "dry_run" | "older_than_days">;
+
+/** Add `deleted` to the running count for `table` and pass it through. */
+function tally(tables: Record<string, number>, table: string, deleted: number): number {
+  tables[table] = (tables[table] ?? 0) + deleted;
+  return deleted;
+}
+
+async function deleteSessionBatch(
+  trx: Transaction<Database>,
+  ids: number[],
+  result: DeletionCounts,
+  tables: Record<string, number> = {},
+): Promise<void> {
+  const conversationIds = trx
+    .selectFrom("conversations")
+    .select("id")
+    .where("session_id", "in", ids);
+
+  const conversationSize = await trx
+    .selectFrom("conversations as c")
+    .select(sql<number>`coalesce(sum(pg_column_size(c.*)), 0)::bigint`.as("bytes"))
+    .where("c.session_id", "in", ids)
+    .executeTakeFirst();
+  const blockSize = await trx
+    .selectFrom("conversation_blocks as cb")
+    .select([
+      sql<number>`count(*)::int`.as("count"),
+      sql<number>`coalesce(sum(pg_column_size(cb.*)), 0)::bigint`.as("bytes"),
+    ])
+    .where("cb.conversation_id", "in", conversationIds)
+    .executeTakeFirst();
+  result.blocks += Number(blockSize?.count ?? 0);
+  result.freed_bytes += Number(conversationSize?.bytes ?? 0) + Number(blockSize?.bytes ?? 0);
+
+  // Rows that only make sense alongside the session go with it.
+  const entities = await trx.deleteFrom("entities").where("session_id", "in", ids).execute();
+  result.entities += tally(tables, "entities", Number(entities[0]?.numDeletedRows ?? 0));
+
+  const owned = [
+    ["context_cache", trx.deleteFrom("context_cache").where("session_id", "in", ids)],
+    ["emotion_states", trx.deleteFrom("emotion_states").where("session_id", "in", ids)],
+    ["stimulus_history", trx.deleteFrom("stimulus_history").where("session_id", "in", ids)],
+    ["surfaced_findings", trx.deleteFrom("surfaced_findings").where("session_id", "in", ids)],
+    ["session_costs", trx.deleteFrom("session_costs").where("session_id", "in", ids)],
+    ["session_tags", trx.deleteFrom("session_tags").where("session_id", "in", ids)],
+    [
+      "session_summary_variants",
+      trx.deleteFrom("session_summary_variants").where("session_id", "in", ids),
+    ],
+    ["action_items", trx.deleteFrom("action_items").where("session_id", "in", ids)],
+    ["tool_events", trx.deleteFrom("tool_events").where("session_id", "in", ids)],
+    [
+      "core_memory_versions",
+      trx
+        .deleteFrom("core_memory_versions")
+        .where(
+          "block_id",
+          "in",
+          trx.selectFrom("core_memory_blocks").select("id").where("session_id", "in", ids),
+        ),
+    ],
+    ["core_memory_blocks", trx.deleteFrom("core_memory_blocks").where("session_id", "in", ids)],
+  ] as const;
+  for (const [table, query] of owned) {
+    const deleted = await query.execute();
+    result.other_rows += tally(tables, table, Number(deleted[0]?.numDeletedRows ?? 0));
+  }
+
+  // Shared records outlive the session; just drop the reference.
+  await trx
+    .updateTable("sessions")
+    .set({ continued_from: null })
+    .where("continued_from", "in", ids)
+    .execute();
+  await trx
+    .updateTable("swarms")
+    .set({ parent_session_id: null })
+    .where("parent_session_id", "in", ids)
+    .execute();
+  await trx
+    .updateTable("swarm_agents")
+    .set({ session_id: null })
+    .where("session_id", "in", ids)
+    .execute();
+  await trx
+    .updateTable("project_tasks")
+    .set({ claimed_by_session_id: null })
+    .where("claimed_by_session_id", "in", ids)
+    .execute();
+  await trx
+    .updateTable("project_tasks")
+    .set({ created_by_session_id: null })
+    .where("created_by_session_id", "in", ids)
+    .execute();
+
+  // conversation_blocks cascade from conversations
+  const conversations = await trx
+    .deleteFrom("conversations")
+    .where("id", "in", conversationIds)
+    .execute();
+  result.conversations += tally(
+    tables,
+    "conversations",
+    Number(conversations[0]?.numDeletedRows ?? 0),
+  );
+  tally(tables, "conversation_blocks", Number(blockSize?.count ?? 0));
+
+  const sessions = await trx.deleteFrom("sessions").where("id", "in", ids).execute();
+  result.sessions += tally(tables, "sessions", Number(sessions[0]?.numDeletedRows ?? 0));
+}
+
+export type SessionPurgeResult = DeletionCounts & {
+  session_id: number;
+  dry_run: boolean;
+  /** Rows deleted per table */
+  tables: Record<string, number>;
+  /** Set when the knowledge graph couldn't be cleared (or, in a dry run, counted) */
+  graph_error?: string;
+};
+
+/**
+ * Remove (or with `dryRun`, count) the knowledge graph episodes ingested from
+ * a session, and entities only they mentioned. Episodes ingested before they
+ * carried a session id can't be matched and are left in place.
+ */
+async function purgeSessionEpisodes(
+  groupIds: string[],
+  sessionId: number,
+  result: SessionPurgeResult,
+): Promise<void> {
+  try {
+    for (const groupId of groupIds) {
+      if (result.dry_run) {
+        tally(result.tables, "graph_episodes", await countSessionEpisodes(groupId, sessionId));
+        continue;
+      }
+      const deleted = await deleteSessionEpisodes(groupId, sessionId);
+      tally(result.tables, "graph_episodes", deleted.episodes);
+      tally(result.tables, "graph_entities", deleted.entities);
+    }
+  } catch (error) {
+    result.graph_error = String(error);
+    log.session.warn("Failed to purge session graph episodes", {
+      sessionId,
+      error: result.graph_error,
+    });
+  }
+}
+
+/**
+ * Delete one session and everything recorded under its id, pinned or not, in
+ * a single transaction. Beyond what cleanup removes, this also drops its
+ * queued tasks and any cross-session summary built from it, since those hold
+ * its text, and its episodes in the knowledge graph once the rows are gone.
+ * Returns null when the session doesn't exist.
+ */
+export async function purgeSession(
+  sessionId: number,
+  options: { dryRun?: boolean } = {},
+): Promise<SessionPurgeResult | null> {
+  const db = await getDb();
+  const session = await db
+    .selectFrom("sessions")
+    .select(["id"])
+    .where("id", "=", sessionId)
+    .executeTakeFirst();
+  if (!session) {
+    return null;
+  }
+  // Episodes are stored under the user's group; read it before the rows go.
+  const users = await db
+    .selectFrom("conversations")
+    .select("user_id")
+    .distinct()
+    .where("session_id", "=", sessionId)
+    .execute();
+  const groupIds = [...new Set(users.map((row) => row.user_id ?? "default"))];
+
+  const result: SessionPurgeResult = {
+    session_id: sessionId,
+    dry_run: options.dryRun === true,
+    sessions: 0,
+    conversations: 0,
+    blocks: 0,
+    entities: 0,
+    other_rows: 0,
+    freed_bytes: 0,
+    tables: {},
+  };
+  try {
+    await db.transaction().execute(async (trx) => {
+      const queued = await trx
+        .deleteFrom("task_queue")
+        .where("session_id", "=", sessionId)
+        .execute();
+      result.other_rows += tally(
+        result.tables,
+        "task_queue",
+        Number(queued[0]?.numDeletedRows ?? 0),
+      );
+      const summaries = await trx
+        .deleteFrom("summary_context")
+        .where(sql<boolean>`${sessionId} = any(session_ids)`)
+        .execute();
+      result.other_rows += tally(
+        result.tables,
+        "summary_context",
+        Number(summaries[0]?.numDeletedRows ?? 0),
+      );
+      await deleteSessionBatch(trx, [sessionId], result, result.tables);
+      if (result.dry_run) {
+        throw new DryRunRollback();
+      }
+    });
+  } catch (error) {
+    if (!(error instanceof DryRunRollback)) {
+      throw error;
+    }
+  }
+  await purgeSessionEpisodes(groupIds, sessionId, result);
+  return result;
+}
+
+/**
+ * Delete unpinned sessions with no activity in the last `olderThanDays` days,
+ * along with their conversations and session-scoped rows. With `dryRun` the same
+ * deletes run in transactions that are rolled back, so the counts are exact.
+ */
+export async function cleanupOldSessions(options: {
+  olderThanDays: number;
+  dryRun?: boolean;
+}): Promise<SessionCleanupResult> {
+  const { olderThanDays, dryRun = false } = options;
+  const result: SessionCleanupResult = {
+    dry_run: dryRun,
+    older_than_days: olderThanDays,
+    sessions: 0,
+    conversations: 0,
+    blocks: 0,
+    entities: 0,
+    other_rows: 0,
+    freed_bytes: 0,
+  };
+
+  const cutoff = new Date(Date.now() - olderThanDays * 24 * 60 * 60 * 1000);
+  const db = await getDb();
+  let lastId = 0;
+  while (true) {
+    const rows = await db
+      .selectFrom("sessions")
+      .select(["id"])
+      .where("last_activity", "<", cutoff)
+      .where("pinned", "=", false)
+      .where("id", ">", lastId)
+      .orderBy("id")
+      .limit(CLEANUP_BATCH_SIZE)
+      .execute();
+    if (rows.length === 0) {
+      break;
+    }
+    const ids = rows.map((row) => row.id);
+    lastId = ids[ids.length - 1] ?? lastId;
+    try {
+      await db.transaction().execute(async (trx) => {
+        await deleteSessionBatch(trx, ids, result);
+        if (dryRun) {
+          throw new DryRunRollback();
+        }
+      });
+    } catch (error) {
+      if (!(error instanceof DryRunRollback)) {
+        throw error;
+      }
+    }
+  }
+
+  return result;
+}
+
+async function runCleanupCycle(): Promise<void> {
+  if (cleanupRunning) {
+    return;
+  }
+  cleanupRunning = true;
+  try {
+    const retentionDays = await loadSessionRetentionDays();
+    if (retentionDays <= 0) {
+      return;
+    }
+    const result = await cleanupOldSessions({ olderThanDays: retentionDays });
+    if (result.sessions > 0) {
+      log.session.info("Removed old sessions", { ...result });
+    }
+  } catch (error) {
+    log.session.warn("Session cleanup failed", { error: String(error) });
+  } finally {
+    cleanupRunning = false;
+  }
+}
+
+/** Daily cleanup per [database].session_retention_days; a no-op while it's unset. */
+export function startSessionCleanupLoop(): void {
+  if (cleanupTimer) {
+    return;
+  }
+  cleanupTimer = setInterval(() => {
+    void runCleanupCycle();
+  }, CLEANUP_INTERVAL_MS);
+  void runCleanupCycle();
+  log.session.info("Session cleanup loop started", { intervalMs: CLEANUP_INTERVAL_MS });
+}
+
+export function stopSessionCleanupLoop(): void {
+  if (!cleanupTimer) {
+    return;
+  }
+  clearInterval(cleanupTimer);
+  cleanupTimer = null;
+  log.session.info("Session cleanup loop stopped");
+}
@@ -1,12 +1,16 @@
+import { createHash } from "node:crypto";
+
 import type { Hono } from "hono";
 
-import { loadConfig } from "@dere/shared-config";
-import { addEpisode } from "@dere/graph";
+import { captureMinPromptChars, isMeaningfulPrompt, loadConfig } from "@dere/shared-config";
+import { addEpisode, type ContextHint } from "@dere/graph";
 
 import { getDb } from "../db.js";
 import { bufferEmotionStimulus } from "../emotions/runtime.js";
 import { log } from "../logger.js";
 import { insertConversation } from "../utils/conversations.js";
+import { isExcludedWorkingDir, normalizeWorkingDir } from "../utils/working-dir.js";
+import { addSessionTags, parseTags } from "./tags.js";
 
 function nowDate(): Date {
   return new Date();
@@ -16,6 +20,90 @@ function nowSeconds(): number {
   return Math.floor(Date.now() / 1000);
 }
 
+// Background task types a session can opt out of via the capture payload.
+const OPTIONAL_TASKS = new Set(["entities", "summary", "embeddings"]);
+
+// Hooks can fire more than once for the same prompt; captures of an identical
+// prompt within this window are treated as duplicates.
+const DEDUP_WINDOW_SECONDS = 60;
+
+function contentHash(messageType: string, prompt: string, timestamp: number): string {
+  const bucket = Math.floor(timestamp / DEDUP_WINDOW_SECONDS);
+  return createHash("sha256").update(`${messageType}\0${bucket}\0${prompt}`).digest("hex");
+}
+
+// `dere --mode` values whose conversations are about the user's wellbeing
+// rather than code.
+const WELLNESS_MODES = new Set(["wellness", "therapy", "mental-health"]);
+
+const CONTEXT_HINTS = new Set<ContextHint>(["coding", "general", "wellness"]);
+
+/** A stored context hint, or "general" for anything unrecognized. */
+export function parseContextHint(value: unknown): ContextHint {
+  return typeof value === "string" && CONTEXT_HINTS.has(value as ContextHint)
+    ? (value as ContextHint)
+    : "general";
+}
+
+// An explicit hint (set by user-defined modes) wins over the built-in mode list.
+function contextHintForMode(mode: string | null, hint: unknown): ContextHint {
+  if (typeof hint === "string" && CONTEXT_HINTS.has(hint as ContextHint)) {
+    return hint as ContextHint;
+  }
+  if (mode && WELLNESS_MODES.has(mode)) {
+    return "wellness";
+  }
+  return mode === "code" ? "coding" : "general";
+}
+
+// Claude Code passes slash commands through as the raw "/name args" prompt.
+const SLASH_COMMAND_RE = /^\/[\w:-]+(?:\s|$)/;
+
+function isSlashCommand(prompt: string): boolean {
+  return SLASH_COMMAND_RE.test(prompt.trim());
+}
+
+// Whitespace, stray keystrokes, and bare punctuation would only queue
+// embedding and entity work for nothing.
+async function isTrivialPrompt(prompt: string): Promise<boolean> {
+  try {
+    return !isMeaningfulPrompt(prompt, captureMinPromptChars(await loadConfig()));
+  } catch {
+    return !isMeaningfulPrompt(prompt, captureMinPromptChars({}));
+  }
+}
+
+function isUniqueViolation(error: unknown): boolean {
+  return (error as { code?: unknown })?.code === "23505";
+}
+
+/** Run a user message through graph entity extraction as one episode. */
+export async function ingestUserMessage(args: {
+  prompt: string;
+  referenceTime: Date;
+  medium: string | null;
+  userId: string | null;
+  personality: string | null;
+  contextHint: ContextHint;
+  sessionId: number;
+}) {
+  const config = await loadConfig();
+  const canonicalUserName =
+    typeof config.user?.name === "string" && config.user.name ? config.user.name : "User";
+  return addEpisode({
+    episodeBody: args.prompt,
+    sourceDescription: `${args.medium ?? "cli"} conversation`,
+    referenceTime: args.referenceTime,
+    source: "message",
+    groupId: args.userId ?? "default",
+    speakerId: args.userId ?? null,
+    speakerName: canonicalUserName,
+    personality: args.personality,
+    contextHint: args.contextHint,
+    sessionId: args.sessionId,
+  });
+}
+
 async function parseJson<T>(req: Request): Promise<T | null> {
   try {
     return (await req.json()) as T;
@@ -33,27 +121,54 @@ export function registerConversationRoutes(app: Hono): void {
 
     const sessionId = typeof payload.session_id === "number" ? payload.session_id : null;
     const personality = typeof payload.personality === "string" ? payload.personality : null;
-    const projectPath = typeof payload.project_path === "string" ? payload.project_path : "";
+    const projectPath =
+      typeof payload.project_path === "string"
+        ? await normalizeWorkingDir(payload.project_path)
+        : "";
     const prompt = typeof payload.prompt === "string" ? payload.prompt : "";
-    const messageType = typeof payload.message_type === "string" ? payload.message_type : "user";
+    const rawMessageType =
+      typeof payload.message_type === "string" ? payload.message_type : "user";
+    // Commands are kept for the transcript but skip embedding, entity
+    // extraction, and emotion processing.
+    const isCommand =
+      rawMessageType === "user" && (Boolean(payload.is_command) || isSlashCommand(prompt));
+    const messageType = isCommand ? "command" : rawMessageType;
     const medium = typeof payload.medium === "string" ? payload.medium : null;
     const userId = typeof payload.user_id === "string" ? payload.user_id : null;
-    const isCommand = Boolean(payload.is_command);
     const speakerName = typeof payload.speaker_name === "string" ? payload.speaker_name : null;
+    const mode = typeof payload.mode === "string" ? payload.mode : null;
+    const contextHint = contextHintForMode(mode, payload.context_hint);
+    const disabledTasks = Array.isArray(payload.disabled_tasks)
+      ? payload.disabled_tasks.filter(
+          (task): task is string => typeof task === "string" && OPTIONAL_TASKS.has(task),
+        )
+      : [];
 
     if (!sessionId || !personality || !projectPath) {
       return c.json({ error: "session_id, personality, and project_path are required" }, 400);
     }
+    if (await isExcludedWorkingDir(projectPath)) {
+      return c.json({ status: "excluded", background: [] });
+    }
 
     const db = await getDb();
     const now = nowDate();
 
     const existing = await db
       .selectFrom("sessions")
-      .select(["id", "working_dir", "start_time"])
+      .select(["id", "working_dir", "start_time", "disabled_tasks", "context_hint"])
       .where("id", "=", sessionId)
       .executeTakeFirst();
 
+    if (existing && projectPath && existing.working_dir && existing.working_dir !== projectPath) {
+      // Two launches picked the same id; their conversations will be merged.
+      log.session.warn("Session id collision: capture from a different working directory", {
+        sessionId,
+        existing: existing.working_dir,
+        incoming: projectPath,
+      });
+    }
+
     const sessionStart = existing?.start_time ?? nowSeconds();
     if (!existing) {
       await db
@@ -80,39 +195,106 @@ export function registerConversationRoutes(app: Hono): void {
           summary_updated_at: null,
           name: null,
           end_time: null,
+          disabled_tasks: disabledTasks,
+          context_hint: contextHint,
         })
         .execute();
+    } else {
+      if (disabledTasks.some((task) => !existing.disabled_tasks.includes(task))) {
+        const merged = Array.from(new Set([...existing.disabled_tasks, ...disabledTasks]));
+        await db
+          .updateTable("sessions")
+          .set({ disabled_tasks: merged })
+          .where("id", "=", sessionId)
+          .execute();
+      }
+      // Sessions the CLI reserved at launch have no hint until their first capture
+      if (existing.context_hint === null) {
+        await db
+          .updateTable("sessions")
+          .set({ context_hint: contextHint })
+          .where("id", "=", sessionId)
+          .execute();
+      }
+    }
+    // Tags passed at launch with `dere --tag=...`
+    const launchTags = parseTags(payload.tags);
+    if (launchTags && launchTags.length > 0) {
+      await addSessionTags(sessionId, launchTags);
     }
+    const isDisabled = (task: string) =>
+      disabledTasks.includes(task) || Boolean(existing?.disabled_tasks.includes(task));
+    const entitiesDisabled = isDisabled("entities");
 
-    const conversationId = await insertConversation({
-      sessionId,
-      messageType,
-      prompt,
-      personality,
-      userId,
-      medium,
-      updateLastActivity: false,
-    });
+    // The session is still recorded above; only the message itself is dropped.
+    if (!isCommand && (await isTrivialPrompt(prompt))) {
+      return c.json({ status: "skipped", background: [] });
+    }
+
+    if (prompt.trim()) {
+      const duplicate = await db
+        .selectFrom("conversations")
+        .select(["id"])
+        .where("session_id", "=", sessionId)
+        .where("message_type", "=", messageType)
+        .where("prompt", "=", prompt)
+        .where("timestamp", ">=", nowSeconds() - DEDUP_WINDOW_SECONDS)
+        .executeTakeFirst();
+      if (duplicate) {
+        return c.json({ status: "duplicate", conversation_id: duplicate.id });
+      }
+    }
+
+    let conversationId: number;
+    try {
+      conversationId = await insertConversation({
+        sessionId,
+        messageType,
+        prompt,
+        personality,
+        userId,
+        medium,
+        contentHash: prompt.trim() ? contentHash(messageType, prompt, nowSeconds()) : null,
+        updateLastActivity: false,
+      });
+    } catch (error) {
+      // A concurrent capture of the same prompt won the insert
+      if (isUniqueViolation(error)) {
+        return c.json({ status: "duplicate" });
+      }
+      throw error;
+    }
+
+    // Background work this capture sets off, reported so `dere capture` can
+    // show what to watch for.
+    const background: string[] = [];
+    if (messageType === "user" && prompt.trim() && !entitiesDisabled) {
+      background.push("entities");
+    }
+    if (!isCommand) {
+      background.push("emotion");
+      if (prompt.trim() && !isDisabled("embeddings")) {
+        background.push("embeddings");
+      }
+    }
 
     const workingDir = projectPath || existing?.working_dir || "/workspace";
     const sessionDurationMinutes = Math.max(0, Math.floor((nowSeconds() - sessionStart) / 60));
 
     void (async () => {
       let kgNodes: Array<Record<string, unknown>> | null = null;
-      if (messageType === "user" && prompt.trim()) {
+      // Assistant turns are stored, embedded by the backfill loop, and included
+      // in summaries, but only the user's own words feed the knowledge graph.
+      if (messageType === "user" && prompt.trim() && !entitiesDisabled) {
         try {
-          const config = await loadConfig();
-          const canonicalUserName =
-            typeof config.user?.name === "string" && config.user.name ? config.user.name : "User";
-          const episodeResult = await addEpisode({
-            episodeBody: prompt,
-            sourceDescription: `${medium ?? "cli"} conversation`,
+          const episodeResult = await ingestUserMessage({
+            prompt,
             referenceTime: now,
-            source: "message",
-            groupId: userId ?? "default",
-            speakerId: userId ?? null,
-            speakerName: canonicalUserName,
+            medium,
+            userId,
             personality,
+            contextHint,
+            sessionId,
           });
           kgNodes = episodeResult.nodes.map((node) => ({
             uuid: node.uuid,
@@ -125,6 +307,10 @@ export function registerConversationRoutes(app: Hono): void {
         }
       }
 
+      if (isCommand) {
+        return;
+      }
+
       void bufferEmotionStimulus({
         sessionId,
         prompt,
@@ -139,7 +325,7 @@ export function registerConversationRoutes(app: Hono): void {
 
     })();
 
-    return c.json({ status: "stored" });
+    return c.json({ status: "stored", conversation_id: conversationId, background });
   });
 
   app.get("/conversations/last_dm/:user_id", async (c) => {
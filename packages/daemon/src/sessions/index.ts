@@ -1,13 +1,37 @@
-import type { Hono } from "hono";
+import type { Context, Hono } from "hono";
+
+import { captureExcludePaths, isCaptureExcluded, loadConfig } from "@dere/shared-config";
+import { renderTag, renderTextTag } from "@dere/shared-llm";
 
 import { getDb } from "../db.js";
 import { bufferEmotionStimulus, flushGlobalEmotionBatch } from "../emotions/runtime.js";
+import { DaemonError, ErrorCode, errorResponse } from "../errors.js";
 import { log } from "../logger.js";
-import { generateShortSummary } from "../utils/summary.js";
+import { embedSessionSummary } from "../memory/embeddings.js";
+import { getSessionChain } from "./chain.js";
+import { cleanupOldSessions, purgeSession } from "./cleanup.js";
+import {
+  REPROCESS_TYPES,
+  findReprocessSessions,
+  startReprocess,
+  type ReprocessType,
+} from "./reprocess.js";
+import {
+  SUMMARY_STYLES,
+  isSummaryStyle,
+  regenerateSummary,
+  resummarizeSession,
+} from "./summary.js";
+import { addSessionTags, getSessionTags, parseTags, sessionIdsTagged } from "./tags.js";
+import { buildRecentTranscript, generateShortSummary } from "../utils/summary.js";
 import { insertConversation } from "../utils/conversations.js";
+import { normalizeWorkingDir } from "../utils/working-dir.js";
 
 const SUMMARY_WINDOW_SECONDS = 1800;
 const SUMMARY_LIMIT = 50;
+const RESUME_CONTEXT_LIMIT = 10;
+const RESUME_MESSAGE_MAX_CHARS = 500;
+const LIST_DEFAULT_LIMIT = 20;
 
 function nowSeconds(): number {
   return Math.floor(Date.now() / 1000);
@@ -25,6 +49,25 @@ async function parseJson<T>(req: Request): Promise<T | null> {
   }
 }
 
+async function setSessionPinned(c: Context, pinned: boolean): Promise<Response> {
+  const sessionId = Number(c.req.param("session_id"));
+  if (!Number.isFinite(sessionId)) {
+    return c.json({ error: "Invalid session_id" }, 400);
+  }
+
+  const db = await getDb();
+  const result = await db
+    .updateTable("sessions")
+    .set({ pinned })
+    .where("id", "=", sessionId)
+    .executeTakeFirst();
+  if (Number(result.numUpdatedRows ?? 0) === 0) {
+    return errorResponse(c, new DaemonError(ErrorCode.SESSION_NOT_FOUND, "Session not found"));
+  }
+
+  return c.json({ session_id: sessionId, pinned });
+}
+
 export function registerSessionRoutes(app: Hono): void {
   app.get("/sessions/last_interaction", async (c) => {
     const userId = c.req.query("user_id");
@@ -46,8 +89,68 @@ export function registerSessionRoutes(app: Hono): void {
     return c.json({ last_interaction_time: timestamp });
   });
 
+  app.get("/sessions/resume_context", async (c) => {
+    const resumeId = (c.req.query("resume_id") ?? "").trim();
+    if (!resumeId) {
+      return c.json({ error: "resume_id is required" }, 400);
+    }
+
+    const limitParam = Number(c.req.query("limit"));
+    const limit = Number.isFinite(limitParam) && limitParam > 0 ? limitParam : RESUME_CONTEXT_LIMIT;
+
+    // Resume IDs are either dere session ids or Claude session UUIDs recorded
+    // via /sessions/:session_id/claude_session.
+    const db = await getDb();
+    const numericId = /^\d+$/.test(resumeId) ? Number(resumeId) : null;
+    let query = db.selectFrom("sessions").select(["id", "personality", "summary"]);
+    query =
+      numericId !== null
+        ? query.where((eb) =>
+            eb.or([eb("id", "=", numericId), eb("claude_session_id", "=", resumeId)]),
+          )
+        : query.where("claude_session_id", "=", resumeId);
+    const session = await query.orderBy("start_time", "desc").limit(1).executeTakeFirst();
+
+    if (!session) {
+      return c.json({ found: false, session_id: null, context: "" });
+    }
+
+    const rows = await db
+      .selectFrom("conversations")
+      .select(["prompt", "message_type"])
+      .where("session_id", "=", session.id)
+      .where("prompt", "<>", "")
+      .orderBy("timestamp", "desc")
+      .limit(limit)
+      .execute();
+
+    const parts: string[] = [];
+    if (session.summary) {
+      parts.push(renderTextTag("summary", session.summary, { indent: 2 }));
+    }
+    for (const row of rows.slice().reverse()) {
+      let text = row.prompt.trim();
+      if (text.length > RESUME_MESSAGE_MAX_CHARS) {
+        text = `${text.slice(0, RESUME_MESSAGE_MAX_CHARS).trim()}...`;
+      }
+      parts.push(
+        renderTextTag("message", text, { indent: 2, attrs: { role: row.message_type } }),
+      );
+    }
+
+    const config = await loadConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    const framing = contextConfig.personality_framing !== false;
+    const context = renderTag("previous_session", parts.join("\n"), {
+      attrs: framing ? { id: session.id, personality: session.personality } : { id: session.id },
+    });
+
+    return c.json({ found: true, session_id: session.id, context });
+  });
+
   app.post("/sessions/create", async (c) => {
     const payload = await parseJson<{
+      id?: number;
       working_dir?: string;
       personality?: string | null;
       medium?: string;
@@ -58,37 +161,168 @@ export function registerSessionRoutes(app: Hono): void {
     }
 
     const db = await getDb();
+    // The CLI picks its own random id; claim it, or 409 so it retries.
+    const requestedId =
+      typeof payload.id === "number" && Number.isInteger(payload.id) ? payload.id : null;
+    if (requestedId !== null) {
+      const taken = await db
+        .selectFrom("sessions")
+        .select(["id"])
+        .where("id", "=", requestedId)
+        .executeTakeFirst();
+      if (taken) {
+        return c.json({ error: "Session id already in use" }, 409);
+      }
+    }
+
     const now = nowDate();
-    const inserted = await db
-      .insertInto("sessions")
-      .values({
-        working_dir: payload.working_dir,
-        start_time: nowSeconds(),
-        personality: payload.personality ?? null,
-        medium: payload.medium ?? "cli",
-        last_activity: now,
-        sandbox_mode: false,
-        sandbox_mount_type: "none",
-        is_locked: false,
-        sandbox_settings: null,
-        continued_from: null,
-        project_type: null,
-        claude_session_id: null,
-        user_id: null,
-        thinking_budget: null,
-        mission_id: null,
-        created_at: now,
-        summary: null,
-        summary_updated_at: null,
-        name: null,
-        end_time: null,
-      })
-      .returning(["id"])
-      .executeTakeFirstOrThrow();
+    let inserted: { id: number };
+    try {
+      inserted = await db
+        .insertInto("sessions")
+        .values({
+          ...(requestedId !== null ? { id: requestedId } : {}),
+          working_dir: await normalizeWorkingDir(payload.working_dir),
+          start_time: nowSeconds(),
+          personality: payload.personality ?? null,
+          medium: payload.medium ?? "cli",
+          last_activity: now,
+          sandbox_mode: false,
+          sandbox_mount_type: "none",
+          is_locked: false,
+          sandbox_settings: null,
+          continued_from: null,
+          project_type: null,
+          claude_session_id: null,
+          user_id: null,
+          thinking_budget: null,
+          mission_id: null,
+          created_at: now,
+          summary: null,
+          summary_updated_at: null,
+          name: null,
+          end_time: null,
+        })
+        .returning(["id"])
+        .executeTakeFirstOrThrow();
+    } catch (error) {
+      // Lost a race with another launch claiming the same id
+      if (requestedId !== null && (error as { code?: unknown })?.code === "23505") {
+        return c.json({ error: "Session id already in use" }, 409);
+      }
+      throw error;
+    }
 
     return c.json({ session_id: inserted.id });
   });
 
+  // Load a finished session from elsewhere (e.g. a Claude Code transcript)
+  // with its original timestamps. The backfill loop embeds the turns; the
+  // summary is generated in the background.
+  app.post("/sessions/import", async (c) => {
+    const payload = await parseJson<{
+      working_dir?: string;
+      personality?: string | null;
+      claude_session_id?: string | null;
+      turns?: Array<{ message_type?: unknown; prompt?: unknown; timestamp?: unknown }>;
+    }>(c.req.raw);
+    if (!payload?.working_dir) {
+      return c.json({ error: "working_dir is required" }, 400);
+    }
+    const turns = (Array.isArray(payload.turns) ? payload.turns : [])
+      .flatMap((turn) =>
+        (turn.message_type === "user" || turn.message_type === "assistant") &&
+        typeof turn.prompt === "string" &&
+        turn.prompt.trim() &&
+        typeof turn.timestamp === "number" &&
+        Number.isFinite(turn.timestamp)
+          ? [
+              {
+                messageType: turn.message_type,
+                prompt: turn.prompt,
+                timestamp: Math.floor(turn.timestamp),
+              },
+            ]
+          : [],
+      )
+      .sort((a, b) => a.timestamp - b.timestamp);
+    if (turns.length === 0) {
+      return c.json({ error: "No user or assistant turns to import" }, 400);
+    }
+
+    const workingDir = await normalizeWorkingDir(payload.working_dir);
+    if (isCaptureExcluded(workingDir, captureExcludePaths(await loadConfig()))) {
+      return c.json({ error: "working_dir is excluded from capture" }, 403);
+    }
+
+    const db = await getDb();
+    const claudeSessionId =
+      typeof payload.claude_session_id === "string" && payload.claude_session_id.trim()
+        ? payload.claude_session_id.trim()
+        : null;
+    if (claudeSessionId) {
+      const existing = await db
+        .selectFrom("sessions")
+        .select(["id"])
+        .where("claude_session_id", "=", claudeSessionId)
+        .executeTakeFirst();
+      if (existing) {
+        return c.json({ error: "Transcript already imported", session_id: existing.id }, 409);
+      }
+    }
+
+    const personality = payload.personality ?? null;
+    const startTime = turns[0]!.timestamp;
+    const endTime = turns[turns.length - 1]!.timestamp;
+    const now = nowDate();
+    const sessionId = await db.transaction().execute(async (trx) => {
+      const inserted = await trx
+        .insertInto("sessions")
+        .values({
+          working_dir: workingDir,
+          start_time: startTime,
+          personality,
+          medium: "cli",
+          last_activity: new Date(endTime * 1000),
+          sandbox_mode: false,
+          sandbox_mount_type: "none",
+          is_locked: false,
+          sandbox_settings: null,
+          continued_from: null,
+          project_type: null,
+          claude_session_id: claudeSessionId,
+          user_id: null,
+          thinking_budget: null,
+          mission_id: null,
+          created_at: now,
+          summary: null,
+          summary_updated_at: null,
+          name: null,
+          end_time: endTime,
+        })
+        .returning(["id"])
+        .executeTakeFirstOrThrow();
+      for (const turn of turns) {
+        await insertConversation({
+          sessionId: inserted.id,
+          messageType: turn.messageType,
+          prompt: turn.prompt,
+          personality,
+          medium: "cli",
+          timestamp: turn.timestamp,
+          updateLastActivity: false,
+          trx,
+        });
+      }
+      return inserted.id;
+    });
+    await addSessionTags(sessionId, ["import"]);
+    void resummarizeSession(sessionId);
+
+    log.session.info("Imported session", { sessionId, turns: turns.length });
+    return c.json({ session_id: sessionId, imported: turns.length });
+  });
+
   app.post("/sessions/find_or_create", async (c) => {
     const payload = await parseJson<{
       working_dir?: string;
@@ -102,11 +336,12 @@ export function registerSessionRoutes(app: Hono): void {
       return c.json({ error: "working_dir is required" }, 400);
     }
 
+    const workingDir = await normalizeWorkingDir(payload.working_dir);
     const db = await getDb();
     let query = db
       .selectFrom("sessions")
       .select(["id", "claude_session_id", "start_time"])
-      .where("working_dir", "=", payload.working_dir)
+      .where("working_dir", "=", workingDir)
       .orderBy("start_time", "desc");
 
     if (payload.max_age_hours !== null && payload.max_age_hours !== undefined) {
@@ -127,7 +362,7 @@ export function registerSessionRoutes(app: Hono): void {
     const inserted = await db
       .insertInto("sessions")
       .values({
-        working_dir: payload.working_dir,
+        working_dir: workingDir,
         start_time: nowSeconds(),
         continued_from: null,
         personality: payload.personality ?? null,
@@ -247,10 +482,13 @@ export function registerSessionRoutes(app: Hono): void {
 
     const limitParam = c.req.query("limit");
     const parsedLimit = limitParam ? Number(limitParam) : 50;
-    const limit = Number.isFinite(parsedLimit) ? Math.max(1, parsedLimit) : 50;
+    const limit = Number.isFinite(parsedLimit) ? Math.max(1, Math.min(parsedLimit, 500)) : 50;
+    // Page backwards through long sessions with the last id from the previous page.
+    const beforeParam = c.req.query("before_id");
+    const beforeId = beforeParam && /^\d+$/.test(beforeParam) ? Number(beforeParam) : null;
 
     const db = await getDb();
-    const messages = await db
+    let query = db
       .selectFrom("conversations")
       .select([
         "id",
@@ -264,12 +502,211 @@ export function registerSessionRoutes(app: Hono): void {
         "tool_uses",
         "tool_names",
       ])
-      .where("session_id", "=", sessionId)
-      .orderBy("timestamp", "desc")
-      .limit(limit)
+      .where("session_id", "=", sessionId);
+    if (beforeId !== null) {
+      query = query.where("id", "<", beforeId);
+    }
+    const rows = await query
+      .orderBy("id", "desc")
+      .limit(limit + 1)
       .execute();
 
-    return c.json({ messages });
+    const hasMore = rows.length > limit;
+    const messages = hasMore ? rows.slice(0, limit) : rows;
+    const nextBeforeId = hasMore ? (messages[messages.length - 1]?.id ?? null) : null;
+    return c.json({ messages, has_more: hasMore, next_before_id: nextBeforeId });
+  });
+
+  app.get("/sessions/:session_id/chain", async (c) => {
+    const sessionId = Number(c.req.param("session_id"));
+    if (!Number.isFinite(sessionId)) {
+      return c.json({ error: "Invalid session_id" }, 400);
+    }
+
+    const chain = await getSessionChain(sessionId);
+    if (chain.length === 0) {
+      return errorResponse(c, new DaemonError(ErrorCode.SESSION_NOT_FOUND, "Session not found"));
+    }
+
+    return c.json({ session_id: sessionId, chain });
+  });
+
+  app.get("/sessions/:session_id/summary", async (c) => {
+    const sessionId = Number(c.req.param("session_id"));
+    if (!Number.isFinite(sessionId)) {
+      return c.json({ error: "Invalid session_id" }, 400);
+    }
+
+    const db = await getDb();
+    const session = await db
+      .selectFrom("sessions")
+      .select([
+        "id",
+        "name",
+        "working_dir",
+        "personality",
+        "start_time",
+        "end_time",
+        "summary",
+        "summary_updated_at",
+      ])
+      .where("id", "=", sessionId)
+      .executeTakeFirst();
+    if (!session) {
+      return errorResponse(c, new DaemonError(ErrorCode.SESSION_NOT_FOUND, "Session not found"));
+    }
+
+    return c.json(session);
+  });
+
+  app.get("/sessions/list", async (c) => {
+    const limitRaw = Number(c.req.query("limit") ?? LIST_DEFAULT_LIMIT);
+    const limit = Number.isFinite(limitRaw) && limitRaw > 0 ? Math.floor(limitRaw) : 0;
+    if (limit === 0) {
+      return c.json({ error: "Invalid limit" }, 400);
+    }
+
+    const tag = c.req.query("tag");
+    const workingDir = c.req.query("working_dir");
+    // Only sessions Claude can pick up again with -r
+    const resumable = c.req.query("resumable") === "true";
+
+    const db = await getDb();
+    let query = db
+      .selectFrom("sessions")
+      .select([
+        "id",
+        "name",
+        "working_dir",
+        "personality",
+        "start_time",
+        "last_activity",
+        "pinned",
+        "summary",
+        "claude_session_id",
+      ])
+      .orderBy("last_activity", "desc")
+      .limit(limit);
+    if (tag) {
+      query = query.where("id", "in", sessionIdsTagged(db, tag));
+    }
+    if (workingDir) {
+      query = query.where("working_dir", "=", await normalizeWorkingDir(workingDir));
+    }
+    if (resumable) {
+      query = query.where("claude_session_id", "is not", null);
+    }
+    const sessions = await query.execute();
+    const tags = await getSessionTags(sessions.map((session) => session.id));
+
+    return c.json({
+      sessions: sessions.map((session) => ({ ...session, tags: tags.get(session.id) ?? [] })),
+    });
+  });
+
+  app.get("/sessions/summaries", async (c) => {
+    const limitRaw = Number(c.req.query("limit") ?? LIST_DEFAULT_LIMIT);
+    const limit = Number.isFinite(limitRaw) && limitRaw > 0 ? Math.floor(limitRaw) : 0;
+    if (limit === 0) {
+      return c.json({ error: "Invalid limit" }, 400);
+    }
+    const tag = c.req.query("tag");
+
+    const db = await getDb();
+    let query = db
+      .selectFrom("sessions")
+      .select(["id", "name", "working_dir", "personality", "start_time", "summary"])
+      .where("summary", "is not", null)
+      .orderBy("start_time", "desc")
+      .limit(limit);
+    if (tag) {
+      query = query.where("id", "in", sessionIdsTagged(db, tag));
+    }
+    const sessions = await query.execute();
+    const tags = await getSessionTags(sessions.map((session) => session.id));
+
+    return c.json({
+      sessions: sessions.map((session) => ({ ...session, tags: tags.get(session.id) ?? [] })),
+    });
+  });
+
+  app.post("/sessions/:session_id/tags", async (c) => {
+    const sessionId = Number(c.req.param("session_id"));
+    if (!Number.isFinite(sessionId)) {
+      return c.json({ error: "Invalid session_id" }, 400);
+    }
+    const payload = await parseJson<{ tags?: unknown }>(c.req.raw);
+    const tags = parseTags(payload?.tags);
+    if (!tags || tags.length === 0) {
+      return c.json({ error: "tags must be a non-empty list of words like 'bugfix'" }, 400);
+    }
+
+    const db = await getDb();
+    const session = await db
+      .selectFrom("sessions")
+      .select(["id"])
+      .where("id", "=", sessionId)
+      .executeTakeFirst();
+    if (!session) {
+      return errorResponse(c, new DaemonError(ErrorCode.SESSION_NOT_FOUND, "Session not found"));
+    }
+
+    await addSessionTags(sessionId, tags);
+    const current = await getSessionTags([sessionId]);
+    return c.json({ session_id: sessionId, tags: current.get(sessionId) ?? [] });
+  });
+
+  app.post("/sessions/:session_id/summary/regenerate", async (c) => {
+    const sessionId = Number(c.req.param("session_id"));
+    if (!Number.isFinite(sessionId)) {
+      return c.json({ error: "Invalid session_id" }, 400);
+    }
+    const payload = await parseJson<{ max_words?: number; style?: string; replace?: boolean }>(
+      c.req.raw,
+    );
+    const maxWords = payload?.max_words;
+    if (maxWords !== undefined && (!Number.isInteger(maxWords) || maxWords <= 0)) {
+      return c.json({ error: "max_words must be a positive integer" }, 400);
+    }
+    const rawStyle = payload?.style;
+    const style = isSummaryStyle(rawStyle) ? rawStyle : undefined;
+    if (rawStyle !== undefined && !style) {
+      const styles = Object.keys(SUMMARY_STYLES).join(", ");
+      return c.json({ error: `style must be one of: ${styles}` }, 400);
+    }
+
+    const db = await getDb();
+    const session = await db
+      .selectFrom("sessions")
+      .select(["id"])
+      .where("id", "=", sessionId)
+      .executeTakeFirst();
+    if (!session) {
+      return errorResponse(c, new DaemonError(ErrorCode.SESSION_NOT_FOUND, "Session not found"));
+    }
+
+    try {
+      const variant = await regenerateSummary(sessionId, {
+        maxWords,
+        style,
+        replace: payload?.replace === true,
+      });
+      if (!variant) {
+        return c.json({ error: "Session is too short to summarize" }, 422);
+      }
+      return c.json({ session_id: sessionId, ...variant });
+    } catch (error) {
+      log.summary.warn("Summary regeneration failed", { sessionId, error: String(error) });
+      return c.json({ error: String(error) }, 500);
+    }
+  });
+
+  app.post("/sessions/:session_id/pin", async (c) => {
+    return setSessionPinned(c, true);
+  });
+
+  app.post("/sessions/:session_id/unpin", async (c) => {
+    return setSessionPinned(c, false);
   });
 
   app.get("/sessions/:session_id/last_message_time", async (c) => {
@@ -292,6 +729,72 @@ export function registerSessionRoutes(app: Hono): void {
     return c.json({ session_id: sessionId, last_message_time: timestamp });
   });
 
+  app.post("/sessions/cleanup", async (c) => {
+    const payload = await parseJson<{ older_than_days?: number; dry_run?: boolean }>(c.req.raw);
+    const olderThanDays = payload?.older_than_days;
+    if (typeof olderThanDays !== "number" || !(olderThanDays > 0)) {
+      return c.json({ error: "older_than_days must be a positive number" }, 400);
+    }
+    try {
+      const result = await cleanupOldSessions({
+        olderThanDays,
+        dryRun: payload?.dry_run === true,
+      });
+      return c.json(result);
+    } catch (error) {
+      log.session.warn("Session cleanup failed", { error: String(error) });
+      return errorResponse(c, error);
+    }
+  });
+
+  app.post("/sessions/:session_id/purge", async (c) => {
+    const sessionId = Number(c.req.param("session_id"));
+    if (!Number.isInteger(sessionId) || sessionId <= 0) {
+      return c.json({ error: "Invalid session_id" }, 400);
+    }
+    const payload = await parseJson<{ dry_run?: boolean }>(c.req.raw);
+    try {
+      const result = await purgeSession(sessionId, { dryRun: payload?.dry_run === true });
+      if (!result) {
+        return c.json({ error: "Session not found" }, 404);
+      }
+      if (!result.dry_run) {
+        log.session.info("Purged session", { sessionId, tables: result.tables });
+      }
+      return c.json(result);
+    } catch (error) {
+      log.session.warn("Session purge failed", { sessionId, error: String(error) });
+      return errorResponse(c, error);
+    }
+  });
+
+  app.post("/sessions/reprocess", async (c) => {
+    const payload = await parseJson<{ type?: string; session_id?: number; all?: boolean }>(
+      c.req.raw,
+    );
+    const type = payload?.type;
+    if (!type || !(REPROCESS_TYPES as readonly string[]).includes(type)) {
+      return c.json({ error: `type must be one of: ${REPROCESS_TYPES.join(", ")}` }, 400);
+    }
+    const sessionId = typeof payload?.session_id === "number" ? payload.session_id : null;
+    if (sessionId === null && payload?.all !== true) {
+      return c.json({ error: "session_id or all is required" }, 400);
+    }
+
+    const sessionIds = await findReprocessSessions(type as ReprocessType, sessionId);
+    if (sessionId !== null && sessionIds.length === 0) {
+      return errorResponse(
+        c,
+        new DaemonError(ErrorCode.SESSION_NOT_FOUND, `Session not found or opted out of ${type}`),
+      );
+    }
+    if (!startReprocess(type as ReprocessType, sessionIds)) {
+      return c.json({ error: "A reprocess is already running" }, 409);
+    }
+
+    return c.json({ status: "queued", type, sessions: sessionIds.length });
+  });
+
   app.post("/sessions/end", async (c) => {
     const payload = await parseJson<{ session_id?: number }>(c.req.raw);
     const sessionId = payload?.session_id;
@@ -318,6 +821,24 @@ export function registerSessionRoutes(app: Hono): void {
       .execute();
 
     const endTime = nowSeconds();
+    const session = await db
+      .selectFrom("sessions")
+      .select(["disabled_tasks"])
+      .where("id", "=", sessionId)
+      .executeTakeFirst();
+    if (!session) {
+      return errorResponse(c, new DaemonError(ErrorCode.SESSION_NOT_FOUND, "Session not found"));
+    }
+
+    if (session.disabled_tasks.includes("summary")) {
+      await db
+        .updateTable("sessions")
+        .set({ end_time: endTime })
+        .where("id", "=", sessionId)
+        .execute();
+
+      return c.json({ status: "ended", summary_generated: false, reason: "disabled" });
+    }
 
     if (rows.length === 0) {
       await db
@@ -329,20 +850,23 @@ export function registerSessionRoutes(app: Hono): void {
       return c.json({ status: "ended", summary_generated: false, reason: "no_content" });
     }
 
-    const content = rows
-      .slice()
-      .reverse()
-      .map((row) => `${row.message_type}: ${row.prompt}`)
-      .join("\n");
+    const { text: content, truncated } = buildRecentTranscript(rows);
+    if (truncated) {
+      log.session.debug("Session transcript capped for summary", { sessionId });
+    }
 
     const summary = await generateShortSummary(content);
     const updateValues: Record<string, unknown> = { end_time: endTime };
     if (summary) {
       updateValues.summary = summary;
       updateValues.summary_updated_at = nowDate();
+      updateValues.summary_embedding = null;
     }
 
     await db.updateTable("sessions").set(updateValues).where("id", "=", sessionId).execute();
+    if (summary) {
+      void embedSessionSummary(sessionId, summary);
+    }
 
     return c.json({ status: "ended", summary_generated: Boolean(summary) });
   });
@@ -0,0 +1,139 @@
+import { sql } from "kysely";
+
+import { deleteSessionEpisodes } from "@dere/graph";
+
+import { getDb } from "../db.js";
+import { log } from "../logger.js";
+import { ingestUserMessage, parseContextHint } from "./conversations.js";
+import { resummarizeSession } from "./summary.js";
+
+export const REPROCESS_TYPES = ["entities", "summary", "embeddings"] as const;
+
+export type ReprocessType = (typeof REPROCESS_TYPES)[number];
+
+let reprocessRunning = false;
+
+/** Sessions to reprocess: one id, or every session that hasn't opted out of `type`. */
+export async function findReprocessSessions(
+  type: ReprocessType,
+  sessionId: number | null,
+): Promise<number[]> {
+  const db = await getDb();
+  let query = db
+    .selectFrom("sessions")
+    .select(["id"])
+    .where(sql<boolean>`not (${type} = any(disabled_tasks))`)
+    .orderBy("id");
+  if (sessionId !== null) {
+    query = query.where("id", "=", sessionId);
+  }
+  return (await query.execute()).map((row) => row.id);
+}
+
+/**
+ * Clear a session's graph extraction and run its user messages through entity
+ * extraction again, oldest first so episodes rebuild in order.
+ */
+async function reextractEntities(sessionId: number): Promise<number> {
+  const db = await getDb();
+  const rows = await db
+    .selectFrom("conversations")
+    .select(["prompt", "timestamp", "medium", "user_id", "personality"])
+    .where("session_id", "=", sessionId)
+    .where("message_type", "=", "user")
+    .orderBy("timestamp")
+    .execute();
+  const first = rows[0];
+  if (!first) {
+    return 0;
+  }
+
+  const session = await db
+    .selectFrom("sessions")
+    .select(["context_hint"])
+    .where("id", "=", sessionId)
+    .executeTakeFirst();
+  const contextHint = parseContextHint(session?.context_hint);
+
+  const groupId = first.user_id ?? "default";
+  const cleared = await deleteSessionEpisodes(groupId, sessionId);
+  await db.deleteFrom("entities").where("session_id", "=", sessionId).execute();
+  log.kg.debug("Cleared previous extraction", { sessionId, ...cleared });
+
+  let processed = 0;
+  for (const row of rows) {
+    if (!row.prompt.trim()) {
+      continue;
+    }
+    try {
+      await ingestUserMessage({
+        prompt: row.prompt,
+        referenceTime: new Date(row.timestamp * 1000),
+        medium: row.medium,
+        userId: row.user_id,
+        personality: row.personality,
+        contextHint,
+        sessionId,
+      });
+      processed += 1;
+    } catch (error) {
+      log.kg.warn("Reprocess extraction failed", { sessionId, error: String(error) });
+    }
+  }
+  return processed;
+}
+
+/** Drop a session's conversation and summary vectors; the backfill loop re-embeds them. */
+async function clearEmbeddings(sessionId: number): Promise<void> {
+  const db = await getDb();
+  await db
+    .updateTable("conversation_blocks")
+    .set({ content_embedding: null })
+    .where(
+      "conversation_id",
+      "in",
+      db.selectFrom("conversations").select("id").where("session_id", "=", sessionId),
+    )
+    .execute();
+  await db
+    .updateTable("sessions")
+    .set({ summary_embedding: null })
+    .where("id", "=", sessionId)
+    .execute();
+}
+
+/**
+ * Re-run one pipeline stage over already-stored sessions, one at a time in
+ * the background. Returns false if a reprocess is already in progress.
+ */
+export function startReprocess(type: ReprocessType, sessionIds: number[]): boolean {
+  if (reprocessRunning) {
+    return false;
+  }
+  reprocessRunning = true;
+
+  void (async () => {
+    let done = 0;
+    try {
+      for (const sessionId of sessionIds) {
+        try {
+          if (type === "entities") {
+            await reextractEntities(sessionId);
+          } else if (type === "summary") {
+            await resummarizeSession(sessionId);
+          } else {
+            await clearEmbeddings(sessionId);
+          }
+          done += 1;
+        } catch (error) {
+          log.session.warn("Reprocess failed", { type, sessionId, error: String(error) });
+        }
+      }
+      log.session.info("Reprocess complete", { type, sessions: done });
+    } finally {
+      reprocessRunning = false;
+    }
+  })();
+
+  return true;
+}
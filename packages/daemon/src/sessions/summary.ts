@@ -2,13 +2,26 @@ import { sql } from "kysely";
 
 import { ClaudeAgentTransport, TextResponseClient } from "@dere/shared-llm";
 
+import { loadConfig, loadPromptTemplate } from "@dere/shared-config";
+
 import { getDb } from "../db.js";
 import { log } from "../logger.js";
+import { embedSessionSummary } from "../memory/embeddings.js";
+import { loadPersonality } from "../personalities/index.js";
+import { extractActionItems } from "./action-items.js";
+import { formatToolActivity, getToolActivity } from "./tool-events.js";
+import {
+  buildRecentTranscript,
+  enforceWordLimit,
+  loadSummaryMaxWords,
+} from "../utils/summary.js";
 
 const SUMMARY_IDLE_TIMEOUT_SECONDS = 1800;
 const SUMMARY_CHECK_INTERVAL_MS = 300_000;
 const SUMMARY_MIN_MESSAGES = 5;
 const DEFAULT_SUMMARY_MODEL = "claude-opus-4-5";
+const DEFAULT_PERIODIC_SUMMARY_MINUTES = 30;
+const DEFAULT_PERIODIC_SUMMARY_MESSAGES = 20;
 
 let summaryTimer: ReturnType<typeof setInterval> | null = null;
 let summaryRunning = false;
@@ -17,6 +30,25 @@ function nowDate(): Date {
   return new Date();
 }
 
+/** The session personality's `[summary] tone`, if it defines one. */
+async function loadSummaryTone(sessionId: number): Promise<string | null> {
+  const db = await getDb();
+  const session = await db
+    .selectFrom("sessions")
+    .select(["personality"])
+    .where("id", "=", sessionId)
+    .executeTakeFirst();
+  const name = session?.personality?.split(",")[0]?.trim();
+  if (!name) {
+    return null;
+  }
+  try {
+    return (await loadPersonality(name)).summary_tone ?? null;
+  } catch {
+    return null;
+  }
+}
+
 function getSummaryClient(): TextResponseClient {
   const transport = new ClaudeAgentTransport({
     workingDirectory: process.env.DERE_TS_LLM_CWD ?? "/tmp/dere-llm-sessions",
@@ -62,6 +94,305 @@ async function runSummaryCycle(): Promise<void> {
   }
 }
 
+async function loadPeriodicSummarySettings(): Promise<{ minutes: number; messages: number }> {
+  let minutes = DEFAULT_PERIODIC_SUMMARY_MINUTES;
+  let messages = DEFAULT_PERIODIC_SUMMARY_MESSAGES;
+  try {
+    const config = await loadConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    if (typeof contextConfig.periodic_summary_minutes === "number") {
+      minutes = contextConfig.periodic_summary_minutes;
+    }
+    if (typeof contextConfig.periodic_summary_messages === "number") {
+      messages = contextConfig.periodic_summary_messages;
+    }
+  } catch {
+    // defaults already set
+  }
+  return { minutes, messages };
+}
+
+// Alternative summary lengths for `dere summaries regenerate`; a style sets
+// the default word limit and how much of the session the model sees.
+export const SUMMARY_STYLES = {
+  brief: {
+    instruction: "Style: a single-line TL;DR.",
+    maxWords: 25,
+    transcriptChars: 2000,
+    maxMessages: 50,
+  },
+  detailed: {
+    instruction:
+      "Style: detailed. Use as many sentences as the word limit allows to cover " +
+      "what was discussed, decisions made, and anything left open.",
+    maxWords: 200,
+    transcriptChars: 8000,
+    maxMessages: 200,
+  },
+  decisions: {
+    instruction:
+      "Style: structured. Reply with exactly three lines starting with " +
+      '"Discussed:", "Decided:", and "To-do:". Decisions, conclusions, and ' +
+      "action items mostly live in the assistant turns, so read those closely. " +
+      'Write "none" for a section with nothing in it.',
+    maxWords: 150,
+    transcriptChars: 8000,
+    maxMessages: 200,
+  },
+} as const;
+
+export type SummaryStyle = keyof typeof SUMMARY_STYLES;
+
+export function isSummaryStyle(value: unknown): value is SummaryStyle {
+  return typeof value === "string" && Object.hasOwn(SUMMARY_STYLES, value);
+}
+
+type DecisionSections = { discussed: string; decided: string | null; todo: string | null };
+
+// Tolerates markdown around the headings, e.g. "- **Decided:** ..."
+const DECISION_HEADING_RE = /^\W*(discussed|decided|to-?do)\W*?:[\s*_]*(.*)$/i;
+
+/**
+ * Split a "decisions" summary into its three parts, each trimmed to a third of
+ * the word limit so a long first section can't crowd out the to-do list.
+ * Null when the model ignored the format.
+ */
+function parseDecisionSections(raw: string, maxWords: number): DecisionSections | null {
+  const parts: Record<"discussed" | "decided" | "todo", string[]> = {
+    discussed: [],
+    decided: [],
+    todo: [],
+  };
+  let current: keyof typeof parts | null = null;
+  for (const line of raw.split("\n")) {
+    const match = line.match(DECISION_HEADING_RE);
+    if (match) {
+      const heading = match[1]!.toLowerCase();
+      current = heading === "discussed" ? "discussed" : heading === "decided" ? "decided" : "todo";
+      parts[current].push(match[2] ?? "");
+    } else if (current && line.trim()) {
+      parts[current].push(line.trim());
+    }
+  }
+  const sectionWords = Math.max(1, Math.ceil(maxWords / 3));
+  const section = (lines: string[]) => {
+    const text = lines.join(" ").replace(/\s+/g, " ").trim();
+    return text && !/^none\.?$/i.test(text) ? enforceWordLimit(text, sectionWords) : null;
+  };
+  const discussed = section(parts.discussed);
+  if (!discussed) {
+    return null;
+  }
+  return { discussed, decided: section(parts.decided), todo: section(parts.todo) };
+}
+
+function formatDecisionSections(sections: DecisionSections): string {
+  return [
+    `Discussed: ${sections.discussed}`,
+    `Decided: ${sections.decided ?? "none"}`,
+    `To-do: ${sections.todo ?? "none"}`,
+  ].join("\n");
+}
+
+/**
+ * Add the files the session touched and the commands it ran, when tool events
+ * were captured, so the summary can say what was actually changed.
+ */
+async function appendToolActivity(sessionId: number, transcript: string): Promise<string> {
+  try {
+    const db = await getDb();
+    const session = await db
+      .selectFrom("sessions")
+      .select(["working_dir"])
+      .where("id", "=", sessionId)
+      .executeTakeFirst();
+    const activity = formatToolActivity(
+      await getToolActivity(sessionId),
+      session?.working_dir ?? "",
+    );
+    return activity ? `${transcript}\n\nDuring the session:\n${activity}` : transcript;
+  } catch (error) {
+    log.summary.warn("Failed to load tool activity", { sessionId, error: String(error) });
+    return transcript;
+  }
+}
+
+/**
+ * Generate a summary of one session from its latest turns, or null when the
+ * session is too short to summarize or the model returns nothing.
+ */
+async function generateSummary(
+  client: TextResponseClient,
+  sessionId: number,
+  maxWords: number,
+  style: SummaryStyle | null,
+): Promise<string | null> {
+  const db = await getDb();
+  const countRow = await db
+    .selectFrom("conversations")
+    .select(db.fn.countAll().as("count"))
+    .where("session_id", "=", sessionId)
+    .executeTakeFirst();
+
+  const messageCount = Number(countRow?.count ?? 0);
+  if (messageCount < SUMMARY_MIN_MESSAGES) {
+    return null;
+  }
+
+  const rows = await db
+    .selectFrom("conversations")
+    .select(["prompt", "message_type"])
+    .where("session_id", "=", sessionId)
+    .orderBy("timestamp", "desc")
+    .limit(style ? SUMMARY_STYLES[style].maxMessages : 50)
+    .execute();
+
+  if (rows.length === 0) {
+    return null;
+  }
+
+  // Favor the latest turns; the oldest ones are what a capped transcript drops.
+  const transcriptChars = style ? SUMMARY_STYLES[style].transcriptChars : 2000;
+  const { text: transcript } = buildRecentTranscript(rows, transcriptChars);
+  const content = await appendToolActivity(sessionId, transcript);
+
+  const prompt = await loadPromptTemplate(
+    "session-summary",
+    `Summarize this conversation in 1-2 concise sentences (at most {{max_words}} words). Focus on what was discussed and any outcomes.
+
+{{content}}`,
+    { content, max_words: maxWords },
+  );
+  const tone = await loadSummaryTone(sessionId);
+  const instructions = [tone, style ? SUMMARY_STYLES[style].instruction : null].filter(Boolean);
+
+  const raw = (await client.generate([...instructions, prompt].join("\n\n"))).trim();
+  if (!raw) {
+    return null;
+  }
+  // Sentence-based trimming would cut the structured format off at the end
+  if (style === "decisions") {
+    const sections = parseDecisionSections(raw, maxWords);
+    if (sections) {
+      return formatDecisionSections(sections);
+    }
+  }
+  return enforceWordLimit(raw, maxWords);
+}
+
+/**
+ * Write a fresh summary for one session from its latest turns. Returns false
+ * when the session is too short to summarize or generation fails.
+ */
+async function summarizeSession(
+  client: TextResponseClient,
+  sessionId: number,
+  maxWords: number,
+  now: Date,
+): Promise<boolean> {
+  try {
+    const summary = await generateSummary(client, sessionId, maxWords, null);
+    if (!summary) {
+      return false;
+    }
+
+    const db = await getDb();
+    await db
+      .updateTable("sessions")
+      .set({
+        summary,
+        summary_updated_at: now,
+        summary_embedding: null,
+      })
+      .where("id", "=", sessionId)
+      .execute();
+    void embedSessionSummary(sessionId, summary);
+
+    log.summary.debug("Generated summary", { sessionId });
+    return true;
+  } catch (error) {
+    log.summary.warn("Failed to summarize session", { sessionId, error: String(error) });
+    return false;
+  }
+}
+
+/** Regenerate one session's summary regardless of age, e.g. after a prompt change. */
+export async function resummarizeSession(sessionId: number): Promise<boolean> {
+  const maxWords = await loadSummaryMaxWords("session");
+  return summarizeSession(getSummaryClient(), sessionId, maxWords, nowDate());
+}
+
+export type SummaryVariant = {
+  summary: string;
+  summary_type: string;
+  max_words: number;
+  replaced: boolean;
+  /** The "Decided" and "To-do" parts of a decisions-style summary */
+  decisions: string | null;
+  next_steps: string | null;
+};
+
+/**
+ * Summarize a session again at another length or style. The result is kept
+ * as a variant alongside the original unless `replace` is set, in which case
+ * it becomes the session's summary. Null when there is nothing to summarize.
+ */
+export async function regenerateSummary(
+  sessionId: number,
+  options: { maxWords?: number | undefined; style?: SummaryStyle | undefined; replace?: boolean },
+): Promise<SummaryVariant | null> {
+  const style = options.style ?? null;
+  const maxWords =
+    options.maxWords ??
+    (style ? SUMMARY_STYLES[style].maxWords : await loadSummaryMaxWords("session"));
+  const summary = await generateSummary(getSummaryClient(), sessionId, maxWords, style);
+  if (!summary) {
+    return null;
+  }
+
+  const db = await getDb();
+  const summaryType = style ?? "default";
+  const sections = style === "decisions" ? parseDecisionSections(summary, maxWords) : null;
+  const decisions = sections?.decided ?? null;
+  const nextSteps = sections?.todo ?? null;
+  if (options.replace) {
+    await db
+      .updateTable("sessions")
+      .set({ summary, summary_updated_at: nowDate(), summary_embedding: null })
+      .where("id", "=", sessionId)
+      .execute();
+    void embedSessionSummary(sessionId, summary);
+  }
+  // Structured parts only live on variants, so keep one even when replacing
+  if (!options.replace || sections) {
+    await db
+      .insertInto("session_summary_variants")
+      .values({
+        session_id: sessionId,
+        summary_type: summaryType,
+        max_words: maxWords,
+        summary,
+        decisions,
+        next_steps: nextSteps,
+      })
+      .execute();
+  }
+  return {
+    summary,
+    summary_type: summaryType,
+    max_words: maxWords,
+    replaced: Boolean(options.replace),
+    decisions,
+    next_steps: nextSteps,
+  };
+}
+
+/**
+ * Summarize sessions that have gone idle, plus long-running sessions that are
+ * due a periodic refresh (enough new messages or enough time since the last
+ * summary). The periodic pass means a session that crashes or never sends
+ * SessionEnd still contributes a recent summary to future context.
+ */
 async function summarizeIdleSessions(): Promise<void> {
   if (process.env.DERE_DISABLE_SUMMARY === "1") {
     return;
@@ -71,79 +402,67 @@ async function summarizeIdleSessions(): Promise<void> {
   const now = nowDate();
   const idleThreshold = new Date(now.getTime() - SUMMARY_IDLE_TIMEOUT_SECONDS * 1000);
   const recentThreshold = new Date(now.getTime() - 24 * 60 * 60 * 1000);
+  const periodic = await loadPeriodicSummarySettings();
+  const periodicThreshold = new Date(now.getTime() - periodic.minutes * 60 * 1000);
 
-  const sessions = await db
+  const candidates = await db
     .selectFrom("sessions")
     .selectAll()
     .where("last_activity", ">=", recentThreshold)
-    .where("last_activity", "<=", idleThreshold)
     .where("end_time", "is", null)
     .where(sql<boolean>`(summary is null or summary_updated_at < last_activity)`)
+    .where(sql<boolean>`not ('summary' = any(disabled_tasks))`)
     .execute();
 
+  const sessions: typeof candidates = [];
+  for (const session of candidates) {
+    if (session.last_activity <= idleThreshold) {
+      sessions.push(session);
+      continue;
+    }
+
+    const summarizedAt = session.summary_updated_at ?? new Date(session.start_time * 1000);
+    if (periodic.minutes > 0 && summarizedAt <= periodicThreshold) {
+      sessions.push(session);
+      continue;
+    }
+
+    if (periodic.messages > 0) {
+      const newRow = await db
+        .selectFrom("conversations")
+        .select(db.fn.countAll().as("count"))
+        .where("session_id", "=", session.id)
+        .where("created_at", ">", summarizedAt)
+        .executeTakeFirst();
+      if (Number(newRow?.count ?? 0) >= periodic.messages) {
+        sessions.push(session);
+      }
+    }
+  }
+
   if (sessions.length === 0) {
     return;
   }
 
   const client = getSummaryClient();
+  const maxWords = await loadSummaryMaxWords("session");
   const updatedUsers = new Set<string>();
 
   for (const session of sessions) {
-    const countRow = await db
-      .selectFrom("conversations")
-      .select(db.fn.countAll().as("count"))
-      .where("session_id", "=", session.id)
-      .executeTakeFirst();
-
-    const messageCount = Number(countRow?.count ?? 0);
-    if (messageCount < SUMMARY_MIN_MESSAGES) {
+    const summarized = await summarizeSession(client, session.id, maxWords, now);
+    if (!summarized) {
       continue;
     }
-
-    const rows = await db
-      .selectFrom("conversations")
-      .select(["prompt", "message_type"])
-      .where("session_id", "=", session.id)
-      .orderBy("timestamp", "desc")
-      .limit(50)
-      .execute();
-
-    if (rows.length === 0) {
-      continue;
+    if (session.user_id && session.user_id !== "default") {
+      updatedUsers.add(session.user_id);
     }
-
-    const content = rows
-      .slice()
-      .reverse()
-      .map((row) => `${row.message_type}: ${row.prompt}`)
-      .join("\n");
-
-    const prompt = `Summarize this conversation in 1-2 concise sentences. Focus on what was discussed and any outcomes.
-
-${content.slice(0, 2000)}`;
-
     try {
-      const summary = (await client.generate(prompt)).trim();
-      if (!summary) {
-        continue;
-      }
-
-      await db
-        .updateTable("sessions")
-        .set({
-          summary,
-          summary_updated_at: now,
-        })
-        .where("id", "=", session.id)
-        .execute();
-
-      log.summary.debug("Generated summary", { sessionId: session.id });
-
-      if (session.user_id && session.user_id !== "default") {
-        updatedUsers.add(session.user_id);
-      }
+      await extractActionItems(session.id);
     } catch (error) {
-      log.summary.warn("Failed to summarize session", { sessionId: session.id, error: String(error) });
+      log.summary.warn("Action item extraction failed", {
+        sessionId: session.id,
+        error: String(error),
+      });
     }
   }
 
@@ -184,25 +503,31 @@ async function updateSummaryContext(): Promise<void> {
     return;
   }
 
+  const maxWords = await loadSummaryMaxWords("context");
   const sessionSummaries = newSessions
     .map((session) => session.summary)
     .filter(Boolean)
     .map((summary) => `- ${summary}`)
     .join("\n");
 
-  const prompt = `Previous: ${prevSummary ?? "None"}
+  const prompt = await loadPromptTemplate(
+    "summary-context",
+    `Previous: {{previous}}
 
 Recent:
-${sessionSummaries}
+{{recent}}
 
-Merge into 1-2 sentences. No headers, no preambles.`;
+Merge into 1-2 sentences (at most {{max_words}} words). No headers, no preambles.`,
+    { previous: prevSummary ?? "None", recent: sessionSummaries, max_words: maxWords },
+  );
 
   try {
     const client = getSummaryClient();
-    const newSummary = (await client.generate(prompt)).trim();
-    if (!newSummary) {
+    const raw = (await client.generate(prompt)).trim();
+    if (!raw) {
       return;
     }
+    const newSummary = enforceWordLimit(raw, maxWords);
 
     const combinedIds = new Set<number>(prevSessionIds);
     for (const session of newSessions) {
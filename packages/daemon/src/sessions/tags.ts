@@ -0,0 +1,60 @@
+import type { Kysely } from "kysely";
+
+import { getDb } from "../db.js";
+import type { Database } from "../db-types.js";
+
+const TAG_PATTERN = /^[a-z0-9][a-z0-9._-]{0,63}$/;
+
+/** Lowercased, deduplicated tags; null if any value isn't a usable tag. */
+export function parseTags(raw: unknown): string[] | null {
+  if (!Array.isArray(raw)) {
+    return null;
+  }
+  const tags = new Set<string>();
+  for (const value of raw) {
+    const tag = typeof value === "string" ? value.trim().toLowerCase() : "";
+    if (!TAG_PATTERN.test(tag)) {
+      return null;
+    }
+    tags.add(tag);
+  }
+  return Array.from(tags);
+}
+
+/** Attach tags to a session; tags it already has are left alone. */
+export async function addSessionTags(sessionId: number, tags: string[]): Promise<void> {
+  if (tags.length === 0) {
+    return;
+  }
+  const db = await getDb();
+  await db
+    .insertInto("session_tags")
+    .values(tags.map((tag) => ({ session_id: sessionId, tag })))
+    .onConflict((oc) => oc.columns(["session_id", "tag"]).doNothing())
+    .execute();
+}
+
+export async function getSessionTags(sessionIds: number[]): Promise<Map<number, string[]>> {
+  const tagsBySession = new Map<number, string[]>();
+  if (sessionIds.length === 0) {
+    return tagsBySession;
+  }
+  const db = await getDb();
+  const rows = await db
+    .selectFrom("session_tags")
+    .select(["session_id", "tag"])
+    .where("session_id", "in", sessionIds)
+    .orderBy("tag")
+    .execute();
+  for (const row of rows) {
+    const tags = tagsBySession.get(row.session_id) ?? [];
+    tags.push(row.tag);
+    tagsBySession.set(row.session_id, tags);
+  }
+  return tagsBySession;
+}
+
+/** Subquery of session ids carrying `tag`, for `where(column, "in", ...)` filters. */
+export function sessionIdsTagged(db: Kysely<Database>, tag: string) {
+  return db.selectFrom("session_tags").select("session_id").where("tag", "=", tag.toLowerCase());
+}
@@ -0,0 +1,195 @@
+/**
+ * Tool use recorded by the PostToolUse hook: which files Claude read and
+ * edited and which commands it ran. Summaries draw on it so they describe
+ * what actually happened, not only what was said.
+ */
+
+import { relative } from "node:path";
+
+import type { Hono } from "hono";
+
+import {
+  captureExcludePaths,
+  captureToolEvents,
+  isCaptureExcluded,
+  loadConfig,
+} from "@dere/shared-config";
+
+import { getDb } from "../db.js";
+import { log } from "../logger.js";
+
+const MAX_COMMAND_CHARS = 500;
+const MAX_ACTIVITY_FILES = 15;
+const MAX_ACTIVITY_COMMANDS = 10;
+
+type ToolKind = "read" | "edit" | "command";
+
+// Tools worth recording and the input field that names what they touched.
+const RECORDED_TOOLS: Record<string, { kind: ToolKind; field: string }> = {
+  Read: { kind: "read", field: "file_path" },
+  Edit: { kind: "edit", field: "file_path" },
+  MultiEdit: { kind: "edit", field: "file_path" },
+  Write: { kind: "edit", field: "file_path" },
+  NotebookEdit: { kind: "edit", field: "notebook_path" },
+  Bash: { kind: "command", field: "command" },
+};
+
+/**
+ * The file or command a tool call was about, or null for tools that aren't
+ * recorded or calls without the expected input.
+ */
+export function describeToolUse(
+  toolName: string,
+  toolInput: unknown,
+): { target: string | null; command: string | null } | null {
+  const recorded = RECORDED_TOOLS[toolName];
+  if (!recorded || !toolInput || typeof toolInput !== "object") {
+    return null;
+  }
+  const value = (toolInput as Record<string, unknown>)[recorded.field];
+  if (typeof value !== "string" || !value.trim()) {
+    return null;
+  }
+  if (recorded.kind === "command") {
+    const command = value.trim().replace(/\s+/g, " ");
+    return {
+      target: null,
+      command:
+        command.length > MAX_COMMAND_CHARS ? `${command.slice(0, MAX_COMMAND_CHARS)}...` : command,
+    };
+  }
+  return { target: value.trim(), command: null };
+}
+
+export type ToolActivity = {
+  edited: string[];
+  read: string[];
+  commands: string[];
+};
+
+/** Files edited and read and commands run in a session, each in first-use order. */
+export async function getToolActivity(sessionId: number): Promise<ToolActivity> {
+  const db = await getDb();
+  const rows = await db
+    .selectFrom("tool_events")
+    .select(["tool_name", "target", "command"])
+    .where("session_id", "=", sessionId)
+    .orderBy("created_at")
+    .orderBy("id")
+    .execute();
+
+  const edited = new Set<string>();
+  const read = new Set<string>();
+  const commands = new Set<string>();
+  for (const row of rows) {
+    const kind = RECORDED_TOOLS[row.tool_name]?.kind;
+    if (kind === "command" && row.command) {
+      commands.add(row.command);
+    } else if (kind === "edit" && row.target) {
+      edited.add(row.target);
+    } else if (kind === "read" && row.target) {
+      read.add(row.target);
+    }
+  }
+  // A file that was edited doesn't need listing as read as well.
+  for (const path of edited) {
+    read.delete(path);
+  }
+  return { edited: [...edited], read: [...read], commands: [...commands] };
+}
+
+function listWithOverflow(items: string[], max: number, separator: string): string {
+  const shown = items.slice(0, max).join(separator);
+  return items.length > max ? `${shown}${separator}+${items.length - max} more` : shown;
+}
+
+/**
+ * Tool activity as a few labelled lines for a summarization prompt, with
+ * paths made relative to the session's project. Empty when nothing was
+ * recorded.
+ */
+export function formatToolActivity(activity: ToolActivity, workingDir: string): string {
+  const display = (path: string) => {
+    const rel = workingDir ? relative(workingDir, path) : path;
+    return rel && !rel.startsWith("..") ? rel : path;
+  };
+  const lines: string[] = [];
+  if (activity.edited.length > 0) {
+    const files = activity.edited.map(display);
+    lines.push(`Files edited: ${listWithOverflow(files, MAX_ACTIVITY_FILES, ", ")}`);
+  }
+  if (activity.read.length > 0) {
+    const files = activity.read.map(display);
+    lines.push(`Files read: ${listWithOverflow(files, MAX_ACTIVITY_FILES, ", ")}`);
+  }
+  if (activity.commands.length > 0) {
+    lines.push(
+      `Commands run: ${listWithOverflow(activity.commands, MAX_ACTIVITY_COMMANDS, "; ")}`,
+    );
+  }
+  return lines.join("\n");
+}
+
+async function parseJson<T>(req: Request): Promise<T | null> {
+  try {
+    return (await req.json()) as T;
+  } catch {
+    return null;
+  }
+}
+
+export function registerToolEventRoutes(app: Hono): void {
+  app.post("/conversation/tool-event", async (c) => {
+    const payload = await parseJson<Record<string, unknown>>(c.req.raw);
+    if (!payload) {
+      return c.json({ error: "Invalid JSON payload" }, 400);
+    }
+    const sessionId = typeof payload.session_id === "number" ? payload.session_id : null;
+    const toolName = typeof payload.tool_name === "string" ? payload.tool_name : "";
+    if (!sessionId || !toolName) {
+      return c.json({ error: "session_id and tool_name are required" }, 400);
+    }
+
+    const config = await loadConfig();
+    if (!captureToolEvents(config)) {
+      return c.json({ status: "disabled" });
+    }
+    const described = describeToolUse(toolName, payload.tool_input);
+    if (!described) {
+      return c.json({ status: "ignored" });
+    }
+
+    const db = await getDb();
+    const session = await db
+      .selectFrom("sessions")
+      .select(["working_dir"])
+      .where("id", "=", sessionId)
+      .executeTakeFirst();
+    if (!session) {
+      return c.json({ error: "Session not found" }, 404);
+    }
+    if (isCaptureExcluded(session.working_dir, captureExcludePaths(config))) {
+      return c.json({ status: "excluded" });
+    }
+
+    await db
+      .insertInto("tool_events")
+      .values({
+        session_id: sessionId,
+        tool_name: toolName,
+        target: described.target,
+        command: described.command,
+      })
+      .execute();
+    log.session.debug("Recorded tool event", { sessionId, toolName });
+    return c.json({ status: "stored" });
+  });
+
+  app.get("/sessions/:session_id/tool-activity", async (c) => {
+    const sessionId = Number(c.req.param("session_id"));
+    if (!Number.isInteger(sessionId) || sessionId <= 0) {
+      return c.json({ error: "Invalid session_id" }, 400);
+    }
+    return c.json({ session_id: sessionId, ...(await getToolActivity(sessionId)) });
+  });
+}
@@ -0,0 +1,249 @@
+/**
+ * Themes across sessions: recent session summary embeddings grouped with
+ * spherical k-means, so `dere topics` can show what the time went to.
+ */
+
+import type { Hono } from "hono";
+import { sql } from "kysely";
+
+import { cosineSimilarity, toEmbedding } from "@dere/graph";
+
+import { getDb } from "../db.js";
+import { log } from "../logger.js";
+
+const DEFAULT_TOPIC_DAYS = 90;
+const DEFAULT_EXAMPLES = 3;
+const MAX_CLUSTERS = 12;
+const KMEANS_ITERATIONS = 25;
+
+type TopicSession = {
+  id: number;
+  name: string | null;
+  working_dir: string;
+  summary: string;
+  last_activity: Date;
+};
+
+export type TopicCluster = {
+  /** Sessions in the cluster */
+  size: number;
+  /** Mean similarity of members to the cluster centre (0-1) */
+  cohesion: number;
+  /** Projects the sessions came from, most frequent first */
+  projects: Array<{ working_dir: string; sessions: number }>;
+  /** Sessions nearest the centre, the most representative summaries */
+  examples: Array<TopicSession & { similarity: number }>;
+};
+
+function normalize(vector: number[]): number[] {
+  const norm = Math.sqrt(vector.reduce((sum, value) => sum + value * value, 0));
+  return norm > 0 ? vector.map((value) => value / norm) : vector;
+}
+
+// Small seeded PRNG so the same sessions cluster the same way run to run.
+function seededRandom(seed: number): () => number {
+  let state = seed >>> 0;
+  return () => {
+    state = (state * 1664525 + 1013904223) >>> 0;
+    return state / 2 ** 32;
+  };
+}
+
+/** Cluster count when none is asked for: about sqrt(n/2), at least 2. */
+function defaultClusterCount(points: number): number {
+  return Math.max(2, Math.min(MAX_CLUSTERS, Math.round(Math.sqrt(points / 2))));
+}
+
+/**
+ * Spherical k-means over unit vectors, seeded k-means++ style. Returns the
+ * cluster index of each point and the centroids.
+ */
+function kMeans(
+  points: number[][],
+  k: number,
+): { assignments: number[]; centroids: number[][] } {
+  const random = seededRandom(points.length * 31 + k);
+  const first = points[Math.floor(random() * points.length)] ?? [];
+  const centroids: number[][] = [first];
+  while (centroids.length < k) {
+    // Favour points far from every centroid chosen so far.
+    const distances = points.map(
+      (point) => 1 - Math.max(...centroids.map((centroid) => cosineSimilarity(point, centroid))),
+    );
+    const total = distances.reduce((sum, value) => sum + Math.max(0, value), 0);
+    if (total <= 0) {
+      break;
+    }
+    let target = random() * total;
+    let chosen = points.length - 1;
+    for (let i = 0; i < distances.length; i += 1) {
+      target -= Math.max(0, distances[i] ?? 0);
+      if (target <= 0) {
+        chosen = i;
+        break;
+      }
+    }
+    centroids.push(points[chosen] ?? first);
+  }
+
+  let assignments = points.map(() => 0);
+  for (let iteration = 0; iteration < KMEANS_ITERATIONS; iteration += 1) {
+    const next = points.map((point) => {
+      let best = 0;
+      let bestScore = -Infinity;
+      centroids.forEach((centroid, index) => {
+        const score = cosineSimilarity(point, centroid);
+        if (score > bestScore) {
+          best = index;
+          bestScore = score;
+        }
+      });
+      return best;
+    });
+    const changed = next.some((cluster, i) => cluster !== assignments[i]);
+    assignments = next;
+
+    centroids.forEach((centroid, index) => {
+      const members = points.filter((_, i) => assignments[i] === index);
+      if (members.length === 0) {
+        return;
+      }
+      const sum = centroid.map((_, dim) => members.reduce((acc, m) => acc + (m[dim] ?? 0), 0));
+      centroids[index] = normalize(sum);
+    });
+    if (!changed && iteration > 0) {
+      break;
+    }
+  }
+  return { assignments, centroids };
+}
+
+/**
+ * Group summarized sessions active in the last `days` days by topic. Only
+ * embeddings from the most common model are used, since vectors from
+ * different models can't be compared.
+ */
+export async function clusterSessionTopics(options: {
+  days?: number;
+  clusters?: number;
+  examples?: number;
+}): Promise<{ sessions: number; clusters: TopicCluster[] }> {
+  const days = options.days ?? DEFAULT_TOPIC_DAYS;
+  const examples = options.examples ?? DEFAULT_EXAMPLES;
+  const cutoff = new Date(Date.now() - days * 24 * 60 * 60 * 1000);
+
+  const db = await getDb();
+  const model = await db
+    .selectFrom("sessions")
+    .select(["summary_embedding_model", sql<number>`count(*)::int`.as("count")])
+    .where("summary_embedding", "is not", null)
+    .where("last_activity", ">=", cutoff)
+    .groupBy("summary_embedding_model")
+    .orderBy("count", "desc")
+    .executeTakeFirst();
+  if (!model?.summary_embedding_model) {
+    return { sessions: 0, clusters: [] };
+  }
+
+  const rows = await db
+    .selectFrom("sessions")
+    .select([
+      "id",
+      "name",
+      "working_dir",
+      "summary",
+      "last_activity",
+      sql<string>`summary_embedding::text`.as("embedding"),
+    ])
+    .where("summary_embedding_model", "=", model.summary_embedding_model)
+    .where("summary_embedding", "is not", null)
+    .where("summary", "is not", null)
+    .where("last_activity", ">=", cutoff)
+    .execute();
+
+  const sessions: TopicSession[] = [];
+  const points: number[][] = [];
+  for (const row of rows) {
+    let embedding: number[] | null = null;
+    try {
+      embedding = toEmbedding(JSON.parse(row.embedding));
+    } catch {
+      embedding = null;
+    }
+    if (!embedding || !row.summary) {
+      continue;
+    }
+    sessions.push({
+      id: row.id,
+      name: row.name,
+      working_dir: row.working_dir,
+      summary: row.summary,
+      last_activity: row.last_activity,
+    });
+    points.push(normalize(embedding));
+  }
+  if (points.length < 2) {
+    return { sessions: points.length, clusters: [] };
+  }
+
+  const k = Math.min(points.length, options.clusters ?? defaultClusterCount(points.length));
+  const { assignments, centroids } = kMeans(points, k);
+
+  const clusters: TopicCluster[] = [];
+  centroids.forEach((centroid, index) => {
+    const members = sessions
+      .map((session, i) => ({ session, point: points[i] ?? [], cluster: assignments[i] }))
+      .filter((member) => member.cluster === index)
+      .map((member) => ({
+        ...member.session,
+        similarity: cosineSimilarity(member.point, centroid),
+      }));
+    if (members.length === 0) {
+      return;
+    }
+    const projectCounts = new Map<string, number>();
+    for (const member of members) {
+      projectCounts.set(member.working_dir, (projectCounts.get(member.working_dir) ?? 0) + 1);
+    }
+    clusters.push({
+      size: members.length,
+      cohesion: members.reduce((sum, member) => sum + member.similarity, 0) / members.length,
+      projects: [...projectCounts.entries()]
+        .map(([working_dir, count]) => ({ working_dir, sessions: count }))
+        .sort((a, b) => b.sessions - a.sessions),
+      examples: members.sort((a, b) => b.similarity - a.similarity).slice(0, examples),
+    });
+  });
+  clusters.sort((a, b) => b.size - a.size);
+  return { sessions: points.length, clusters };
+}
+
+function parsePositiveInt(value: string | undefined): number | null | undefined {
+  if (value === undefined) {
+    return undefined;
+  }
+  const parsed = Number(value);
+  return Number.isInteger(parsed) && parsed > 0 ? parsed : null;
+}
+
+export function registerTopicRoutes(app: Hono): void {
+  app.get("/topics", async (c) => {
+    const days = parsePositiveInt(c.req.query("days"));
+    const clusters = parsePositiveInt(c.req.query("clusters"));
+    const examples = parsePositiveInt(c.req.query("examples"));
+    if (days === null || clusters === null || examples === null) {
+      return c.json({ error: "days, clusters, and examples must be positive integers" }, 400);
+    }
+    try {
+      const result = await clusterSessionTopics({
+        ...(days !== undefined ? { days } : {}),
+        ...(clusters !== undefined ? { clusters: Math.min(clusters, MAX_CLUSTERS) } : {}),
+        ...(examples !== undefined ? { examples } : {}),
+      });
+      return c.json({ days: days ?? DEFAULT_TOPIC_DAYS, ...result });
+    } catch (error) {
+      log.session.warn("Topic clustering failed", { error: String(error) });
+      return c.json({ error: String(error) }, 500);
+    }
+  });
+}
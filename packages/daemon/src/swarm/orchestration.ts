@@ -46,7 +46,7 @@ export async function cleanupOrphanedSwarms(): Promise<void> {
 
   for (const swarm of orphanedSwarms) {
     try {
-      log.swarm.info("Cleaning up orphaned swarm", { swarmId: swarm.id, name: swarm.name });
+      log.swarm.debug("Cleaning up orphaned swarm", { swarmId: swarm.id, name: swarm.name });
 
       // Use transaction to ensure all cleanup operations succeed or fail together
       await db.transaction().execute(async (trx) => {
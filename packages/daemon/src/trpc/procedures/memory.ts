@@ -3,7 +3,13 @@ import { TRPCError } from "@trpc/server";
 import { sql } from "kysely";
 import { router, publicProcedure } from "../init.js";
 import { getDb } from "../../db.js";
-import { getRecallEmbedder, vectorLiteral } from "../../memory/embeddings.js";
+import {
+  getRecallEmbedder,
+  loadVectorMetric,
+  vectorDistance,
+  vectorLiteral,
+  vectorScore,
+} from "../../memory/embeddings.js";
 import { log } from "../../logger.js";
 
 const CORE_MEMORY_BLOCK_TYPES = new Set(["persona", "human", "task"]);
@@ -690,6 +696,7 @@ export const recallRouter = router({
         try {
           const queryEmbedding = await embedder.create(query.replace(/\n/g, " "));
           const vector = vectorLiteral(queryEmbedding);
+          const metric = await loadVectorMetric();
 
           let vectorQuery = db
             .selectFrom("conversation_blocks as cb")
@@ -703,14 +710,15 @@ export const recallRouter = router({
               "c.timestamp as timestamp",
               "c.medium as medium",
               "c.user_id as user_id",
-              sql<number>`1 - (cb.content_embedding <=> ${vector}::vector)`.as("score"),
+              vectorScore(metric, vector).as("score"),
             ])
             .where("cb.block_type", "=", "text")
             .where("cb.text", "is not", null)
             .where(sql<boolean>`cb.text <> ''`)
             .where("c.message_type", "in", ["user", "assistant", "system"])
             .where("cb.content_embedding", "is not", null)
-            .orderBy(sql`cb.content_embedding <=> ${vector}::vector`)
+            .where("cb.embedding_model", "=", embedder.model)
+            .orderBy(vectorDistance(metric, vector))
             .limit(limit * 2);
 
           if (sessionId !== undefined) {
@@ -5,6 +5,7 @@ import { router, publicProcedure } from "../init.js";
 import { log } from "../../logger.js";
 import { generateShortSummary } from "../../utils/summary.js";
 import { insertConversation } from "../../utils/conversations.js";
+import { normalizeWorkingDir } from "../../utils/working-dir.js";
 
 const SUMMARY_WINDOW_SECONDS = 1800;
 const SUMMARY_LIMIT = 50;
@@ -48,11 +49,12 @@ export const sessionsRouter = router({
     )
     .mutation(async ({ input }) => {
       const db = await getDb();
+      const workingDir = await normalizeWorkingDir(input.working_dir);
       const now = nowDate();
       const inserted = await db
         .insertInto("sessions")
         .values({
-          working_dir: input.working_dir,
+          working_dir: workingDir,
           start_time: nowSeconds(),
           personality: input.personality ?? null,
           medium: input.medium ?? "cli",
@@ -91,10 +93,11 @@ export const sessionsRouter = router({
     )
     .mutation(async ({ input }) => {
       const db = await getDb();
+      const workingDir = await normalizeWorkingDir(input.working_dir);
       let query = db
         .selectFrom("sessions")
         .select(["id", "claude_session_id", "start_time"])
-        .where("working_dir", "=", input.working_dir)
+        .where("working_dir", "=", workingDir)
         .orderBy("start_time", "desc");
 
       if (input.max_age_hours !== null && input.max_age_hours !== undefined) {
@@ -115,7 +118,7 @@ export const sessionsRouter = router({
       const inserted = await db
         .insertInto("sessions")
         .values({
-          working_dir: input.working_dir,
+          working_dir: workingDir,
           start_time: nowSeconds(),
           continued_from: null,
           personality: input.personality ?? null,
@@ -53,6 +53,10 @@ export interface InsertConversationOptions {
   metrics?: ConversationMetrics;
   toolUses?: number | null;
   toolNames?: string[] | null;
+  /** Dedup key; a second insert with the same hash in the session fails */
+  contentHash?: string | null;
+  /** When the message was sent, in seconds (default: now); set by imports */
+  timestamp?: number;
   /** If true, updates session.last_activity (default: true) */
   updateLastActivity?: boolean;
   /** Optional transaction context - if not provided, uses getDb() */
@@ -80,13 +84,14 @@ export async function insertConversation(
     metrics = {},
     toolUses = null,
     toolNames = null,
+    contentHash = null,
+    timestamp = nowSeconds(),
     updateLastActivity = true,
     trx,
   } = options;
 
   const db = trx ?? (await getDb());
   const now = nowDate();
-  const timestamp = nowSeconds();
 
   const conversation = await db
     .insertInto("conversations")
@@ -103,6 +108,7 @@ export async function insertConversation(
       thinking_ms: metrics.thinkingMs ?? null,
       tool_uses: toolUses,
       tool_names: toolNames,
+      content_hash: contentHash,
       created_at: now,
     })
     .returning(["id"])
@@ -8,6 +8,7 @@
  * - swarm/agent-query.ts
  */
 
+import { loadConfig, loadPromptTemplate } from "@dere/shared-config";
 import { ClaudeAgentTransport, TextResponseClient } from "@dere/shared-llm";
 
 import { log } from "../logger.js";
@@ -48,6 +49,220 @@ function getClient(model?: string): TextResponseClient {
   return client;
 }
 
+// Transcript lines are formatted as `${message_type}: ${prompt}`.
+const TURN_START_RE = /^(?:user|assistant|system|command): /im;
+const TURN_SPLIT_RE = /\n(?=(?:user|assistant|system|command): )/i;
+
+function splitLongText(text: string, maxChars: number): string[] {
+  const pieces: string[] = [];
+  let remaining = text;
+  while (remaining.length > maxChars) {
+    const window = remaining.slice(0, maxChars);
+    let cut = window.lastIndexOf("\n\n");
+    if (cut <= 0) {
+      cut = window.lastIndexOf("\n");
+    }
+    if (cut <= 0) {
+      cut = window.lastIndexOf(" ");
+    }
+    if (cut <= 0) {
+      cut = maxChars;
+    }
+    pieces.push(remaining.slice(0, cut).trimEnd());
+    remaining = remaining.slice(cut).trimStart();
+  }
+  if (remaining) {
+    pieces.push(remaining);
+  }
+  return pieces;
+}
+
+/**
+ * Split a transcript into segments of at most `maxChars`, breaking between
+ * turns so a response never gets separated from its prompt. A single turn
+ * longer than the cap is split on paragraph, line, then word boundaries.
+ */
+export function segmentTranscript(text: string, maxChars: number): string[] {
+  const turns = TURN_START_RE.test(text) ? text.split(TURN_SPLIT_RE) : [text];
+  const segments: string[] = [];
+  let current = "";
+
+  for (const turn of turns) {
+    const pieces = turn.length > maxChars ? splitLongText(turn, maxChars) : [turn];
+    for (const piece of pieces) {
+      if (current && current.length + 1 + piece.length > maxChars) {
+        segments.push(current);
+        current = "";
+      }
+      current = current ? `${current}\n${piece}` : piece;
+    }
+  }
+  if (current) {
+    segments.push(current);
+  }
+  return segments;
+}
+
+// Fenced blocks, including one left open at the end of a cut-off reply.
+const CODE_FENCE_RE = /^[ \t]*```([\w+#.-]*)[^\n]*\n([\s\S]*?)(?:^[ \t]*```[ \t]*$|(?![\s\S]))/gm;
+// Names declared in a block: functions, types, and assigned bindings.
+const CODE_NAME_RE = new RegExp(
+  String.raw`\b(?:function|def|fn|func|class|interface|type|struct|enum|` +
+    String.raw`(?:const|let|var)(?=\s+\w+\s*=))\s+([A-Za-z_$][\w$]*)`,
+  "g",
+);
+const MAX_CODE_NAMES = 3;
+
+/**
+ * Replace fenced code blocks with a one-line placeholder naming the language,
+ * size, and the first few things defined, e.g. `[code: ts, 42 lines; foo, Bar]`.
+ * The code is already on disk; what a summary needs is that it was written.
+ */
+export function compressCodeBlocks(text: string): string {
+  return text.replace(CODE_FENCE_RE, (_match, lang: string, body: string) => {
+    const lines = body.split("\n").filter((line) => line.trim()).length;
+    const names = new Set<string>();
+    for (const match of body.matchAll(CODE_NAME_RE)) {
+      if (names.size >= MAX_CODE_NAMES) {
+        break;
+      }
+      names.add(match[1] ?? "");
+    }
+    const size = `${lines} line${lines === 1 ? "" : "s"}`;
+    const label = lang ? `${lang}, ${size}` : size;
+    return names.size > 0 ? `[code: ${label}; ${[...names].join(", ")}]` : `[code: ${label}]`;
+  });
+}
+
+/**
+ * Longest an assistant turn may run in a summarization transcript (chars).
+ * User turns are kept whole; they carry the intent a summary should follow.
+ */
+const MAX_ASSISTANT_TURN_CHARS = 1500;
+
+/**
+ * Weight a turn for summarization: assistant replies have code blocks
+ * collapsed and are cut to their opening, user turns pass through unchanged.
+ */
+export function condenseTurn(messageType: string, prompt: string): string {
+  if (messageType !== "assistant") {
+    return prompt;
+  }
+  const condensed = compressCodeBlocks(prompt);
+  if (condensed.length <= MAX_ASSISTANT_TURN_CHARS) {
+    return condensed;
+  }
+  return `${condensed.slice(0, MAX_ASSISTANT_TURN_CHARS).trimEnd()} [...]`;
+}
+
+/** Upper bound on transcript text assembled for summarization (chars) */
+export const MAX_TRANSCRIPT_CHARS = 20_000;
+
+const TRUNCATION_MARKER = "[earlier messages omitted]";
+
+/**
+ * Assemble a transcript from conversation rows ordered newest first, keeping
+ * the most recent turns that fit in `maxChars`. Turns go through condenseTurn
+ * first, so long assistant code dumps don't crowd out the user's prompts. A
+ * turn that alone exceeds the budget is cut to its tail. When anything is
+ * dropped the transcript starts with a marker so the summarizer knows it's
+ * seeing the end of the session.
+ */
+export function buildRecentTranscript(
+  rowsNewestFirst: Array<{ message_type: string; prompt: string }>,
+  maxChars: number = MAX_TRANSCRIPT_CHARS,
+): { text: string; truncated: boolean } {
+  const lines: string[] = [];
+  let used = 0;
+  let truncated = false;
+
+  for (const row of rowsNewestFirst) {
+    const prompt = condenseTurn(row.message_type, row.prompt);
+    const line = `${row.message_type}: ${prompt}`;
+    const remaining = maxChars - used - (lines.length > 0 ? 1 : 0);
+    if (line.length <= remaining) {
+      lines.push(line);
+      used += line.length + (lines.length > 1 ? 1 : 0);
+      continue;
+    }
+    truncated = true;
+    if (lines.length === 0) {
+      lines.push(`${row.message_type}: ...${prompt.slice(-Math.max(0, maxChars - 20))}`);
+    }
+    break;
+  }
+
+  const text = lines.reverse().join("\n");
+  return { text: truncated ? `${TRUNCATION_MARKER}\n${text}` : text, truncated };
+}
+
+/** What a summary is for; each kind has its own word budget. */
+export type SummaryKind = "session" | "output" | "context";
+
+const DEFAULT_SUMMARY_MAX_WORDS: Record<SummaryKind, number> = {
+  session: 60,
+  output: 60,
+  context: 80,
+};
+
+const SUMMARY_MAX_WORDS_KEYS: Record<SummaryKind, string> = {
+  session: "summary_max_words",
+  output: "summary_max_words",
+  context: "summary_context_max_words",
+};
+
+/** Slack before a summary counts as over budget and gets trimmed. */
+const WORD_LIMIT_TOLERANCE = 1.2;
+
+/**
+ * Read the word budget for a summary kind from [context].summary_max_words
+ * (session and output) or [context].summary_context_max_words.
+ */
+export async function loadSummaryMaxWords(kind: SummaryKind): Promise<number> {
+  try {
+    const config = await loadConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    const value = Number(contextConfig[SUMMARY_MAX_WORDS_KEYS[kind]]);
+    if (Number.isFinite(value) && value > 0) {
+      return Math.floor(value);
+    }
+    return DEFAULT_SUMMARY_MAX_WORDS[kind];
+  } catch {
+    return DEFAULT_SUMMARY_MAX_WORDS[kind];
+  }
+}
+
+function countWords(text: string): number {
+  return text.split(/\s+/).filter(Boolean).length;
+}
+
+/**
+ * Models routinely overshoot a requested length. If `summary` runs well past
+ * `maxWords`, keep whole sentences up to the limit; a first sentence that is
+ * already too long is cut at the limit with an ellipsis.
+ */
+export function enforceWordLimit(summary: string, maxWords: number): string {
+  if (countWords(summary) <= maxWords * WORD_LIMIT_TOLERANCE) {
+    return summary;
+  }
+
+  const sentences = summary.match(/[^.!?]+(?:[.!?]+["')\]]*|$)/g) ?? [summary];
+  let kept = "";
+  let words = 0;
+  for (const sentence of sentences) {
+    const sentenceWords = countWords(sentence);
+    if (words + sentenceWords > maxWords) {
+      break;
+    }
+    kept += sentence;
+    words += sentenceWords;
+  }
+  if (kept.trim()) {
+    return kept.trim();
+  }
+  return `${summary.split(/\s+/).filter(Boolean).slice(0, maxWords).join(" ")}...`;
+}
+
 export interface GenerateSummaryOptions {
   /** Override the default model */
   model?: string;
@@ -57,6 +272,10 @@ export interface GenerateSummaryOptions {
   skipThresholdCheck?: boolean;
   /** Logger category for warnings (default: "summary") */
   logCategory?: "swarm" | "mission" | "session" | "summary";
+  /** Which word budget applies (default: "output") */
+  kind?: SummaryKind;
+  /** Prompt template a user can override in prompts/templates/ (default: "output-summary") */
+  template?: string;
 }
 
 /**
@@ -65,8 +284,10 @@ export interface GenerateSummaryOptions {
  * Features:
  * - Skips if text is below SUMMARY_THRESHOLD (unless skipThresholdCheck)
  * - Respects DERE_DISABLE_SUMMARY env var
- * - Smart truncation: if text > 4000 chars, uses first/last 2000 with [...] separator
+ * - Smart truncation: if text > 4000 chars, uses first/last ~2000-char segments
+ *   (split on turn boundaries) with [...] separator
  * - Configurable model via options or env vars
+ * - Trims summaries that overshoot the word budget for their kind
  *
  * @param text - The text to summarize
  * @param options - Optional configuration
@@ -94,26 +315,32 @@ export async function generateSummary(
     process.env.DERE_MISSION_SUMMARY_MODEL ??
     SUMMARY_MODEL;
 
-  // Smart context truncation
-  const context =
-    text.length > MAX_CONTEXT * 2
-      ? `${text.slice(0, MAX_CONTEXT)}\n\n[...]\n\n${text.slice(-MAX_CONTEXT)}`
-      : text.slice(0, MAX_CONTEXT * 2);
+  // Smart context truncation: keep the first and last segments whole
+  let context = text;
+  if (text.length > MAX_CONTEXT * 2) {
+    const segments = segmentTranscript(text, MAX_CONTEXT);
+    context = `${segments[0] ?? ""}\n\n[...]\n\n${segments[segments.length - 1] ?? ""}`;
+  }
 
+  const maxWords = await loadSummaryMaxWords(options.kind ?? "output");
   const promptPrefix =
     options.promptPrefix ?? "Summarize this output in 1-2 sentences. Focus on the main result or outcome.";
 
-  const prompt = `${promptPrefix}
+  const prompt = await loadPromptTemplate(
+    options.template ?? "output-summary",
+    `${promptPrefix} Use at most {{max_words}} words.
 
 Output:
-${context}
+{{content}}
 
-Summary:`;
+Summary:`,
+    { content: context, max_words: maxWords },
+  );
 
   try {
     const client = getClient(model);
-    const summary = await client.generate(prompt);
-    return summary.trim() || null;
+    const summary = (await client.generate(prompt)).trim();
+    return summary ? enforceWordLimit(summary, maxWords) : null;
   } catch (error) {
     const category = options.logCategory ?? "summary";
     const logger = log[category as keyof typeof log] ?? log.summary;
@@ -130,6 +357,8 @@ Summary:`;
 export async function generateShortSummary(text: string): Promise<string | null> {
   return generateSummary(text, {
     skipThresholdCheck: true,
+    kind: "session",
+    template: "session-summary",
     promptPrefix: "Summarize in 1-2 sentences. No headers or preambles, just the summary.",
   });
 }
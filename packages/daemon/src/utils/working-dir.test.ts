@@ -0,0 +1,89 @@
+import { mkdirSync, mkdtempSync, realpathSync, rmSync, symlinkSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+
+import { afterEach, beforeEach, describe, expect, it } from "bun:test";
+
+import { normalizeWorkingDir } from "./working-dir.js";
+
+let root: string;
+let previousXdg: string | undefined;
+
+function writeConfig(toml: string): void {
+  mkdirSync(join(root, "xdg", "dere"), { recursive: true });
+  writeFileSync(join(root, "xdg", "dere", "config.toml"), toml);
+}
+
+beforeEach(() => {
+  // realpath the temp root so macOS's /var -> /private/var doesn't leak into expectations
+  root = realpathSync(mkdtempSync(join(tmpdir(), "dere-working-dir-")));
+  previousXdg = process.env.XDG_CONFIG_HOME;
+  process.env.XDG_CONFIG_HOME = join(root, "xdg");
+});
+
+afterEach(() => {
+  if (previousXdg === undefined) {
+    delete process.env.XDG_CONFIG_HOME;
+  } else {
+    process.env.XDG_CONFIG_HOME = previousXdg;
+  }
+  rmSync(root, { recursive: true, force: true });
+});
+
+describe("normalizeWorkingDir", () => {
+  it("drops trailing slashes and dot segments", async () => {
+    const project = join(root, "project");
+    mkdirSync(project);
+    expect(await normalizeWorkingDir(`${project}/`)).toBe(project);
+    expect(await normalizeWorkingDir(`${project}/./src/..`)).toBe(project);
+    expect(await normalizeWorkingDir(`  ${project}  `)).toBe(project);
+  });
+
+  it("resolves symlinks to the real directory", async () => {
+    const project = join(root, "project");
+    mkdirSync(project);
+    symlinkSync(project, join(root, "link"));
+    expect(await normalizeWorkingDir(join(root, "link"))).toBe(project);
+  });
+
+  it("only tidies paths that don't exist here", async () => {
+    const missing = join(root, "elsewhere", "project");
+    expect(await normalizeWorkingDir(`${missing}/`)).toBe(missing);
+    expect(await normalizeWorkingDir("   ")).toBe("");
+  });
+
+  it("keeps subdirectories as-is unless grouping by git root", async () => {
+    const repo = join(root, "repo");
+    mkdirSync(join(repo, ".git"), { recursive: true });
+    mkdirSync(join(repo, "packages", "app"), { recursive: true });
+    expect(await normalizeWorkingDir(join(repo, "packages", "app"))).toBe(
+      join(repo, "packages", "app"),
+    );
+  });
+
+  it("maps subdirectories to the repository root with group_by_git_root", async () => {
+    writeConfig("[context]\ngroup_by_git_root = true\n");
+    const repo = join(root, "repo");
+    mkdirSync(join(repo, ".git"), { recursive: true });
+    mkdirSync(join(repo, "packages", "app"), { recursive: true });
+    expect(await normalizeWorkingDir(join(repo, "packages", "app"))).toBe(repo);
+    expect(await normalizeWorkingDir(repo)).toBe(repo);
+  });
+
+  it("keeps each worktree as its own root", async () => {
+    writeConfig("[context]\ngroup_by_git_root = true\n");
+    const repo = join(root, "repo");
+    const worktree = join(repo, "worktrees", "feature");
+    mkdirSync(join(repo, ".git"), { recursive: true });
+    mkdirSync(join(worktree, "src"), { recursive: true });
+    writeFileSync(join(worktree, ".git"), `gitdir: ${repo}/.git/worktrees/feature\n`);
+    expect(await normalizeWorkingDir(join(worktree, "src"))).toBe(worktree);
+  });
+
+  it("leaves directories outside any repository alone", async () => {
+    writeConfig("[context]\ngroup_by_git_root = true\n");
+    const plain = join(root, "plain", "dir");
+    mkdirSync(plain, { recursive: true });
+    expect(await normalizeWorkingDir(plain)).toBe(plain);
+  });
+});
@@ -0,0 +1,74 @@
+/**
+ * Canonical working directories, so one project's sessions group together in
+ * stats, search, and continuation regardless of how its path was spelled.
+ */
+
+import { existsSync } from "node:fs";
+import { realpath } from "node:fs/promises";
+import { dirname, join, resolve } from "node:path";
+
+import { captureExcludePaths, isCaptureExcluded, loadConfig } from "@dere/shared-config";
+
+async function groupByGitRoot(): Promise<boolean> {
+  try {
+    const config = await loadConfig();
+    const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+    return contextConfig.group_by_git_root === true;
+  } catch {
+    return false;
+  }
+}
+
+// Nearest ancestor with a .git entry; a worktree's .git file counts, so each
+// worktree stays its own project.
+function findGitRoot(dir: string): string | null {
+  let current = dir;
+  while (true) {
+    if (existsSync(join(current, ".git"))) {
+      return current;
+    }
+    const parent = dirname(current);
+    if (parent === current) {
+      return null;
+    }
+    current = parent;
+  }
+}
+
+/**
+ * Resolve symlinks and trailing slashes, and with
+ * [context].group_by_git_root map subdirectories to their repository root.
+ * Paths that don't exist on this machine are only tidied, not resolved.
+ */
+export async function normalizeWorkingDir(path: string): Promise<string> {
+  const trimmed = path.trim();
+  if (!trimmed) {
+    return trimmed;
+  }
+  let normalized = resolve(trimmed);
+  try {
+    normalized = await realpath(normalized);
+  } catch {
+    return normalized;
+  }
+  if (await groupByGitRoot()) {
+    return findGitRoot(normalized) ?? normalized;
+  }
+  return normalized;
+}
+
+/**
+ * Whether a project is under [capture].exclude_paths. That setting is a hard
+ * privacy line: nothing from those directories reaches the database, whichever
+ * client sent it.
+ */
+export async function isExcludedWorkingDir(path: string): Promise<boolean> {
+  if (!path.trim()) {
+    return false;
+  }
+  try {
+    return isCaptureExcluded(path, captureExcludePaths(await loadConfig()));
+  } catch {
+    return false;
+  }
+}
@@ -2,6 +2,9 @@ import { createClient } from "redis";
 import { loadConfig } from "@dere/shared-config";
 import { ClaudeAgentTransport, TextResponseClient } from "@dere/shared-llm";
 
+import { createEmbedder } from "./graph-embedder.js";
+import { sanitizeLabel } from "./graph-vocabulary.js";
+
 type GraphRecord = Record<string, unknown>;
 type GraphRedisClient = ReturnType<typeof createClient>;
 
@@ -681,7 +684,8 @@ export async function mergeDuplicateEntities(groupId: string, limit: number): Pr
                n.citation_count AS citation_count,
                n.retrieval_quality AS retrieval_quality,
                n.last_mentioned AS last_mentioned,
-               n.created_at AS created_at
+               n.created_at AS created_at,
+               n.verified AS verified
       `,
       { uuids },
     );
@@ -691,6 +695,12 @@ export async function mergeDuplicateEntities(groupId: string, limit: number): Pr
     }
 
     nodes.sort((a, b) => {
+      // A human-verified entity always survives the merge.
+      const verifiedA = a.verified === true ? 1 : 0;
+      const verifiedB = b.verified === true ? 1 : 0;
+      if (verifiedA !== verifiedB) {
+        return verifiedB - verifiedA;
+      }
       const mentionA = Number(a.mention_count ?? 0);
       const mentionB = Number(b.mention_count ?? 0);
       if (mentionA !== mentionB) {
@@ -797,6 +807,293 @@ export async function mergeDuplicateEntities(groupId: string, limit: number): Pr
   return merged;
 }
 
+export interface LinkCoOccurringEntitiesOptions {
+  groupId: string;
+  /** Minimum number of distinct conversations a pair must share. Default: 3. */
+  minCount?: number;
+  /** Maximum number of new edges to create per run. Default: 100. */
+  limit?: number;
+}
+
+/**
+ * Link entities that keep showing up in the same conversations with a
+ * RELATED_TO edge. Strength is the share of the rarer entity's conversations
+ * that also mention the other one. Pairs already connected are left alone.
+ */
+export async function linkCoOccurringEntities(
+  options: LinkCoOccurringEntitiesOptions,
+): Promise<number> {
+  const client = await getGraphClient();
+  if (!client) {
+    return 0;
+  }
+
+  const { groupId, minCount = 3, limit = 100 } = options;
+
+  const records = await client.query(
+    `
+      MATCH (e:Episodic)-[:MENTIONS]->(a:Entity {group_id: $group_id}),
+            (e)-[:MENTIONS]->(b:Entity {group_id: $group_id})
+      WHERE a.uuid < b.uuid
+      WITH a, b,
+           count(DISTINCT e.conversation_id) AS together,
+           collect(DISTINCT e.uuid) AS episodes
+      WHERE together >= $min_count
+      OPTIONAL MATCH (a)-[existing:RELATES_TO]-(b)
+      WITH a, b, together, episodes, count(existing) AS existing_count
+      WHERE existing_count = 0
+      MATCH (ea:Episodic)-[:MENTIONS]->(a)
+      WITH a, b, together, episodes, count(DISTINCT ea.conversation_id) AS a_total
+      MATCH (eb:Episodic)-[:MENTIONS]->(b)
+      WITH a, b, together, episodes, a_total, count(DISTINCT eb.conversation_id) AS b_total
+      RETURN a.uuid AS source_uuid,
+             a.name AS source_name,
+             b.uuid AS target_uuid,
+             b.name AS target_name,
+             together,
+             episodes,
+             a_total,
+             b_total
+      ORDER BY together DESC
+      LIMIT $limit
+    `,
+    { group_id: groupId, min_count: minCount, limit },
+  );
+
+  const now = new Date();
+  let created = 0;
+
+  for (const record of records) {
+    const sourceUuid = typeof record.source_uuid === "string" ? record.source_uuid : "";
+    const targetUuid = typeof record.target_uuid === "string" ? record.target_uuid : "";
+    if (!sourceUuid || !targetUuid) {
+      continue;
+    }
+    const sourceName = String(record.source_name ?? "");
+    const targetName = String(record.target_name ?? "");
+    const together = Number(record.together ?? 0);
+    const rarer = Math.min(Number(record.a_total ?? 0), Number(record.b_total ?? 0));
+    const strength = rarer > 0 ? Math.min(1, together / rarer) : 0;
+    const episodes = Array.isArray(record.episodes)
+      ? (record.episodes as unknown[]).filter((item): item is string => typeof item === "string")
+      : [];
+
+    await client.query(
+      `
+        MATCH (source:Entity {uuid: $source_uuid})
+        MATCH (target:Entity {uuid: $target_uuid})
+        CREATE (source)-[:RELATES_TO {
+          uuid: $uuid,
+          name: 'RELATED_TO',
+          fact: $fact,
+          episodes: $episodes,
+          strength: $strength,
+          co_occurrence_count: $together,
+          origin: 'co_occurrence',
+          group_id: $group_id,
+          created_at: $now,
+          valid_at: $now
+        }]->(target)
+      `,
+      {
+        source_uuid: sourceUuid,
+        target_uuid: targetUuid,
+        uuid: crypto.randomUUID(),
+        fact: `${sourceName} and ${targetName} are often discussed together`,
+        episodes: episodes.slice(0, 20),
+        strength,
+        together,
+        group_id: groupId,
+        now,
+      },
+    );
+    created += 1;
+  }
+
+  return created;
+}
+
+export interface EntityCorrection {
+  /** Replaces the entity's type label; the Entity label is always kept. */
+  type?: string;
+  /** New display name; the old name is kept as an alias. */
+  name?: string;
+}
+
+/** Correct a mis-extracted entity by uuid. Returns false if it doesn't exist. */
+export async function correctEntity(uuid: string, correction: EntityCorrection): Promise<boolean> {
+  const client = await getGraphClient();
+  if (!client) {
+    return false;
+  }
+
+  const records = await client.query(
+    `
+      MATCH (n:Entity {uuid: $uuid})
+      RETURN n.name AS name, n.aliases AS aliases, labels(n) AS labels
+    `,
+    { uuid },
+  );
+  const record = records[0];
+  if (!record) {
+    return false;
+  }
+
+  if (correction.type) {
+    // Labels can't be parameterized, so sanitize them like saveEntityNode does.
+    // Existing labels are quoted as stored, since older ones may not be valid
+    // bare identifiers.
+    const label = sanitizeLabel(correction.type);
+    const existing = (Array.isArray(record.labels) ? record.labels : [])
+      .map(String)
+      .filter((item) => item !== "Entity" && item !== label)
+      .map((item) => `\`${item.replace(/`/g, "``")}\``);
+    const removeClause = existing.length > 0 ? `REMOVE n:${existing.join(":")}` : "";
+    await client.query(
+      `
+        MATCH (n:Entity {uuid: $uuid})
+        ${removeClause}
+        SET n:${label}
+      `,
+      { uuid },
+    );
+  }
+
+  const oldName = typeof record.name === "string" ? record.name : "";
+  if (correction.name && correction.name !== oldName) {
+    const aliases = new Set(Array.isArray(record.aliases) ? record.aliases.map(String) : []);
+    if (oldName) {
+      aliases.add(oldName);
+    }
+    aliases.delete(correction.name);
+    // Re-embed the name so vector search finds the entity under it.
+    const embedder = await createEmbedder();
+    const nameEmbedding = await embedder.create(correction.name.replace(/\n/g, " "));
+    await client.query(
+      `
+        MATCH (n:Entity {uuid: $uuid})
+        SET n.name = $name, n.aliases = $aliases, n.name_embedding = vecf32($name_embedding)
+      `,
+      {
+        uuid,
+        name: correction.name,
+        aliases: Array.from(aliases),
+        name_embedding: nameEmbedding,
+      },
+    );
+  }
+
+  return true;
+}
+
+/**
+ * Delete an entity and every relationship touching it. Returns false if it
+ * doesn't exist.
+ */
+export async function deleteEntity(uuid: string): Promise<boolean> {
+  const client = await getGraphClient();
+  if (!client) {
+    return false;
+  }
+  const records = await client.query(
+    `
+      MATCH (n:Entity {uuid: $uuid})
+      DETACH DELETE n
+      RETURN count(n) AS deleted
+    `,
+    { uuid },
+  );
+  return Number(records[0]?.deleted ?? 0) > 0;
+}
+
+/**
+ * Mark an entity as human-verified. Dedup keeps verified entities as the
+ * merge target. Returns false if it doesn't exist.
+ */
+export async function verifyEntity(uuid: string): Promise<boolean> {
+  const client = await getGraphClient();
+  if (!client) {
+    return false;
+  }
+  const records = await client.query(
+    `
+      MATCH (n:Entity {uuid: $uuid})
+      SET n.verified = true, n.verified_at = $now
+      RETURN n.uuid AS uuid
+    `,
+    { uuid, now: new Date() },
+  );
+  return records.length > 0;
+}
+
+/** Number of episodes ingested from one dere session. */
+export async function countSessionEpisodes(groupId: string, sessionId: number): Promise<number> {
+  const client = await getGraphClient();
+  if (!client) {
+    return 0;
+  }
+  const records = await client.query(
+    `
+      MATCH (e:Episodic {group_id: $group_id, session_id: $session_id})
+      RETURN count(e) AS count
+    `,
+    { group_id: groupId, session_id: sessionId },
+  );
+  return Number(records[0]?.count ?? 0);
+}
+
+/**
+ * Delete the episodes ingested from one dere session, plus any entity they
+ * mentioned that no other episode mentions (verified entities are kept).
+ * Episodes stored before episodes carried a session id are not matched.
+ * Returns the number of episodes and entities removed.
+ */
+export async function deleteSessionEpisodes(
+  groupId: string,
+  sessionId: number,
+): Promise<{ episodes: number; entities: number }> {
+  const client = await getGraphClient();
+  if (!client) {
+    return { episodes: 0, entities: 0 };
+  }
+
+  const mentioned = await client.query(
+    `
+      MATCH (e:Episodic {group_id: $group_id, session_id: $session_id})
+      OPTIONAL MATCH (e)-[:MENTIONS]->(n:Entity)
+      RETURN collect(DISTINCT n.uuid) AS uuids
+    `,
+    { group_id: groupId, session_id: sessionId },
+  );
+  const entityUuids = (mentioned[0]?.uuids as string[] | undefined) ?? [];
+
+  const deletedEpisodes = await client.query(
+    `
+      MATCH (e:Episodic {group_id: $group_id, session_id: $session_id})
+      DETACH DELETE e
+      RETURN count(e) AS deleted
+    `,
+    { group_id: groupId, session_id: sessionId },
+  );
+
+  let entities = 0;
+  if (entityUuids.length > 0) {
+    const deletedEntities = await client.query(
+      `
+        MATCH (n:Entity)
+        WHERE n.uuid IN $uuids AND coalesce(n.verified, false) = false
+          AND NOT ()-[:MENTIONS]->(n)
+        DETACH DELETE n
+        RETURN count(n) AS deleted
+      `,
+      { uuids: entityUuids },
+    );
+    entities = Number(deletedEntities[0]?.deleted ?? 0);
+  }
+
+  return { episodes: Number(deletedEpisodes[0]?.deleted ?? 0), entities };
+}
+
 export async function buildCommunities(_groupId?: string, _resolution?: number): Promise<number> {
   const groupId = _groupId ?? "default";
   const resolution = typeof _resolution === "number" ? _resolution : 1.0;
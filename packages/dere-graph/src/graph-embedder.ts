@@ -34,15 +34,63 @@ function averageEmbeddings(embeddings: number[][]): number[] {
   return avg.map((value) => value / embeddings.length);
 }
 
-export class OpenAIEmbedder {
+/**
+ * Text embedding backend. The graph, recall, and fact-checking paths depend on
+ * this rather than a concrete client, so alternative backends (or a fake in
+ * tests) can be dropped in via createEmbedder().
+ */
+export interface Embedder {
+  /** Dimensionality of the vectors this embedder returns. */
+  readonly dim: number;
+  /** Model the vectors come from; vectors from different models aren't comparable. */
+  readonly model: string;
+  create(text: string): Promise<number[]>;
+  createBatch(texts: string[]): Promise<number[][]>;
+}
+
+export const DEFAULT_OPENAI_EMBEDDING_MODEL = "text-embedding-3-small";
+
+// Embedding calls normally return in well under a second; a request still
+// open after this is treated as hung.
+const DEFAULT_EMBEDDING_TIMEOUT_MS = 30_000;
+const EMBEDDING_MAX_ATTEMPTS = 3;
+const EMBEDDING_RETRY_BASE_MS = 1000;
+// Never wait longer than this, whatever the server asks for.
+const EMBEDDING_MAX_RETRY_DELAY_MS = 30_000;
+
+/** Delay requested by a Retry-After header (seconds or HTTP date), in ms. */
+function parseRetryAfter(header: string | null): number | null {
+  if (!header) {
+    return null;
+  }
+  const seconds = Number(header);
+  if (Number.isFinite(seconds) && seconds >= 0) {
+    return seconds * 1000;
+  }
+  const date = Date.parse(header);
+  return Number.isNaN(date) ? null : Math.max(0, date - Date.now());
+}
+
+export class OpenAIEmbedder implements Embedder {
   private readonly apiKey: string;
-  private readonly model: string;
+  readonly model: string;
   private readonly embeddingDim: number;
+  private readonly timeoutMs: number;
 
-  constructor(apiKey: string, model: string, embeddingDim: number) {
+  constructor(
+    apiKey: string,
+    model: string,
+    embeddingDim: number,
+    timeoutMs: number = DEFAULT_EMBEDDING_TIMEOUT_MS,
+  ) {
     this.apiKey = apiKey;
     this.model = model;
     this.embeddingDim = embeddingDim;
+    this.timeoutMs = timeoutMs;
+  }
+
+  get dim(): number {
+    return this.embeddingDim;
   }
 
   static async fromConfig(): Promise<OpenAIEmbedder> {
@@ -54,27 +102,70 @@ export class OpenAIEmbedder {
     const graphConfig = (config.dere_graph ?? {}) as Record<string, unknown>;
     const embeddingDim =
       typeof graphConfig.embedding_dim === "number" ? graphConfig.embedding_dim : 1536;
-    return new OpenAIEmbedder(apiKey, "text-embedding-3-small", embeddingDim);
+    const model =
+      typeof graphConfig.embedding_model === "string" && graphConfig.embedding_model
+        ? graphConfig.embedding_model
+        : DEFAULT_OPENAI_EMBEDDING_MODEL;
+    const timeoutMs =
+      typeof graphConfig.embedding_timeout_seconds === "number" &&
+      graphConfig.embedding_timeout_seconds > 0
+        ? graphConfig.embedding_timeout_seconds * 1000
+        : DEFAULT_EMBEDDING_TIMEOUT_MS;
+    return new OpenAIEmbedder(apiKey, model, embeddingDim, timeoutMs);
+  }
+
+  /**
+   * POST to the embeddings endpoint, retrying rate-limit (429) and overload
+   * (503) responses after the server's Retry-After delay when it sends one.
+   * Each attempt gets its own timeout.
+   */
+  private async request(input: string[]): Promise<Response> {
+    for (let attempt = 1; ; attempt += 1) {
+      let response: Response;
+      try {
+        response = await fetch("https://api.openai.com/v1/embeddings", {
+          method: "POST",
+          headers: {
+            "content-type": "application/json",
+            authorization: `Bearer ${this.apiKey}`,
+          },
+          body: JSON.stringify({
+            model: this.model,
+            input,
+          }),
+          signal: AbortSignal.timeout(this.timeoutMs),
+        });
+      } catch (error) {
+        if (error instanceof Error && error.name === "TimeoutError") {
+          throw new Error(`OpenAI embeddings timed out after ${this.timeoutMs}ms`);
+        }
+        throw error;
+      }
+      if (response.ok) {
+        return response;
+      }
+      const retryable = response.status === 429 || response.status === 503;
+      if (!retryable || attempt >= EMBEDDING_MAX_ATTEMPTS) {
+        const message = await response.text();
+        throw new Error(`OpenAI embeddings failed: ${message}`);
+      }
+      const retryAfterMs = Number(response.headers.get("retry-after-ms"));
+      const requested =
+        Number.isFinite(retryAfterMs) && retryAfterMs > 0
+          ? retryAfterMs
+          : parseRetryAfter(response.headers.get("retry-after"));
+      const delay = Math.min(
+        requested ?? EMBEDDING_RETRY_BASE_MS * 2 ** (attempt - 1),
+        EMBEDDING_MAX_RETRY_DELAY_MS,
+      );
+      await response.body?.cancel();
+      await new Promise((resolve) => setTimeout(resolve, delay));
+    }
   }
 
   async create(text: string): Promise<number[]> {
     const chunks = chunkText(text, 32000, 800);
-    const response = await fetch("https://api.openai.com/v1/embeddings", {
-      method: "POST",
-      headers: {
-        "content-type": "application/json",
-        authorization: `Bearer ${this.apiKey}`,
-      },
-      body: JSON.stringify({
-        model: this.model,
-        input: chunks,
-      }),
-    });
-
-    if (!response.ok) {
-      const message = await response.text();
-      throw new Error(`OpenAI embeddings failed: ${message}`);
-    }
+    const response = await this.request(chunks);
 
     const data = (await response.json()) as { data?: Array<{ embedding: number[] }> };
     const embeddings = (data.data ?? []).map((item) => item.embedding.slice(0, this.embeddingDim));
@@ -86,25 +177,28 @@ export class OpenAIEmbedder {
       return [];
     }
 
-    const response = await fetch("https://api.openai.com/v1/embeddings", {
-      method: "POST",
-      headers: {
-        "content-type": "application/json",
-        authorization: `Bearer ${this.apiKey}`,
-      },
-      body: JSON.stringify({
-        model: this.model,
-        input: texts,
-      }),
-    });
-
-    if (!response.ok) {
-      const message = await response.text();
-      throw new Error(`OpenAI embeddings failed: ${message}`);
-    }
+    const response = await this.request(texts);
 
     const data = (await response.json()) as { data?: Array<{ embedding: number[] }> };
     const embeddings = (data.data ?? []).map((item) => item.embedding.slice(0, this.embeddingDim));
     return embeddings;
   }
 }
+
+/**
+ * Build the embedder selected by `[dere_graph].embedding_backend`.
+ * Only "openai" ships today; it is also the default.
+ */
+export async function createEmbedder(): Promise<Embedder> {
+  const config = (await loadConfig()) as { dere_graph?: Record<string, unknown> };
+  const graphConfig = (config.dere_graph ?? {}) as Record<string, unknown>;
+  const backend =
+    typeof graphConfig.embedding_backend === "string" ? graphConfig.embedding_backend : "openai";
+
+  switch (backend) {
+    case "openai":
+      return OpenAIEmbedder.fromConfig();
+    default:
+      throw new Error(`Unknown embedding backend: ${backend}`);
+  }
+}
@@ -41,6 +41,23 @@ export function toNumber(value: unknown, fallback = 0): number {
   return fallback;
 }
 
+/**
+ * A stored embedding, or null when it is missing or malformed (empty, or
+ * holding anything but finite numbers, e.g. after a partial write) so callers
+ * fall back as they would for an unembedded node instead of scoring garbage.
+ */
+export function toEmbedding(value: unknown): number[] | null {
+  if (!Array.isArray(value) || value.length === 0) {
+    return null;
+  }
+  for (const item of value) {
+    if (typeof item !== "number" || !Number.isFinite(item)) {
+      return null;
+    }
+  }
+  return value as number[];
+}
+
 export function toIsoString(value: unknown): string | null {
   if (value instanceof Date) {
     return value.toISOString();
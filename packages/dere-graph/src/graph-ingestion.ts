@@ -1,4 +1,4 @@
-import { loadConfig } from "@dere/shared-config";
+import { loadConfig, loadPromptTemplate } from "@dere/shared-config";
 import {
   ExtractedEdgesSchema,
   ExtractedEntitiesSchema,
@@ -58,15 +58,17 @@ import {
   saveFactRoleEdge,
 } from "./graph-store.js";
 import { searchSimilarNodes } from "./graph-search.js";
-import { OpenAIEmbedder } from "./graph-embedder.js";
+import { createEmbedder, type Embedder } from "./graph-embedder.js";
 import { getGraphStructuredClient } from "./graph-llm.js";
 import { graphAvailable } from "./graph-helpers.js";
+import { normalizeRelationType, resolveRelationVocabulary } from "./graph-vocabulary.js";
 
 const MAX_EXTRACTION_CHARS = 20000;
 const MAX_CONTEXT_CHARS = 8000;
 const LOG_LINE_THRESHOLD = 0.4;
 const MIN_EXTRACTION_CHARS = 50;
 const MIN_UNIQUE_WORDS = 5;
+const DEFAULT_MIN_ENTITY_CONFIDENCE = 0.5;
 
 const SYSTEM_REMINDER_CLOSE_RE = /<\/system[-_]reminder\s*>/gi;
 const SYSTEM_REMINDER_BLOCK_RE = /<system[-_]reminder\b[^>]*>[\s\S]*?<\/system[-_]reminder\s*>/gi;
@@ -210,6 +212,14 @@ function isLogOrDataDump(content: string): boolean {
   return ratio >= LOG_LINE_THRESHOLD;
 }
 
+const DIFF_HUNK_RE = /^@@ -\d+(?:,\d+)? \+\d+(?:,\d+)? @@/m;
+const DIFF_FILE_RE = /^(?:diff --git |\+\+\+ |--- )\S/m;
+
+/** A unified diff (git diff, patch) pasted alone or inside a message. */
+function looksLikeUnifiedDiff(content: string): boolean {
+  return DIFF_HUNK_RE.test(content) && DIFF_FILE_RE.test(content);
+}
+
 function shouldSkipExtraction(content: string): [boolean, string] {
   if (content.length < MIN_EXTRACTION_CHARS) {
     return [true, `too short (${content.length} chars)`];
@@ -296,6 +306,36 @@ function normalizeLabels(label: string | null | undefined): string[] {
   return trimmed ? [trimmed] : [];
 }
 
+function readStringList(value: unknown): string[] | null {
+  if (!Array.isArray(value)) {
+    return null;
+  }
+  const items = value.filter(
+    (item): item is string => typeof item === "string" && item.trim() !== "",
+  );
+  return items.length > 0 ? items : null;
+}
+
+function passesConfidence(confidence: number | null | undefined, minConfidence: number): boolean {
+  // Entities without a reported confidence are kept; the model omits it on older prompts.
+  return typeof confidence !== "number" || confidence >= minConfidence;
+}
+
+function matchesEntityTypes(
+  node: EntityNode,
+  allowed: Set<string> | null,
+  excluded: Set<string> | null,
+): boolean {
+  const labels = node.labels.map((label) => label.toLowerCase());
+  if (excluded && labels.some((label) => excluded.has(label))) {
+    return false;
+  }
+  if (allowed && !labels.some((label) => allowed.has(label))) {
+    return false;
+  }
+  return true;
+}
+
 function buildEntityTypeSchemas(
   entityTypes: Record<string, Record<string, string>> | null | undefined,
 ): Record<string, Record<string, string>> | null {
@@ -305,15 +345,45 @@ function buildEntityTypeSchemas(
   return entityTypes;
 }
 
+/**
+ * What kind of conversation an episode comes from. Only "wellness" changes the
+ * extraction prompt; "coding" and "general" keep the default guidance.
+ */
+export type ContextHint = "coding" | "general" | "wellness";
+
+const WELLNESS_EXTRACTION_PROMPT = `
+This conversation is a wellness/mental-health session.
+- Prioritize people and relationships, emotions and moods, stressors, coping strategies, habits, goals, and meaningful life events.
+- Do NOT extract tools, libraries, functions, or other technical artifacts unless the user is clearly talking about them as part of their life.
+`;
+
+const DIFF_EXTRACTION_PROMPT = `
+CURRENT_MESSAGE contains a unified diff (code changes).
+- Extract the files touched, the functions, classes, and other symbols that were added, changed, or removed, and the libraries or config keys involved.
+- Put the kind of change (added, modified, removed, renamed) in the entity attributes.
+- Do NOT extract identifiers from unchanged context lines, local variables, or diff syntax (hunk headers, line numbers, +/- markers).
+`;
+
 async function extractNodes(options: {
   episode: EpisodicNode;
   previousEpisodes: EpisodicNode[];
   enableReflection: boolean;
   extractionContent: string;
+  contextHint?: ContextHint | null;
   entityTypes?: string[] | null;
   excludedEntityTypes?: string[] | null;
+  minConfidence?: number;
 }): Promise<EntityNode[]> {
   const rawContent = options.extractionContent;
+  const minConfidence = options.minConfidence ?? DEFAULT_MIN_ENTITY_CONFIDENCE;
+  const allowedTypes =
+    options.entityTypes && options.entityTypes.length > 0
+      ? new Set(options.entityTypes.map((type) => type.toLowerCase()))
+      : null;
+  const excludedTypes =
+    options.excludedEntityTypes && options.excludedEntityTypes.length > 0
+      ? new Set(options.excludedEntityTypes.map((type) => type.toLowerCase()))
+      : null;
   const userMessage = extractUserMessage(rawContent);
 
   const [skip, reason] = shouldSkipExtraction(userMessage);
@@ -359,6 +429,10 @@ Treat CURRENT_MESSAGE as documentation/notes.
 - Extract entities like products, libraries, commands, APIs, configuration keys, concepts, and durable decisions.
 - Avoid extracting generic words that don't add retrieval value.
 `;
+  } else if (looksLikeUnifiedDiff(userMessage)) {
+    customPrompt = await loadPromptTemplate("extract-diff", DIFF_EXTRACTION_PROMPT);
+  } else if (options.contextHint === "wellness") {
+    customPrompt = await loadPromptTemplate("extract-wellness", WELLNESS_EXTRACTION_PROMPT);
   }
 
   const prompt = buildExtractEntitiesPrompt({
@@ -372,21 +446,38 @@ Treat CURRENT_MESSAGE as documentation/notes.
     excludedEntityTypes: options.excludedEntityTypes ?? null,
   });
 
+  // A user template replaces the built-in system prompt; the per-episode
+  // guidance is offered as variables so an override can keep it.
+  const system = await loadPromptTemplate("extract-entities", prompt.system, {
+    entity_types: (options.entityTypes ?? []).join(", "),
+    excluded_entity_types: (options.excludedEntityTypes ?? []).join(", "),
+    custom_prompt: customPrompt.trim(),
+    speaker_name: options.episode.speaker_name ?? "",
+    personality: options.episode.personality ?? "",
+  });
+
   const llm = await getGraphStructuredClient();
-  const response = await llm.generate(prompt, ExtractedEntitiesSchema, {
+  const response = await llm.generateChat(system, prompt.user, ExtractedEntitiesSchema, {
     schemaName: "extracted_entities",
   });
 
-  let extracted = response.extracted_entities.map((entity) =>
-    createEntityNode({
-      name: entity.name.trim(),
-      group_id: options.episode.group_id,
-      labels: normalizeLabels(entity.entity_type ?? null),
-      summary: "",
-      attributes: entity.attributes ?? {},
-      aliases: entity.aliases ?? [],
-    }),
-  );
+  let extracted = response.extracted_entities
+    .filter((entity) => passesConfidence(entity.confidence, minConfidence))
+    .map((entity) =>
+      createEntityNode({
+        name: entity.name.trim(),
+        group_id: options.episode.group_id,
+        labels: normalizeLabels(entity.entity_type ?? null),
+        summary: "",
+        // Kept for `dere entities calibration`; the node's first sighting wins.
+        attributes:
+          typeof entity.confidence === "number"
+            ? { ...entity.attributes, extraction_confidence: entity.confidence }
+            : (entity.attributes ?? {}),
+        aliases: entity.aliases ?? [],
+      }),
+    )
+    .filter((node) => matchesEntityTypes(node, allowedTypes, excludedTypes));
 
   if (options.enableReflection) {
     try {
@@ -398,9 +489,12 @@ Treat CURRENT_MESSAGE as documentation/notes.
         episodeContent: userMessage,
         previousEpisodes: prevEpisodeStrings,
       });
-      const validation = await llm.generate(validationPrompt, EntityValidationSchema, {
-        schemaName: "entity_validation",
-      });
+      const validation = await llm.generateChat(
+        validationPrompt.system,
+        validationPrompt.user,
+        EntityValidationSchema,
+        { schemaName: "entity_validation" },
+      );
 
       if (validation.hallucinated_entities.length > 0) {
         extracted = extracted.filter(
@@ -410,6 +504,10 @@ Treat CURRENT_MESSAGE as documentation/notes.
 
       if (validation.missed_entities.length > 0) {
         for (const missed of validation.missed_entities) {
+          // Recovered entities carry no type, so only the confidence floor applies.
+          if (!passesConfidence(missed.confidence, minConfidence)) {
+            continue;
+          }
           extracted.push(
             createEntityNode({
               name: missed.name,
@@ -458,9 +556,12 @@ Treat CURRENT_MESSAGE as documentation/notes.
       episodeContent: userMessage,
       entitiesPayload,
     });
-    const summaries = await llm.generate(summaryPrompt, EntitySummariesSchema, {
-      schemaName: "entity_summaries",
-    });
+    const summaries = await llm.generateChat(
+      summaryPrompt.system,
+      summaryPrompt.user,
+      EntitySummariesSchema,
+      { schemaName: "entity_summaries" },
+    );
     const summaryMap = new Map(
       summaries.entity_summaries.map((item) => [item.id, item.summary.trim()]),
     );
@@ -509,9 +610,12 @@ async function hydrateNodeAttributes(
 
   const llm = await getGraphStructuredClient();
   try {
-    const response = await llm.generate(prompt, EntityAttributeUpdatesSchema, {
-      schemaName: "entity_attribute_updates",
-    });
+    const response = await llm.generateChat(
+      prompt.system,
+      prompt.user,
+      EntityAttributeUpdatesSchema,
+      { schemaName: "entity_attribute_updates" },
+    );
     const updates = new Map(
       response.entity_attributes.map((update) => [update.id, update.attributes]),
     );
@@ -557,7 +661,7 @@ async function deduplicateNodes(
   extractedNodes: EntityNode[],
   episode: EpisodicNode,
   previousEpisodes: EpisodicNode[],
-  embedder: OpenAIEmbedder,
+  embedder: Embedder,
 ): Promise<{ resolved: EntityNode[]; uuidMap: Map<string, string> }> {
   const candidates = await searchSimilarNodes(extractedNodes, episode.group_id, (text) =>
     embedder.create(text),
@@ -598,7 +702,7 @@ async function deduplicateNodes(
   });
 
   const llm = await getGraphStructuredClient();
-  const response = await llm.generate(prompt, NodeResolutionsSchema, {
+  const response = await llm.generateChat(prompt.system, prompt.user, NodeResolutionsSchema, {
     schemaName: "entity_resolutions",
   });
 
@@ -630,6 +734,13 @@ async function deduplicateNodes(
         if (extracted.labels.length > 0 && resolvedNode.labels.length === 0) {
           resolvedNode.labels = extracted.labels;
         }
+        if (
+          extracted.attributes.extraction_confidence !== undefined &&
+          resolvedNode.attributes.extraction_confidence === undefined
+        ) {
+          resolvedNode.attributes.extraction_confidence =
+            extracted.attributes.extraction_confidence;
+        }
       } else {
         resolvedNode.mention_count = 1;
         uuidMap.set(extracted.uuid, extracted.uuid);
@@ -692,7 +803,7 @@ Only extract facts that are clearly supported by the code and involve two distin
   } else if (episode.source === "doc") {
     customPrompt = `
 This episode source is DOCUMENTATION from: ${episode.source_description}
-Prefer doc-aware relation_type values when appropriate (e.g., DESCRIBES, DOCUMENTS, REQUIRES, CONFIGURES, USES).
+Prefer doc-aware relation_type values when appropriate (e.g., DOCUMENTS, DEPENDS_ON, CONFIGURES, USES).
 Only extract facts that are clearly supported by the text and involve two distinct ENTITIES.
 `;
   }
@@ -710,11 +821,11 @@ Only extract facts that are clearly supported by the text and involve two distin
   });
 
   const llm = await getGraphStructuredClient();
-  const response = await llm.generate(prompt, ExtractedEdgesSchema, {
+  const response = await llm.generateChat(prompt.system, prompt.user, ExtractedEdgesSchema, {
     schemaName: "extracted_edges",
   });
 
-  const allowedEdgeTypes = new Set(options.edgeTypes ?? []);
+  const relationTypes = options.edgeTypes ?? [];
   const excludedEdgeTypes = new Set(options.excludedEdgeTypes ?? []);
   const edges: EntityEdge[] = [];
 
@@ -733,11 +844,14 @@ Only extract facts that are clearly supported by the text and involve two distin
     }
 
     const relationType = (edgeData.relation_type ?? "DEFAULT").trim() || "DEFAULT";
-    let finalRelation = relationType;
-    if (excludedEdgeTypes.has(finalRelation)) {
-      finalRelation = "DEFAULT";
+    let finalRelation: string | null = relationType;
+    if (relationTypes.length > 0) {
+      finalRelation = normalizeRelationType(relationType, relationTypes);
+      if (!finalRelation) {
+        continue;
+      }
     }
-    if (allowedEdgeTypes.size > 0 && !allowedEdgeTypes.has(finalRelation)) {
+    if (excludedEdgeTypes.has(finalRelation)) {
       finalRelation = "DEFAULT";
     }
 
@@ -816,7 +930,7 @@ async function deduplicateEdges(edges: EntityEdge[]): Promise<EntityEdge[]> {
       invalidationCandidates: invalidationCandidatesData,
     });
 
-    const response = await llm.generate(prompt, EdgeDuplicateSchema, {
+    const response = await llm.generateChat(prompt.system, prompt.user, EdgeDuplicateSchema, {
       schemaName: "edge_duplicate",
     });
 
@@ -888,7 +1002,7 @@ async function refineEdgeDates(
 
   const llm = await getGraphStructuredClient();
   try {
-    const response = await llm.generate(prompt, EdgeDateUpdatesSchema, {
+    const response = await llm.generateChat(prompt.system, prompt.user, EdgeDateUpdatesSchema, {
       schemaName: "edge_date_updates",
     });
     const updates = new Map(response.edge_dates.map((update) => [update.id, update]));
@@ -965,7 +1079,7 @@ Avoid purely narrative or speculative statements.
   const llm = await getGraphStructuredClient();
   let response;
   try {
-    response = await llm.generate(prompt, ExtractedFactsSchema, {
+    response = await llm.generateChat(prompt.system, prompt.user, ExtractedFactsSchema, {
       schemaName: "extracted_facts",
     });
   } catch (error) {
@@ -1255,7 +1369,9 @@ async function deduplicateFactNodes(
         newFact: newFactPayload,
         existingFacts: existingFactsPayload,
       });
-      response = await llm.generate(prompt, FactDuplicateSchema, { schemaName: "fact_duplicate" });
+      response = await llm.generateChat(prompt.system, prompt.user, FactDuplicateSchema, {
+        schemaName: "fact_duplicate",
+      });
     } catch (error) {
       console.log(`[graph] fact dedupe failed: ${String(error)}`);
       response = { duplicate_facts: [] };
@@ -1327,7 +1443,7 @@ async function deduplicateFactNodes(
 }
 
 async function generateNodeEmbeddings(
-  embedder: OpenAIEmbedder,
+  embedder: Embedder,
   nodes: EntityNode[],
 ): Promise<void> {
   if (nodes.length === 0) {
@@ -1341,7 +1457,7 @@ async function generateNodeEmbeddings(
 }
 
 async function generateEdgeEmbeddings(
-  embedder: OpenAIEmbedder,
+  embedder: Embedder,
   edges: EntityEdge[],
 ): Promise<void> {
   if (edges.length === 0) {
@@ -1354,7 +1470,7 @@ async function generateEdgeEmbeddings(
   });
 }
 
-async function generateFactEmbeddings(embedder: OpenAIEmbedder, facts: FactNode[]): Promise<void> {
+async function generateFactEmbeddings(embedder: Embedder, facts: FactNode[]): Promise<void> {
   if (facts.length === 0) {
     return;
   }
@@ -1445,6 +1561,8 @@ export type AddEpisodeOptions = {
   excludedEntityTypes?: string[] | null;
   edgeTypes?: string[] | null;
   excludedEdgeTypes?: string[] | null;
+  contextHint?: ContextHint | null;
+  sessionId?: number | null;
 };
 
 export type AddEpisodeResults = {
@@ -1475,9 +1593,14 @@ export async function addEpisode(options: AddEpisodeOptions): Promise<AddEpisode
       ? graphConfig.idle_threshold_minutes
       : 15;
 
+  // A session's messages get an episode of their own, so reprocessing or
+  // purging one session never touches another's episodes.
+  const sessionId = options.sessionId ?? null;
   const conversationId =
     options.conversationId ??
-    (await generateConversationId(referenceTime, sourceDescription, groupId, idleThreshold));
+    (sessionId !== null
+      ? `session_${sessionId}`
+      : await generateConversationId(referenceTime, sourceDescription, groupId, idleThreshold));
 
   const isoDate = referenceTime.toISOString().split("T")[0] ?? referenceTime.toISOString();
   const name = options.name ?? isoDate;
@@ -1500,6 +1623,7 @@ export async function addEpisode(options: AddEpisodeOptions): Promise<AddEpisode
       speaker_id: options.speakerId ?? null,
       speaker_name: options.speakerName ?? null,
       personality: options.personality ?? null,
+      session_id: sessionId,
     });
   }
 
@@ -1509,7 +1633,7 @@ export async function addEpisode(options: AddEpisodeOptions): Promise<AddEpisode
 
   await saveEpisodicNode(episode);
 
-  const embedder = await OpenAIEmbedder.fromConfig();
+  const embedder = await createEmbedder();
   const enableReflection = graphConfig.enable_reflection !== false;
 
   const extractedNodes = await extractNodes({
@@ -1517,8 +1641,14 @@ export async function addEpisode(options: AddEpisodeOptions): Promise<AddEpisode
     previousEpisodes,
     enableReflection,
     extractionContent,
-    entityTypes: options.entityTypes ?? null,
-    excludedEntityTypes: options.excludedEntityTypes ?? null,
+    entityTypes: options.entityTypes ?? readStringList(graphConfig.allowed_entity_types),
+    excludedEntityTypes:
+      options.excludedEntityTypes ?? readStringList(graphConfig.excluded_entity_types),
+    minConfidence:
+      typeof graphConfig.min_entity_confidence === "number"
+        ? graphConfig.min_entity_confidence
+        : DEFAULT_MIN_ENTITY_CONFIDENCE,
+    contextHint: options.contextHint ?? null,
   });
 
   if (extractedNodes.length === 0) {
@@ -1538,7 +1668,7 @@ export async function addEpisode(options: AddEpisodeOptions): Promise<AddEpisode
   }
 
   const edges = await extractEntityEdges(episode, resolved, previousEpisodes, {
-    edgeTypes: options.edgeTypes ?? null,
+    edgeTypes: options.edgeTypes ?? resolveRelationVocabulary(graphConfig),
     excludedEdgeTypes: options.excludedEdgeTypes ?? null,
     extractionContent,
   });
@@ -1703,7 +1833,7 @@ export async function addFact(options: AddFactOptions): Promise<AddFactResult> {
   }
 
   if (!factNode.fact_embedding) {
-    const embedder = await OpenAIEmbedder.fromConfig();
+    const embedder = await createEmbedder();
     factNode.fact_embedding = await embedder.create(factText.replace(/\n/g, " "));
   }
 
@@ -1,18 +1,16 @@
 import { z } from "zod";
 
-type PromptPair = {
+/** Instructions and content kept apart so they can be sent as separate roles. */
+export type PromptPair = {
   system: string;
   user: string;
 };
 
-function buildPrompt({ system, user }: PromptPair): string {
-  return `SYSTEM:\n${system}\n\nUSER:\n${user}`;
-}
-
 export const ExtractedEntitySchema = z.object({
   name: z.string(),
   entity_type: z.string().nullable().optional(),
   knowledge_scope: z.enum(["skip", "curious"]).default("skip"),
+  confidence: z.number().nullable().optional(),
   attributes: z.record(z.string(), z.unknown()).optional().default({}),
   aliases: z.array(z.string()).optional().default([]),
 });
@@ -107,6 +105,7 @@ export const FactDuplicateSchema = z.object({
 export const MissedEntitySchema = z.object({
   name: z.string(),
   summary: z.string(),
+  confidence: z.number().nullable().optional(),
 });
 
 export const EntityRefinementSchema = z.object({
@@ -130,7 +129,7 @@ export function buildExtractEntitiesPrompt(options: {
   personality?: string | null;
   entityTypes?: string[] | null;
   excludedEntityTypes?: string[] | null;
-}): string {
+}): PromptPair {
   const system = `You are an AI assistant that extracts entity nodes from text.
 Your primary task is to extract and classify significant entities mentioned in the provided text.`;
 
@@ -257,6 +256,10 @@ Examples:
 
 When in doubt, default to "skip" - we don't want to waste cycles researching common knowledge.
 
+Confidence:
+For each entity, give a confidence between 0.0 and 1.0 that it is a real, clearly mentioned entity worth storing.
+Use low values for vague references, generic words, or guesses.
+
 Attributes:
 For each entity, extract relevant attributes that help distinguish it from similar entities:
 - For People/Users: job_title, company, location, relationship_to_user, role, expertise, user_id, is_speaker, attributes, preferences
@@ -272,14 +275,14 @@ Only include attributes that are explicitly mentioned or clearly implied in the
 Empty attributes dict is acceptable if no distinguishing attributes are present.
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildSummarizeEntitiesPrompt(options: {
   previousEpisodes: string[];
   episodeContent: string;
   entitiesPayload: Array<Record<string, unknown>>;
-}): string {
+}): PromptPair {
   const system =
     "You are a helpful assistant that writes concise summaries for entities extracted from a conversation.";
 
@@ -301,7 +304,7 @@ Summaries should capture distinguishing context, roles, and relationships mentio
 If there is insufficient information to summarize, return an empty string.
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildHydrateAttributesPrompt(options: {
@@ -309,7 +312,7 @@ export function buildHydrateAttributesPrompt(options: {
   episodeContent: string;
   entitiesPayload: Array<Record<string, unknown>>;
   entityTypeSchemas?: Record<string, Record<string, string>> | null;
-}): string {
+}): PromptPair {
   const system = `You are an AI assistant that enriches entity attributes with structured data.
 Your task is to extract additional attributes for the provided entities based on the conversation context.`;
 
@@ -336,7 +339,7 @@ Only add attributes that are explicitly mentioned or clearly implied.
 Return updated attributes for each entity by id.
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildExtractEdgesPrompt(options: {
@@ -347,7 +350,7 @@ export function buildExtractEdgesPrompt(options: {
   customPrompt?: string;
   edgeTypes?: string[] | null;
   excludedEdgeTypes?: string[] | null;
-}): string {
+}): PromptPair {
   const system =
     "You are a helpful assistant that extracts factual relationships between entities.";
 
@@ -373,11 +376,11 @@ Guidelines:
 - If a relation is time-bound, include valid_at/invalid_at in ISO format
 - Use relation_type in SCREAMING_SNAKE_CASE
 - Provide concise factual "fact" text describing the relationship
-${options.edgeTypes && options.edgeTypes.length > 0 ? `- Prefer relation_type values from: ${options.edgeTypes.join(", ")}` : ""}
+${options.edgeTypes && options.edgeTypes.length > 0 ? `- relation_type must be one of: ${options.edgeTypes.join(", ")}` : ""}
 ${options.excludedEdgeTypes && options.excludedEdgeTypes.length > 0 ? `- Avoid relation_type values from: ${options.excludedEdgeTypes.join(", ")}` : ""}
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildExtractFactsPrompt(options: {
@@ -386,7 +389,7 @@ export function buildExtractFactsPrompt(options: {
   nodesContext: Array<Record<string, unknown>>;
   referenceTime: string;
   customPrompt?: string;
-}): string {
+}): PromptPair {
   const system = "You are a helpful assistant that extracts multi-entity facts from text.";
 
   const user = `
@@ -413,7 +416,7 @@ Guidelines:
 - Provide valid_at/invalid_at in ISO format when time-bound
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildDedupeEntitiesPrompt(options: {
@@ -421,7 +424,7 @@ export function buildDedupeEntitiesPrompt(options: {
   existingNodes: Array<Record<string, unknown>>;
   episodeContent: string;
   previousEpisodes: string[];
-}): string {
+}): PromptPair {
   const system =
     "You are a helpful assistant that determines whether or not ENTITIES extracted from a conversation are duplicates of existing entities.";
 
@@ -475,14 +478,14 @@ For every entity, return an object with:
 Only use idx values that appear in EXISTING ENTITIES.
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildDedupeEdgesPrompt(options: {
   newEdge: Record<string, unknown>;
   existingEdges: Array<Record<string, unknown>>;
   invalidationCandidates: Array<Record<string, unknown>>;
-}): string {
+}): PromptPair {
   const system =
     "You are a helpful assistant that de-duplicates facts from fact lists and determines which existing facts are contradicted by the new fact.";
 
@@ -522,13 +525,13 @@ ${JSON.stringify(options.newEdge, null, 2)}
 </NEW FACT>
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildDedupeFactsPrompt(options: {
   newFact: Record<string, unknown>;
   existingFacts: Array<Record<string, unknown>>;
-}): string {
+}): PromptPair {
   const system = "You are a helpful assistant that de-duplicates facts from fact lists.";
 
   const user = `
@@ -553,14 +556,14 @@ ${JSON.stringify(options.newFact, null, 2)}
 </NEW FACT>
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildValidateEntitiesPrompt(options: {
   extractedEntities: Array<Record<string, unknown>>;
   episodeContent: string;
   previousEpisodes: string[];
-}): string {
+}): PromptPair {
   const system = `You are an expert entity extraction validator. Your task is to review extracted entities and:
 1. Identify any important entities that were missed
 2. Flag any hallucinated entities that don't actually appear in the conversation
@@ -599,12 +602,12 @@ Guidelines:
 - If extraction looks good, it's fine to return empty lists for missed/hallucinated entities
 
 Return your analysis in this format:
-- missed_entities: list of {name, summary} for missed entities
+- missed_entities: list of {name, summary, confidence} for missed entities (confidence 0.0-1.0)
 - hallucinated_entities: list of entity names that should be removed
 - refinements: list of {original_name, refined_name, refined_summary} for improvements
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
 
 export function buildExtractEdgeDatesPrompt(options: {
@@ -612,7 +615,7 @@ export function buildExtractEdgeDatesPrompt(options: {
   episodeContent: string;
   edges: Array<Record<string, unknown>>;
   referenceTime: string;
-}): string {
+}): PromptPair {
   const system = `You are an assistant that extracts valid_at/invalid_at timestamps for edges.`;
 
   const user = `
@@ -634,5 +637,5 @@ Fill in valid_at/invalid_at for the edges when possible.
 If unknown, return nulls.
 `;
 
-  return buildPrompt({ system, user });
+  return { system, user };
 }
@@ -1,5 +1,5 @@
-import { queryGraph, toDate } from "./graph-helpers.js";
-import { OpenAIEmbedder } from "./graph-embedder.js";
+import { queryGraph, toDate, toEmbedding } from "./graph-helpers.js";
+import { createEmbedder } from "./graph-embedder.js";
 import { buildTemporalQueryClause, type SearchFilters } from "./graph-filters.js";
 import { DEFAULT_DOMAIN_ROUTES, mergeFilters, selectDomainFilters } from "./graph-routing.js";
 import {
@@ -63,9 +63,7 @@ function parseEntityRecord(record: Record<string, unknown>): EntityNode {
     labels,
     created_at: toDate(record.created_at) ?? new Date(),
     expired_at: toDate(record.expired_at),
-    name_embedding: Array.isArray(record.name_embedding)
-      ? (record.name_embedding as number[])
-      : null,
+    name_embedding: toEmbedding(record.name_embedding),
     summary: typeof record.summary === "string" ? record.summary : "",
     attributes,
     aliases: toStringArray(record.aliases),
@@ -106,9 +104,7 @@ function parseEdgeRecord(record: Record<string, unknown>): EntityEdge {
     source_node_uuid: String(record.source_uuid ?? ""),
     target_node_uuid: String(record.target_uuid ?? ""),
     fact: String(record.fact ?? ""),
-    fact_embedding: Array.isArray(record.fact_embedding)
-      ? (record.fact_embedding as number[])
-      : null,
+    fact_embedding: toEmbedding(record.fact_embedding),
     episodes: toStringArray(record.episodes),
     created_at: toDate(record.created_at) ?? new Date(),
     expired_at: toDate(record.expired_at),
@@ -153,9 +149,7 @@ function parseFactRecord(record: Record<string, unknown>): FactNode {
     created_at: toDate(record.created_at) ?? new Date(),
     expired_at: toDate(record.expired_at),
     fact: String(record.fact ?? ""),
-    fact_embedding: Array.isArray(record.fact_embedding)
-      ? (record.fact_embedding as number[])
-      : null,
+    fact_embedding: toEmbedding(record.fact_embedding),
     attributes,
     episodes: toStringArray(record.episodes),
     valid_at: toDate(record.valid_at),
@@ -199,6 +193,102 @@ function mergeRankedItems<T extends { uuid: string }>(resultLists: T[][], limit:
   return mergedUuids.map((uuid) => itemByUuid.get(uuid)).filter((item): item is T => Boolean(item));
 }
 
+const NODE_RETURN_FIELDS = `
+      RETURN node.uuid AS uuid,
+             node.name AS name,
+             node.group_id AS group_id,
+             node.name_embedding AS name_embedding,
+             node.summary AS summary,
+             node.created_at AS created_at,
+             node.expired_at AS expired_at,
+             node.aliases AS aliases,
+             node.last_mentioned AS last_mentioned,
+             node.mention_count AS mention_count,
+             node.retrieval_count AS retrieval_count,
+             node.citation_count AS citation_count,
+             node.retrieval_quality AS retrieval_quality,
+             labels(node) AS labels,
+             properties(node) AS attributes`;
+
+// Entity names scanned for the typo-tolerant fallback, most mentioned first.
+const FUZZY_CANDIDATE_LIMIT = 2000;
+const FUZZY_MIN_SIMILARITY = 0.3;
+
+function trigrams(text: string): Set<string> {
+  const grams = new Set<string>();
+  for (const word of text.toLowerCase().split(/\s+/).filter(Boolean)) {
+    const padded = `  ${word} `;
+    for (let i = 0; i + 3 <= padded.length; i += 1) {
+      grams.add(padded.slice(i, i + 3));
+    }
+  }
+  return grams;
+}
+
+/** pg_trgm-style similarity: shared trigrams over all trigrams, 0-1. */
+export function trigramSimilarity(a: string, b: string): number {
+  const left = trigrams(a);
+  const right = trigrams(b);
+  if (left.size === 0 || right.size === 0) {
+    return 0;
+  }
+  let shared = 0;
+  for (const gram of left) {
+    if (right.has(gram)) {
+      shared += 1;
+    }
+  }
+  return shared / (left.size + right.size - shared);
+}
+
+// Words too common to say anything about which entity a query means.
+const STOPWORDS = new Set(
+  (
+    "about after all also and any are but can could did does for from had has have her his " +
+    "how into its just let like make more not now off one our out please she should some " +
+    "than that the their them then there these they this use using want was way were what " +
+    "when where which while who why will with would you your"
+  ).split(" "),
+);
+
+// Characters treated as word breaks when matching whole words. Dots, hyphens
+// and underscores stay, since they appear inside names like node.js.
+const WORD_BREAKS = ",;:!?()[]{}\"'/\n\t";
+
+/** A Cypher string literal for one character. */
+function cypherChar(char: string): string {
+  return `'${JSON.stringify(char).slice(1, -1).replace(/'/g, "\\'")}'`;
+}
+
+/** Query words worth matching: 3+ characters and not a stopword. */
+function queryWords(query: string): string[] {
+  const words = query
+    .split(/[^\p{L}\p{N}_.-]+/u)
+    .map((word) => word.replace(/^[.-]+|[.-]+$/g, ""))
+    .filter((word) => word.length >= 3 && !STOPWORDS.has(word));
+  return Array.from(new Set(words));
+}
+
+/**
+ * A Cypher expression for `expr` with punctuation turned into spaces and a
+ * space at each end, so ` word ` CONTAINS-matches only whole words.
+ */
+function spacedWords(expr: string): string {
+  let spaced = `${expr} + ' '`;
+  for (const char of WORD_BREAKS) {
+    spaced = `replace(${spaced}, ${cypherChar(char)}, ' ')`;
+  }
+  // Sentence-ending dots, now always followed by a space.
+  return `' ' + replace(${spaced}, '. ', ' ')`;
+}
+
+/**
+ * Keyword search over entity names and summaries. Any query word of three or
+ * more characters that isn't a stopword can match, as a whole word; results
+ * rank by whole-phrase and per-word name matches, then summary matches, then
+ * how often the entity is mentioned. When no word matches at all, names are
+ * compared by trigram similarity so typos still find something.
+ */
 export async function fulltextNodeSearch(
   query: string,
   groupId: string,
@@ -209,42 +299,95 @@ export async function fulltextNodeSearch(
   const { clause, params } = buildTemporalQueryClause(filters, "node", null);
 
   const whereParts = ["node.group_id = $group_id"];
-  if (q) {
-    whereParts.push(
-      "(toLower(node.name) CONTAINS $query OR toLower(node.summary) CONTAINS $query)",
-    );
-  }
   if (clause) {
     whereParts.push(clause.replace("WHERE ", ""));
   }
   const whereClause = `WHERE ${whereParts.join(" AND ")}`;
 
+  if (!q) {
+    const records = await queryGraph(
+      `
+        MATCH (node:Entity)
+        ${whereClause}
+        ${NODE_RETURN_FIELDS}
+        ORDER BY node.created_at DESC
+        LIMIT $limit
+      `,
+      { group_id: groupId, limit, ...params },
+    );
+    return records.map((record) => parseEntityRecord(record));
+  }
+
+  const words = queryWords(q);
   const records = await queryGraph(
     `
       MATCH (node:Entity)
       ${whereClause}
-      RETURN node.uuid AS uuid,
-             node.name AS name,
-             node.group_id AS group_id,
-             node.name_embedding AS name_embedding,
-             node.summary AS summary,
-             node.created_at AS created_at,
-             node.expired_at AS expired_at,
-             node.aliases AS aliases,
-             node.last_mentioned AS last_mentioned,
-             node.mention_count AS mention_count,
-             node.retrieval_count AS retrieval_count,
-             node.citation_count AS citation_count,
-             node.retrieval_quality AS retrieval_quality,
-             labels(node) AS labels,
-             properties(node) AS attributes
-      ORDER BY node.created_at DESC
+      WITH node,
+           toLower(coalesce(node.name, '')) AS name_lower,
+           toLower(coalesce(node.summary, '')) AS summary_lower
+      WITH node, name_lower, summary_lower,
+           ${spacedWords("name_lower")} AS name_words,
+           ${spacedWords("summary_lower")} AS summary_words
+      WITH node,
+           CASE WHEN name_lower = $query THEN 8 ELSE 0 END +
+           CASE WHEN name_lower CONTAINS $query THEN 4 ELSE 0 END +
+           2 * size([word IN $words WHERE name_words CONTAINS word]) +
+           size([word IN $words WHERE summary_words CONTAINS word]) +
+           CASE WHEN summary_lower CONTAINS $query THEN 1 ELSE 0 END AS match_score
+      WHERE match_score > 0
+      ${NODE_RETURN_FIELDS}
+      ORDER BY match_score DESC, coalesce(node.mention_count, 0) DESC
       LIMIT $limit
     `,
-    { group_id: groupId, query: q, limit, ...params },
+    { group_id: groupId, query: q, words: words.map((word) => ` ${word} `), limit, ...params },
   );
+  if (records.length > 0) {
+    return records.map((record) => parseEntityRecord(record));
+  }
+  return fuzzyNodeSearch(q, whereClause, { group_id: groupId, limit, ...params });
+}
 
-  return records.map((record) => parseEntityRecord(record));
+async function fuzzyNodeSearch(
+  query: string,
+  whereClause: string,
+  params: Record<string, unknown> & { limit: number },
+): Promise<EntityNode[]> {
+  const candidates = await queryGraph(
+    `
+      MATCH (node:Entity)
+      ${whereClause}
+      RETURN node.uuid AS uuid, node.name AS name
+      ORDER BY coalesce(node.mention_count, 0) DESC
+      LIMIT $candidate_limit
+    `,
+    { ...params, candidate_limit: FUZZY_CANDIDATE_LIMIT },
+  );
+
+  const ranked = candidates
+    .map((record) => ({
+      uuid: String(record.uuid ?? ""),
+      similarity: trigramSimilarity(query, String(record.name ?? "")),
+    }))
+    .filter((candidate) => candidate.uuid && candidate.similarity >= FUZZY_MIN_SIMILARITY)
+    .sort((a, b) => b.similarity - a.similarity)
+    .slice(0, params.limit);
+  if (ranked.length === 0) {
+    return [];
+  }
+
+  const records = await queryGraph(
+    `
+      MATCH (node:Entity)
+      WHERE node.uuid IN $uuids
+      ${NODE_RETURN_FIELDS}
+    `,
+    { uuids: ranked.map((candidate) => candidate.uuid) },
+  );
+  const order = new Map(ranked.map((candidate, index) => [candidate.uuid, index]));
+  return records
+    .map((record) => parseEntityRecord(record))
+    .sort((a, b) => (order.get(a.uuid) ?? 0) - (order.get(b.uuid) ?? 0));
 }
 
 export async function vectorNodeSearch(
@@ -367,7 +510,14 @@ export async function searchSimilarNodes(
   return candidates;
 }
 
+/**
+ * Cosine similarity of two embeddings. Vectors of different dimensions come
+ * from different models and aren't comparable, so they score 0.
+ */
 export function cosineSimilarity(a: number[], b: number[]): number {
+  if (a.length !== b.length) {
+    return 0;
+  }
   let dot = 0;
   let normA = 0;
   let normB = 0;
@@ -396,7 +546,8 @@ function mmrRerank<T>(
   }
 
   const embeddings = items.map((item) => getEmbedding(item));
-  if (embeddings.some((embedding) => !embedding || embedding.length === 0)) {
+  // Missing or other-model embeddings can't be diversified; keep input order
+  if (embeddings.some((embedding) => !embedding || embedding.length !== queryEmbedding.length)) {
     return items.slice(0, limit);
   }
 
@@ -461,6 +612,43 @@ function scoreByRecency<T extends { created_at: Date }>(
   });
 }
 
+const MS_PER_DAY = 86_400_000;
+
+/**
+ * Blend query similarity with an exponential age decay so a strong match from
+ * months ago doesn't automatically outrank a slightly weaker one from yesterday.
+ * Nodes without a comparable embedding fall back to their position in the
+ * hybrid ranking, mapped onto the range of similarities the embedded nodes
+ * scored so both kinds of score sit on the same 0-1 scale.
+ */
+function scoreBySimilarityAndAge(
+  items: EntityNode[],
+  queryEmbedding: number[],
+  halfLifeDays: number,
+): Array<[EntityNode, number]> {
+  if (items.length === 0) {
+    return [];
+  }
+  const now = Date.now();
+  const similarities = items.map((item) =>
+    item.name_embedding?.length === queryEmbedding.length
+      ? Math.max(0, cosineSimilarity(queryEmbedding, item.name_embedding))
+      : null,
+  );
+  const known = similarities.filter((value): value is number => value !== null);
+  const low = known.length > 0 ? Math.min(...known) : 0;
+  const high = known.length > 0 ? Math.max(...known) : 1;
+  const scored = items.map((item, index) => {
+    const similarity = similarities[index] ?? low + (high - low) * (1 - index / items.length);
+    const lastSeen = (item.last_mentioned ?? item.created_at).getTime();
+    const ageDays = Math.max(0, now - lastSeen) / MS_PER_DAY;
+    const recency = Math.pow(0.5, ageDays / halfLifeDays);
+    return [item, similarity * recency] as [EntityNode, number];
+  });
+  scored.sort((a, b) => b[1] - a[1]);
+  return scored;
+}
+
 function scoreByEpisodeMentions(items: EntityNode[], alpha = 0.5): Array<[EntityNode, number]> {
   if (items.length === 0) {
     return [];
@@ -924,6 +1112,7 @@ export type GraphSearchOptions = {
   lambdaParam?: number;
   rerankAlpha?: number;
   recencyWeight?: number;
+  recencyHalfLifeDays?: number;
   conversationId?: string | null;
   includeExpiredFacts?: boolean;
 };
@@ -932,6 +1121,7 @@ export type GraphSearchResults = {
   nodes: EntityNode[];
   edges: EntityEdge[];
   facts: FactNode[];
+  nodeScores?: Record<string, number>;
 };
 
 export async function searchGraph(options: GraphSearchOptions): Promise<GraphSearchResults> {
@@ -945,6 +1135,10 @@ export async function searchGraph(options: GraphSearchOptions): Promise<GraphSea
   const rerankAlpha = typeof options.rerankAlpha === "number" ? options.rerankAlpha : 0.5;
   const lambdaParam = typeof options.lambdaParam === "number" ? options.lambdaParam : 0.5;
   const recencyWeight = typeof options.recencyWeight === "number" ? options.recencyWeight : 0;
+  const recencyHalfLifeDays =
+    typeof options.recencyHalfLifeDays === "number" && options.recencyHalfLifeDays > 0
+      ? options.recencyHalfLifeDays
+      : 0;
 
   const enableBfs = true;
   const searchBfsLimit = 5;
@@ -957,7 +1151,7 @@ export async function searchGraph(options: GraphSearchOptions): Promise<GraphSea
     enableBfs && searchBfsLimit > 0 && limit > 1 ? Math.min(searchBfsLimit, limit - 1) : 0;
   const primaryLimit = limit - bfsSlots;
 
-  const embedder = await OpenAIEmbedder.fromConfig();
+  const embedder = await createEmbedder();
   const queryEmbedding = await embedder.create(query.replace(/\n/g, " "));
 
   const nodeFetchLimit = limit + bfsSlots;
@@ -1068,6 +1262,13 @@ export async function searchGraph(options: GraphSearchOptions): Promise<GraphSea
       .slice(0, nodeFetchLimit);
   }
 
+  let nodeScores: Record<string, number> | undefined;
+  if (recencyHalfLifeDays > 0) {
+    const blended = scoreBySimilarityAndAge(rankedNodes, queryEmbedding, recencyHalfLifeDays);
+    nodeScores = Object.fromEntries(blended.map(([node, score]) => [node.uuid, score]));
+    rankedNodes = blended.map(([node]) => node);
+  }
+
   let nodes = rankedNodes.slice(0, primaryLimit);
   let edges = edgeCandidates.slice(0, primaryLimit);
   let facts = factCandidates.slice(0, limit);
@@ -1097,9 +1298,9 @@ export async function searchGraph(options: GraphSearchOptions): Promise<GraphSea
     }
   }
 
-  if (recencyWeight > 0 && nodes.length > 0) {
+  if (recencyWeight > 0 && nodes.length > 0 && recencyHalfLifeDays === 0) {
     nodes = scoreByRecency(nodes, recencyWeight).map(([node]) => node);
   }
 
-  return { nodes, edges, facts };
+  return { nodes, edges, facts, nodeScores };
 }
@@ -1,4 +1,4 @@
-import { queryGraph, toDate, toIsoString } from "./graph-helpers.js";
+import { queryGraph, toDate, toEmbedding, toIsoString } from "./graph-helpers.js";
 import {
   createEpisodicEdge,
   createEpisodicNode,
@@ -14,6 +14,7 @@ import {
   type FactRoleDetail,
   type FactRoleEdge,
 } from "./graph-types.js";
+import { sanitizeLabel } from "./graph-vocabulary.js";
 
 function parseDate(value: unknown): Date | null {
   return toDate(value);
@@ -40,10 +41,6 @@ function toStringArray(value: unknown): string[] {
   return [];
 }
 
-function sanitizeLabel(label: string): string {
-  return label.replace(/[^a-zA-Z0-9_]/g, "_");
-}
-
 function parseAttributes(
   record: Record<string, unknown>,
   reservedKeys: string[],
@@ -183,6 +180,7 @@ export async function saveEpisodicNode(node: EpisodicNode): Promise<void> {
           e.speaker_id = $speaker_id,
           e.speaker_name = $speaker_name,
           e.personality = $personality,
+          e.session_id = $session_id,
           e.created_at = $created_at
     `,
     {
@@ -199,6 +197,7 @@ export async function saveEpisodicNode(node: EpisodicNode): Promise<void> {
       speaker_id: node.speaker_id,
       speaker_name: node.speaker_name,
       personality: node.personality,
+      session_id: node.session_id,
       created_at: node.created_at,
     },
   );
@@ -277,6 +276,7 @@ export async function getRecentEpisodes(groupId: string, limit = 10): Promise<Ep
              e.speaker_id AS speaker_id,
              e.speaker_name AS speaker_name,
              e.personality AS personality,
+             e.session_id AS session_id,
              e.created_at AS created_at
       ORDER BY e.created_at DESC
       LIMIT $limit
@@ -307,6 +307,7 @@ export async function getEpisodesByConversationId(
              e.speaker_id AS speaker_id,
              e.speaker_name AS speaker_name,
              e.personality AS personality,
+             e.session_id AS session_id,
              e.created_at AS created_at
       ORDER BY e.created_at DESC
     `,
@@ -342,6 +343,7 @@ export async function getEpisodesForEntities(
              episode.speaker_id AS speaker_id,
              episode.speaker_name AS speaker_name,
              episode.personality AS personality,
+             episode.session_id AS session_id,
              episode.created_at AS created_at
       ORDER BY episode.valid_at DESC
       LIMIT $limit
@@ -594,6 +596,7 @@ function parseEpisodicRecord(record: Record<string, unknown>): EpisodicNode {
       speaker_id: record.speaker_id ? String(record.speaker_id) : null,
       speaker_name: record.speaker_name ? String(record.speaker_name) : null,
       personality: record.personality ? String(record.personality) : null,
+      session_id: typeof record.session_id === "number" ? record.session_id : null,
     }),
     uuid: String(record.uuid ?? ""),
     created_at: parseDate(record.created_at) ?? new Date(),
@@ -632,9 +635,7 @@ function parseEntityRecord(record: Record<string, unknown>): EntityNode {
     uuid: String(record.uuid ?? ""),
     created_at: parseDate(record.created_at) ?? new Date(),
     expired_at: parseDate(record.expired_at),
-    name_embedding: Array.isArray(record.name_embedding)
-      ? (record.name_embedding as number[])
-      : null,
+    name_embedding: toEmbedding(record.name_embedding),
     last_mentioned: parseDate(record.last_mentioned),
     mention_count: parseNumber(record.mention_count, 1),
     retrieval_count: parseNumber(record.retrieval_count, 0),
@@ -672,9 +673,7 @@ function parseFactRecord(record: Record<string, unknown>): FactNode {
     name: String(record.name ?? record.fact ?? ""),
     created_at: parseDate(record.created_at) ?? new Date(),
     expired_at: parseDate(record.expired_at),
-    fact_embedding: Array.isArray(record.fact_embedding)
-      ? (record.fact_embedding as number[])
-      : null,
+    fact_embedding: toEmbedding(record.fact_embedding),
     supersedes: toStringArray(record.supersedes),
     superseded_by: toStringArray(record.superseded_by),
   };
@@ -715,9 +714,7 @@ function parseEntityEdgeRecord(record: Record<string, unknown>): EntityEdge {
     uuid: String(record.uuid ?? ""),
     created_at: parseDate(record.created_at) ?? new Date(),
     expired_at: parseDate(record.expired_at),
-    fact_embedding: Array.isArray(record.fact_embedding)
-      ? (record.fact_embedding as number[])
-      : null,
+    fact_embedding: toEmbedding(record.fact_embedding),
   };
 }
 
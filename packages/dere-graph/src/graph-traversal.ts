@@ -1,4 +1,4 @@
-import { queryGraph } from "./graph-helpers.js";
+import { queryGraph, toEmbedding } from "./graph-helpers.js";
 import type { EntityEdge, EntityNode } from "./graph-types.js";
 
 function toStringArray(value: unknown): string[] {
@@ -66,9 +66,7 @@ function parseEntityRecord(record: Record<string, unknown>): EntityNode {
     labels,
     created_at: parseDate(record.created_at) ?? new Date(),
     expired_at: parseDate(record.expired_at),
-    name_embedding: Array.isArray(record.name_embedding)
-      ? (record.name_embedding as number[])
-      : null,
+    name_embedding: toEmbedding(record.name_embedding),
     summary: typeof record.summary === "string" ? record.summary : "",
     attributes,
     aliases: toStringArray(record.aliases),
@@ -110,9 +108,7 @@ function parseEdgeRecord(record: Record<string, unknown>): EntityEdge {
     source_node_uuid: String(record.source_uuid ?? ""),
     target_node_uuid: String(record.target_uuid ?? ""),
     fact: String(record.fact ?? ""),
-    fact_embedding: Array.isArray(record.fact_embedding)
-      ? (record.fact_embedding as number[])
-      : null,
+    fact_embedding: toEmbedding(record.fact_embedding),
     episodes: toStringArray(record.episodes),
     created_at: parseDate(record.created_at) ?? new Date(),
     expired_at: parseDate(record.expired_at),
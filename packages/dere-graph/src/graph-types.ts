@@ -32,6 +32,8 @@ export type EpisodicNode = BaseNode & {
   speaker_id: string | null;
   speaker_name: string | null;
   personality: string | null;
+  /** The dere session the message came from, when ingested from one. */
+  session_id: number | null;
 };
 
 export type CommunityNode = BaseNode & {
@@ -133,6 +135,7 @@ export function createEpisodicNode(input: {
   speaker_id?: string | null;
   speaker_name?: string | null;
   personality?: string | null;
+  session_id?: number | null;
 }): EpisodicNode {
   return {
     uuid: newUuid(),
@@ -151,6 +154,7 @@ export function createEpisodicNode(input: {
     speaker_id: input.speaker_id ?? null,
     speaker_name: input.speaker_name ?? null,
     personality: input.personality ?? null,
+    session_id: input.session_id ?? null,
   };
 }
 
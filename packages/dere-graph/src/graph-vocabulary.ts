@@ -0,0 +1,120 @@
+export const DEFAULT_RELATION_TYPES: string[] = [
+  "USES",
+  "DEPENDS_ON",
+  "PART_OF",
+  "RELATED_TO",
+  "IMPLEMENTS",
+  "CREATED",
+  "OWNS",
+  "WORKS_ON",
+  "WORKS_AT",
+  "MEMBER_OF",
+  "KNOWS",
+  "LIKES",
+  "DISLIKES",
+  "PREFERS",
+  "LOCATED_IN",
+  "DEFINES",
+  "IMPORTS",
+  "CALLS",
+  "CONFIGURES",
+  "DOCUMENTS",
+];
+
+// Common LLM phrasings mapped onto their canonical relation type.
+const RELATION_SYNONYMS: Record<string, string> = {
+  UTILIZES: "USES",
+  EMPLOYS: "USES",
+  LEVERAGES: "USES",
+  USING: "USES",
+  REQUIRES: "DEPENDS_ON",
+  NEEDS: "DEPENDS_ON",
+  RELIES_ON: "DEPENDS_ON",
+  DEPENDS: "DEPENDS_ON",
+  BELONGS_TO: "PART_OF",
+  COMPONENT_OF: "PART_OF",
+  CONTAINED_IN: "PART_OF",
+  RELATES_TO: "RELATED_TO",
+  ASSOCIATED_WITH: "RELATED_TO",
+  CONNECTED_TO: "RELATED_TO",
+  IMPLEMENTED_BY: "IMPLEMENTS",
+  AUTHORED: "CREATED",
+  BUILT: "CREATED",
+  WROTE: "CREATED",
+  HAS: "OWNS",
+  WORKING_ON: "WORKS_ON",
+  EMPLOYED_BY: "WORKS_AT",
+  WORKS_FOR: "WORKS_AT",
+  ENJOYS: "LIKES",
+  LOVES: "LIKES",
+  HATES: "DISLIKES",
+  LIVES_IN: "LOCATED_IN",
+  BASED_IN: "LOCATED_IN",
+  DESCRIBES: "DOCUMENTS",
+  INVOKES: "CALLS",
+};
+
+const FALLBACK_RELATION_TYPE = "RELATED_TO";
+
+function canonicalize(value: string): string {
+  return value
+    .trim()
+    .replace(/[\s-]+/g, "_")
+    .replace(/([a-z])([A-Z])/g, "$1_$2")
+    .toUpperCase();
+}
+
+/**
+ * Resolve the relation vocabulary from `[dere_graph].relation_types`, falling
+ * back to the built-in set when unset or empty.
+ */
+export function resolveRelationVocabulary(graphConfig: Record<string, unknown>): string[] {
+  const configured = graphConfig.relation_types;
+  if (Array.isArray(configured)) {
+    const types = configured
+      .filter((item): item is string => typeof item === "string")
+      .map(canonicalize)
+      .filter(Boolean);
+    if (types.length > 0) {
+      return Array.from(new Set(types));
+    }
+  }
+  return DEFAULT_RELATION_TYPES;
+}
+
+/**
+ * Map a raw relation type onto the vocabulary. Returns null when nothing
+ * close exists and the vocabulary has no RELATED_TO catch-all.
+ */
+export function normalizeRelationType(raw: string, vocabulary: string[]): string | null {
+  const allowed = new Set(vocabulary);
+  const value = canonicalize(raw);
+  if (!value) {
+    return null;
+  }
+
+  const candidates = [value, RELATION_SYNONYMS[value]];
+  if (value.endsWith("S")) {
+    candidates.push(value.slice(0, -1));
+  } else {
+    candidates.push(`${value}S`);
+  }
+
+  for (const candidate of candidates) {
+    if (candidate && allowed.has(candidate)) {
+      return candidate;
+    }
+  }
+
+  return allowed.has(FALLBACK_RELATION_TYPE) ? FALLBACK_RELATION_TYPE : null;
+}
+
+/**
+ * A node label safe to splice into Cypher, which can't parameterize labels:
+ * anything but letters, digits and underscores becomes an underscore, and a
+ * leading digit gets an underscore in front since labels can't start with one.
+ */
+export function sanitizeLabel(label: string): string {
+  const cleaned = label.replace(/[^a-zA-Z0-9_]/g, "_");
+  return /^[0-9]/.test(cleaned) ? `_${cleaned}` : cleaned;
+}
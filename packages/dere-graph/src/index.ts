@@ -11,3 +11,4 @@ export * from "./graph-search.js";
 export * from "./graph-store.js";
 export * from "./graph-traversal.js";
 export * from "./graph-types.js";
+export * from "./graph-vocabulary.js";
@@ -0,0 +1,74 @@
+import { homedir } from "node:os";
+import { join } from "node:path";
+
+import { describe, expect, it } from "bun:test";
+
+import { captureExcludePaths, globToRegExp, isCaptureExcluded } from "./capture.js";
+
+describe("globToRegExp", () => {
+  it("keeps * and ? within one path segment", () => {
+    const re = globToRegExp("/work/*/secret?");
+    expect(re.test("/work/client/secret1")).toBe(true);
+    expect(re.test("/work/a/b/secret1")).toBe(false);
+    expect(re.test("/work/client/secret12")).toBe(false);
+  });
+
+  it("lets ** span directories, including none", () => {
+    const re = globToRegExp("/work/**/private");
+    expect(re.test("/work/private")).toBe(true);
+    expect(re.test("/work/a/b/private")).toBe(true);
+    expect(re.test("/work/a/b/public")).toBe(false);
+  });
+
+  it("matches regex metacharacters literally", () => {
+    const re = globToRegExp("/work/a.b+(c)");
+    expect(re.test("/work/a.b+(c)")).toBe(true);
+    expect(re.test("/work/aXbb(c)")).toBe(false);
+  });
+
+  it("anchors the whole path", () => {
+    const re = globToRegExp("/work/*");
+    expect(re.test("/home/work/x")).toBe(false);
+    expect(re.test("/work/x/y")).toBe(false);
+  });
+});
+
+describe("isCaptureExcluded", () => {
+  it("excludes a plain directory and everything below it", () => {
+    expect(isCaptureExcluded("/work/client", ["/work/client"])).toBe(true);
+    expect(isCaptureExcluded("/work/client/src/app", ["/work/client"])).toBe(true);
+    expect(isCaptureExcluded("/work/client/", ["/work/client/"])).toBe(true);
+  });
+
+  it("does not treat a shared name prefix as a parent", () => {
+    expect(isCaptureExcluded("/work/client-other", ["/work/client"])).toBe(false);
+    expect(isCaptureExcluded("/work", ["/work/client"])).toBe(false);
+  });
+
+  it("excludes directories below a glob match", () => {
+    const patterns = ["/work/*/secrets"];
+    expect(isCaptureExcluded("/work/acme/secrets", patterns)).toBe(true);
+    expect(isCaptureExcluded("/work/acme/secrets/keys", patterns)).toBe(true);
+    expect(isCaptureExcluded("/work/acme/public", patterns)).toBe(false);
+  });
+
+  it("normalizes the path before matching", () => {
+    expect(isCaptureExcluded("/work/other/../client/./src", ["/work/client"])).toBe(true);
+  });
+
+  it("excludes nothing without a path or patterns", () => {
+    expect(isCaptureExcluded("", ["/work"])).toBe(false);
+    expect(isCaptureExcluded("/work", [])).toBe(false);
+  });
+});
+
+describe("captureExcludePaths", () => {
+  it("expands ~ and drops blank entries", () => {
+    const config = { capture: { exclude_paths: ["~/private", " ", "/srv/x "] } };
+    expect(captureExcludePaths(config)).toEqual([join(homedir(), "private"), "/srv/x"]);
+  });
+
+  it("is empty when unset", () => {
+    expect(captureExcludePaths({})).toEqual([]);
+  });
+});
@@ -0,0 +1,107 @@
+import { homedir } from "node:os";
+import { dirname, join, resolve } from "node:path";
+
+import type { DereConfig } from "./schema.js";
+
+function expandHome(path: string): string {
+  if (path === "~") {
+    return homedir();
+  }
+  if (path.startsWith("~/")) {
+    return join(homedir(), path.slice(2));
+  }
+  return path;
+}
+
+/** `[capture].exclude_paths`, with `~` expanded; empty when unset. */
+export function captureExcludePaths(config: DereConfig): string[] {
+  const captureConfig = (config.capture ?? {}) as Record<string, unknown>;
+  const raw = captureConfig.exclude_paths;
+  if (!Array.isArray(raw)) {
+    return [];
+  }
+  return raw
+    .filter((entry): entry is string => typeof entry === "string" && entry.trim() !== "")
+    .map((entry) => expandHome(entry.trim()));
+}
+
+const DEFAULT_MIN_PROMPT_CHARS = 2;
+
+/** `[capture].min_prompt_chars`: shortest message worth storing (default 2). */
+export function captureMinPromptChars(config: DereConfig): number {
+  const captureConfig = (config.capture ?? {}) as Record<string, unknown>;
+  const value = Number(captureConfig.min_prompt_chars);
+  return Number.isFinite(value) && value >= 0 ? Math.floor(value) : DEFAULT_MIN_PROMPT_CHARS;
+}
+
+/**
+ * Whether a captured message has enough in it to store, embed, and extract
+ * entities from: at least `minChars` once trimmed, and not only punctuation.
+ * Hooks check this before sending and the daemon again before storing.
+ */
+export function isMeaningfulPrompt(prompt: string, minChars: number): boolean {
+  const trimmed = prompt.trim();
+  return trimmed.length > 0 && trimmed.length >= minChars && /[\p{L}\p{N}]/u.test(trimmed);
+}
+
+/** `[capture].tool_events`: record files touched and commands run (off by default). */
+export function captureToolEvents(config: DereConfig): boolean {
+  const captureConfig = (config.capture ?? {}) as Record<string, unknown>;
+  return captureConfig.tool_events === true;
+}
+
+/** An anchored regex for a path glob: `**` spans directories, `*` and `?` stay in one segment. */
+export function globToRegExp(pattern: string): RegExp {
+  let source = "";
+  for (let i = 0; i < pattern.length; i += 1) {
+    const char = pattern[i]!;
+    if (char === "*" && pattern[i + 1] === "*" && pattern[i + 2] === "/") {
+      source += "(?:.*/)?";
+      i += 2;
+    } else if (char === "*" && pattern[i + 1] === "*") {
+      source += ".*";
+      i += 1;
+    } else if (char === "*") {
+      source += "[^/]*";
+    } else if (char === "?") {
+      source += "[^/]";
+    } else {
+      source += char.replace(/[.+^${}()|[\]\\]/g, "\\$&");
+    }
+  }
+  return new RegExp(`^${source}$`);
+}
+
+/**
+ * Whether `path` falls under one of `patterns`. Plain entries match the
+ * directory and everything below it; entries with `*` or `?` are globs, and
+ * a directory matches when it or any of its ancestors does.
+ */
+export function isCaptureExcluded(path: string, patterns: string[]): boolean {
+  if (!path || patterns.length === 0) {
+    return false;
+  }
+  const target = resolve(path);
+  for (const pattern of patterns) {
+    if (/[*?]/.test(pattern)) {
+      const re = globToRegExp(resolve(pattern));
+      let current = target;
+      while (true) {
+        if (re.test(current)) {
+          return true;
+        }
+        const parent = dirname(current);
+        if (parent === current) {
+          break;
+        }
+        current = parent;
+      }
+      continue;
+    }
+    const prefix = resolve(pattern);
+    if (target === prefix || target.startsWith(prefix.endsWith("/") ? prefix : `${prefix}/`)) {
+      return true;
+    }
+  }
+  return false;
+}
@@ -121,6 +121,22 @@ export type UserID = string | null;
  * Custom announcement messages
  */
 export type Messages = string[];
+/**
+ * Never store anything from these directories; entries with * or ? are globs (** spans directories)
+ */
+export type ExcludePaths = string[];
+/**
+ * Drop shorter messages and ones with no letters or digits
+ */
+export type MinPromptChars = number;
+/**
+ * Record files read and edited and commands run, for session summaries
+ */
+export type ToolEvents = boolean;
+/**
+ * Extract follow-ups into dere todos when sessions are summarized
+ */
+export type ActionItems = boolean;
 /**
  * Include ActivityWatch data
  */
@@ -145,14 +161,30 @@ export type MaxDuration = number;
  * Minimum lookback for differential mode
  */
 export type MinLookback = number;
+/**
+ * Reuse built context this long, unless the session has new messages
+ */
+export type ContextCacheTTL = number;
 /**
  * Include calendar events
  */
 export type Calendar = boolean;
+/**
+ * Characters per token for estimates (CJK text counts one token per character)
+ */
+export type CharsPerToken = number;
 /**
  * Context output format
  */
 export type Format = string;
+/**
+ * Group sessions in a git repository's subdirectories under the repository root
+ */
+export type GroupByGitRoot = boolean;
+/**
+ * Put context before or after the prompt; a {{CONTEXT}} marker overrides
+ */
+export type InjectionPlacement = string;
 /**
  * Use knowledge graph context
  */
@@ -161,6 +193,10 @@ export type KnowledgeGraph = boolean;
  * Prefix XML context lines with numbers for precise editing
  */
 export type LineNumbers = boolean;
+/**
+ * Cap on the composed system prompt; larger prompts are cut at a paragraph break
+ */
+export type MaxSystemPromptBytes = number;
 /**
  * Truncate titles longer than this
  */
@@ -169,6 +205,34 @@ export type MaxTitleLength = number;
  * Show currently playing media
  */
 export type MediaPlayer = boolean;
+/**
+ * Knowledge graph entities per prompt
+ */
+export type MemoryEntities = number;
+/**
+ * Events per prompt (0 = whatever the entity search returns)
+ */
+export type MemoryEvents = number;
+/**
+ * Relationship facts per prompt (0 = whatever the entity search returns)
+ */
+export type MemoryFacts = number;
+/**
+ * Re-summarize after this many new messages (0 disables)
+ */
+export type PeriodicSummaryMessages = number;
+/**
+ * Re-summarize an active session this often (0 disables)
+ */
+export type PeriodicSummaryInterval = number;
+/**
+ * Tag injected context with the personality; off for plain context
+ */
+export type PersonalityFraming = boolean;
+/**
+ * Age at which a knowledge graph match's relevance is halved; 0 keeps the reranker's order
+ */
+export type RecencyHalfLife = number;
 /**
  * Show recently modified files
  */
@@ -185,6 +249,18 @@ export type MaxDepth = number;
  * How far back to look for files
  */
 export type RecentFilesTimeframe = string;
+/**
+ * Rebuild prompt-relevant memory mid-session at most this often (0 = startup context only)
+ */
+export type MemoryRefresh = number;
+/**
+ * Only the nearest N continued sessions (0 = the whole chain)
+ */
+export type SessionChainLimit = number;
+/**
+ * Include summaries from sessions this one continues (-c)
+ */
+export type SessionChainSummaries = boolean;
 /**
  * Show duration for short activities
  */
@@ -193,6 +269,14 @@ export type ShowDuration = boolean;
  * Show inactive context items
  */
 export type ShowInactive = boolean;
+/**
+ * Word budget for the rolling cross-session summary
+ */
+export type SummaryContextMaxWords = number;
+/**
+ * Word budget for session and task summaries; longer ones are trimmed
+ */
+export type SummaryMaxWords = number;
 /**
  * Include Taskwarrior tasks
  */
@@ -213,22 +297,66 @@ export type Weather = boolean;
  * Cache weather data for this long
  */
 export type WeatherCache = number;
+/**
+ * Database password kept out of the URL (DERE_DATABASE_PASSWORD also works)
+ */
+export type DatabasePassword = string;
+/**
+ * Raise a waiting queue task one priority level per this many minutes (0 disables)
+ */
+export type QueueAging = number;
+/**
+ * Delete sessions idle longer than this, daily (0 keeps everything)
+ */
+export type SessionRetention = number;
+/**
+ * Require TLS for the database connection
+ */
+export type DatabaseTLS = boolean;
+/**
+ * At startup, report dere transactions left idle this long
+ */
+export type StaleTransactionAge = number;
+/**
+ * End stale transactions instead of only reporting them
+ */
+export type TerminateStaleTransactions = boolean;
 /**
  * PostgreSQL connection string
  */
 export type DatabaseURL = string;
 /**
- * Global default personality
+ * Global default personality, or a list to combine several
+ */
+export type DefaultPersonality = string | string[];
+/**
+ * Only store entities of these types
  */
-export type DefaultPersonality = string;
+export type AllowedEntityTypes = string[];
 /**
  * Model for graph operations
  */
 export type ClaudeModel = string;
+/**
+ * Embedding provider
+ */
+export type EmbeddingBackend = string;
+/**
+ * Parallel embedding requests during backfill
+ */
+export type EmbeddingConcurrency = number;
 /**
  * Vector embedding dimension
  */
 export type EmbeddingDimension = number;
+/**
+ * Embedding model; switching re-embeds conversations in the background
+ */
+export type EmbeddingModel = string;
+/**
+ * Per-request limit for embedding calls
+ */
+export type EmbeddingTimeout = number;
 /**
  * Enable graph reflection processing
  */
@@ -237,6 +365,10 @@ export type EnableReflection = boolean;
  * Knowledge graph integration
  */
 export type EnableGraph = boolean;
+/**
+ * Never store entities of these types
+ */
+export type ExcludedEntityTypes = string[];
 /**
  * FalkorDB database name
  */
@@ -253,6 +385,18 @@ export type FalkorDBPort = number;
  * Idle time before reflection
  */
 export type IdleThreshold1 = number;
+/**
+ * Drop extracted entities below this confidence
+ */
+export type MinEntityConfidence = number;
+/**
+ * Allowed relationship types; others map to the nearest match or RELATED_TO
+ */
+export type RelationTypes = string[];
+/**
+ * Vector similarity metric; run `dere embeddings reindex` after changing
+ */
+export type VectorMetric = string;
 /**
  * Comma-separated channel IDs
  */
@@ -281,6 +425,18 @@ export type SummaryGrace = number;
  * Discord bot token
  */
 export type Token = string;
+/**
+ * Daemon log level (DERE_LOG_LEVEL overrides); info in production, debug otherwise
+ */
+export type LogLevel = string;
+/**
+ * Mode for sessions launched without --mode
+ */
+export type DefaultMode = string;
+/**
+ * Claude model for sessions launched without --model
+ */
+export type DefaultModel = string;
 /**
  * Directories for auto mode
  */
@@ -317,12 +473,17 @@ export interface DereConfig {
   activitywatch?: ActivityWatch;
   ambient?: Ambient;
   announcements?: AnnouncementsConfig;
+  capture?: Capture;
   context?: Context;
   database?: Database;
   default_personality?: DefaultPersonality;
   dere_graph?: KnowledgeGraph1;
   discord?: Discord;
+  log_level?: LogLevel;
+  mode?: DefaultMode;
+  model?: DefaultModel;
   plugins?: Plugins;
+  profiles?: Profiles;
   user?: User;
   user_id?: UserID1;
   weather?: Weather1;
@@ -375,28 +536,55 @@ export interface AnnouncementsConfig {
   messages?: Messages;
   [k: string]: unknown;
 }
+/**
+ * What gets stored from sessions
+ */
+export interface Capture {
+  exclude_paths?: ExcludePaths;
+  min_prompt_chars?: MinPromptChars;
+  tool_events?: ToolEvents;
+  [k: string]: unknown;
+}
 /**
  * Context gathering settings
  */
 export interface Context {
+  action_items?: ActionItems;
   activity?: Activity;
   activity_differential_enabled?: DifferentialMode;
   activity_full_lookback_threshold_minutes?: FullLookbackThreshold;
   activity_lookback_minutes?: ActivityLookback1;
   activity_max_duration_hours?: MaxDuration;
   activity_min_lookback_minutes?: MinLookback;
+  cache_ttl_minutes?: ContextCacheTTL;
   calendar?: Calendar;
+  chars_per_token?: CharsPerToken;
   format?: Format;
+  group_by_git_root?: GroupByGitRoot;
+  injection_placement?: InjectionPlacement;
   knowledge_graph?: KnowledgeGraph;
   line_numbered_xml?: LineNumbers;
+  max_system_prompt_bytes?: MaxSystemPromptBytes;
   max_title_length?: MaxTitleLength;
   media_player?: MediaPlayer;
+  memory_entities?: MemoryEntities;
+  memory_events?: MemoryEvents;
+  memory_facts?: MemoryFacts;
+  periodic_summary_messages?: PeriodicSummaryMessages;
+  periodic_summary_minutes?: PeriodicSummaryInterval;
+  personality_framing?: PersonalityFraming;
+  recency_half_life_days?: RecencyHalfLife;
   recent_files?: RecentFiles;
   recent_files_base_path?: BasePath;
   recent_files_max_depth?: MaxDepth;
   recent_files_timeframe?: RecentFilesTimeframe;
+  refresh_minutes?: MemoryRefresh;
+  session_chain_limit?: SessionChainLimit;
+  session_chain_summaries?: SessionChainSummaries;
   show_duration_for_short?: ShowDuration;
   show_inactive_items?: ShowInactive;
+  summary_context_max_words?: SummaryContextMaxWords;
+  summary_max_words?: SummaryMaxWords;
   tasks?: Tasks;
   time?: Time;
   update_interval_seconds?: UpdateInterval;
@@ -408,6 +596,12 @@ export interface Context {
  * Database connection
  */
 export interface Database {
+  password?: DatabasePassword;
+  queue_aging_minutes?: QueueAging;
+  session_retention_days?: SessionRetention;
+  ssl?: DatabaseTLS;
+  stale_transaction_minutes?: StaleTransactionAge;
+  terminate_stale_transactions?: TerminateStaleTransactions;
   url?: DatabaseURL;
   [k: string]: unknown;
 }
@@ -415,16 +609,36 @@ export interface Database {
  * Knowledge graph settings
  */
 export interface KnowledgeGraph1 {
+  allowed_entity_types?: AllowedEntityTypes;
   claude_model?: ClaudeModel;
+  embedding_backend?: EmbeddingBackend;
+  embedding_concurrency?: EmbeddingConcurrency;
   embedding_dim?: EmbeddingDimension;
+  embedding_model?: EmbeddingModel;
+  embedding_timeout_seconds?: EmbeddingTimeout;
   enable_reflection?: EnableReflection;
   enabled?: EnableGraph;
+  entity_display?: EntityDisplay;
+  excluded_entity_types?: ExcludedEntityTypes;
   falkor_database?: DatabaseName;
   falkor_host?: FalkorDBHost;
   falkor_port?: FalkorDBPort;
   idle_threshold_minutes?: IdleThreshold1;
+  min_entity_confidence?: MinEntityConfidence;
+  relation_types?: RelationTypes;
+  vector_metric?: VectorMetric;
   [k: string]: unknown;
 }
+/**
+ * Color and icon per entity type in `dere entities list`
+ */
+export interface EntityDisplay {
+  [k: string]: {
+    color?: string;
+    icon?: string;
+    [k: string]: unknown;
+  };
+}
 /**
  * Discord bot settings
  */
@@ -480,6 +694,46 @@ export interface DereVault {
   mode?: Mode;
   [k: string]: unknown;
 }
+/**
+ * Launch presets for `dere --profile=NAME`
+ */
+export interface Profiles {
+  [k: string]: LaunchProfile;
+}
+/**
+ * A `dere --profile` launch preset; flags passed explicitly still win.
+ */
+export interface LaunchProfile {
+  /**
+   * MCP servers by name, or "profile:x" / "tag:x"
+   */
+  mcp?: string | string[];
+  /**
+   * Mode, as with --mode
+   */
+  mode?: string;
+  /**
+   * Claude model, as with --model
+   */
+  model?: string;
+  /**
+   * Output style, as with --output-style
+   */
+  output_style?: string;
+  /**
+   * Claude permission mode
+   */
+  permission_mode?: string;
+  /**
+   * Personalities to combine
+   */
+  personalities?: string | string[];
+  /**
+   * A single personality
+   */
+  personality?: string;
+  [k: string]: unknown;
+}
 /**
  * User identity settings
  */
@@ -65,6 +65,9 @@ export async function loadConfigFile<TSchema extends z.ZodTypeAny>(
   return result.data;
 }
 
+export * from "./capture.js";
 export * from "./config.types.js";
 export { DereConfigSchema };
+export * from "./prompts.js";
 export * from "./storage.js";
+export * from "./tokens.js";
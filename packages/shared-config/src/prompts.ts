@@ -0,0 +1,42 @@
+import { readFile } from "node:fs/promises";
+import { dirname, join } from "node:path";
+
+import { getConfigPath } from "./storage.js";
+
+function templatesDir(): string {
+  return join(dirname(getConfigPath()), "prompts", "templates");
+}
+
+/**
+ * Fill `{{name}}` placeholders from `vars`. Unknown placeholders are left in
+ * place so a typo in a user template is visible in the output.
+ */
+export function renderPromptTemplate(
+  template: string,
+  vars: Record<string, string | number>,
+): string {
+  return template.replace(/\{\{\s*(\w+)\s*\}\}/g, (match, key: string) =>
+    key in vars ? String(vars[key]) : match,
+  );
+}
+
+/**
+ * Load ~/.config/dere/prompts/templates/<name>.txt, falling back to the
+ * built-in `fallback` when the file is missing or empty, and render it.
+ */
+export async function loadPromptTemplate(
+  name: string,
+  fallback: string,
+  vars: Record<string, string | number> = {},
+): Promise<string> {
+  let template = fallback;
+  try {
+    const text = await readFile(join(templatesDir(), `${name}.txt`), "utf-8");
+    if (text.trim()) {
+      template = text;
+    }
+  } catch {
+    // no override
+  }
+  return renderPromptTemplate(template, vars);
+}
@@ -1,6 +1,7 @@
 import { parse, stringify } from "@iarna/toml";
+import { existsSync } from "node:fs";
 import { mkdir, readFile, writeFile } from "node:fs/promises";
-import { dirname, join } from "node:path";
+import { dirname, join, resolve } from "node:path";
 import { homedir } from "node:os";
 import { z } from "zod";
 
@@ -29,6 +30,30 @@ export function getConfigPath(): string {
   return join(getConfigDir(), "config.toml");
 }
 
+export const PROJECT_CONFIG_FILENAME = ".dere.toml";
+
+/**
+ * Find the nearest project config, walking up from `startDir` and stopping at
+ * the enclosing git root (or the filesystem root when not in a repo).
+ */
+export function findProjectConfigPath(startDir: string = process.cwd()): string | null {
+  let current = resolve(startDir);
+  while (true) {
+    const candidate = join(current, PROJECT_CONFIG_FILENAME);
+    if (existsSync(candidate)) {
+      return candidate;
+    }
+    if (existsSync(join(current, ".git"))) {
+      return null;
+    }
+    const parent = dirname(current);
+    if (parent === current) {
+      return null;
+    }
+    current = parent;
+  }
+}
+
 function isPlainObject(value: unknown): value is Record<string, unknown> {
   return typeof value === "object" && value !== null && !Array.isArray(value);
 }
@@ -48,19 +73,18 @@ function deepMerge(
   return result;
 }
 
-export async function loadConfig(schema: z.ZodTypeAny = DereConfigSchema): Promise<DereConfig> {
-  const configPath = getConfigPath();
-  let rawData: Record<string, unknown> = {};
-
+async function readTomlRecord(path: string): Promise<Record<string, unknown>> {
   try {
-    const text = await readFile(configPath, "utf-8");
-    rawData = parse(text) as Record<string, unknown>;
-  } catch (error) {
-    if ((error as NodeJS.ErrnoException).code !== "ENOENT") {
-      // Log-style error handling: return defaults on read/parse failure.
-      rawData = {};
-    }
+    const text = await readFile(path, "utf-8");
+    return parse(text) as Record<string, unknown>;
+  } catch {
+    // Missing or unparseable files contribute nothing; callers fall back to defaults.
+    return {};
   }
+}
+
+export async function loadConfig(schema: z.ZodTypeAny = DereConfigSchema): Promise<DereConfig> {
+  const rawData = await readTomlRecord(getConfigPath());
 
   try {
     return schema.parse(rawData) as DereConfig;
@@ -70,6 +94,30 @@ export async function loadConfig(schema: z.ZodTypeAny = DereConfigSchema): Promi
   }
 }
 
+/**
+ * Load the global config with the nearest `.dere.toml` merged over it.
+ *
+ * Precedence, highest first: CLI flags (applied by the caller), project
+ * config, global config. Only the CLI should use this; the daemon serves many
+ * projects at once and reads the global config via loadConfig().
+ */
+export async function loadProjectConfig(
+  cwd: string = process.cwd(),
+  schema: z.ZodTypeAny = DereConfigSchema,
+): Promise<DereConfig> {
+  const globalData = await readTomlRecord(getConfigPath());
+  const projectPath = findProjectConfigPath(cwd);
+  const rawData = projectPath
+    ? deepMerge(globalData, await readTomlRecord(projectPath))
+    : globalData;
+
+  try {
+    return schema.parse(rawData) as DereConfig;
+  } catch {
+    return loadConfig(schema);
+  }
+}
+
 export async function saveConfig(
   updates: Record<string, unknown>,
   schema: z.ZodTypeAny = DereConfigSchema,
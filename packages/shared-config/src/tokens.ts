@@ -0,0 +1,22 @@
+import type { DereConfig } from "./schema.js";
+
+export const DEFAULT_CHARS_PER_TOKEN = 4;
+
+// CJK, kana, and hangul run about one token per character regardless of ratio.
+const WIDE_CHAR_RE = /[぀-ヿ㐀-鿿가-힯豈-﫿]/g;
+
+/** `[context].chars_per_token`, the ratio for estimating token counts (default 4). */
+export function contextCharsPerToken(config: DereConfig): number {
+  const contextConfig = (config.context ?? {}) as Record<string, unknown>;
+  const value = Number(contextConfig.chars_per_token);
+  return Number.isFinite(value) && value > 0 ? value : DEFAULT_CHARS_PER_TOKEN;
+}
+
+/**
+ * Approximate token count: wide (CJK) characters count as one token each and
+ * everything else uses the chars-per-token ratio.
+ */
+export function estimateTokens(text: string, charsPerToken = DEFAULT_CHARS_PER_TOKEN): number {
+  const wide = text.match(WIDE_CHAR_RE)?.length ?? 0;
+  return wide + Math.ceil((text.length - wide) / charsPerToken);
+}
@@ -22,6 +22,8 @@ export interface StructuredOutputRequestOptions {
   outputFormat?: JsonSchemaOutputFormat;
   schema?: z.ZodTypeAny;
   schemaName?: string;
+  /** Instructions sent in the system role, separate from the prompt content */
+  systemPrompt?: string;
   workingDirectory?: string;
   /** Specify which built-in tools are available - array of tool names or preset */
   tools?: string[] | { type: "preset"; preset: "claude_code" };
@@ -95,6 +97,7 @@ export class StructuredOutputClient {
           ...(model ? { model } : {}),
           ...(outputFormat ? { outputFormat } : {}),
           ...(schemaName ? { schemaName } : {}),
+          ...(overrides.systemPrompt ? { systemPrompt: overrides.systemPrompt } : {}),
           ...(workingDirectory ? { workingDirectory } : {}),
           ...(overrides.tools ? { tools: overrides.tools } : {}),
           ...(overrides.allowedTools ? { allowedTools: overrides.allowedTools } : {}),
@@ -185,4 +188,17 @@ export class StructuredOutputClient {
 
     throw new StructuredOutputError("Structured output generation failed", lastError);
   }
+
+  /**
+   * Generate with instructions in the system role and content in the user role,
+   * so model-facing instructions aren't mixed in with the text being analyzed.
+   */
+  async generateChat<TSchema extends z.ZodTypeAny>(
+    system: string,
+    user: string,
+    schema: TSchema,
+    overrides: StructuredOutputRequestOptions = {},
+  ): Promise<z.infer<TSchema>> {
+    return this.generate(user, schema, { ...overrides, systemPrompt: system });
+  }
 }
@@ -6,6 +6,7 @@ import addFormats from "ajv-formats";
 import { describe, expect, test } from "bun:test";
 
 import {
+  ActionItemsResultSchema,
   AmbientEngagementDecisionSchema,
   AmbientMissionDecisionSchema,
   AppraisalOutputSchema,
@@ -49,6 +50,11 @@ const registry = [
     schema: SessionTitleResultSchema,
     fixture: "session_title_result.json",
   },
+  {
+    name: "action_items_result",
+    schema: ActionItemsResultSchema,
+    fixture: "action_items_result.json",
+  },
 ];
 
 const ajv = new Ajv({ allErrors: true, strict: false });
@@ -112,7 +112,7 @@ export function buildAppraisalPrompt(args: {
   goals: OCCGoal[];
   standards: OCCStandard[];
   attitudes: OCCAttitude[];
-}): string {
+}): { system: string; user: string } {
   const current = args.currentEmotionState;
   let currentEmotionStr = `User's current primary emotion: ${current.primary.name} (${current.primary.type}) at intensity ${current.intensity}.`;
   if (current.secondary) {
@@ -147,11 +147,10 @@ export function buildAppraisalPrompt(args: {
   const appraisalTask = formatAppraisalTask(args.personaPrompt);
   const responseSchema = formatResponseSchema();
 
-  return `${appraisalTask}
-
-${userProfile}
+  return {
+    system: `${appraisalTask}\n\n${responseSchema}`,
+    user: `${userProfile}
 Current: ${currentEmotionStr}
-${contextStr}Stimulus: ${stimulusStr}
-
-${responseSchema}`;
+${contextStr}Stimulus: ${stimulusStr}`,
+  };
 }
@@ -248,6 +248,10 @@ export const SessionTitleResultSchema = z.object({
   title: z.string(),
 });
 
+export const ActionItemsResultSchema = z.object({
+  action_items: z.array(z.string()).default([]),
+});
+
 export type OCCEmotionType = z.infer<typeof OCCEmotionTypeSchema>;
 export type EventOutcome = z.infer<typeof EventOutcomeSchema>;
 export type AgentAction = z.infer<typeof AgentActionSchema>;
@@ -259,3 +263,4 @@ export type AmbientMissionDecision = z.infer<typeof AmbientMissionDecisionSchema
 export type ExplorationOutput = z.infer<typeof ExplorationOutputSchema>;
 export type ScheduleParseResult = z.infer<typeof ScheduleParseResultSchema>;
 export type SessionTitleResult = z.infer<typeof SessionTitleResultSchema>;
+export type ActionItemsResult = z.infer<typeof ActionItemsResultSchema>;
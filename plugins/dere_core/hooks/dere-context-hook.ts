@@ -1,22 +1,25 @@
-import { appendFileSync, existsSync } from "node:fs";
-import { writeFile } from "node:fs/promises";
+import { existsSync } from "node:fs";
+import { readFile, writeFile } from "node:fs/promises";
 
 import { daemonRequest } from "../lib/daemon-client.ts";
+import { createDebugLog } from "../lib/debug-log.ts";
+import { projectContextConfig } from "../lib/project-context.ts";
 
 const DEFAULT_DOCS_TIMEOUT_MS = 10_000;
 const DEFAULT_CONTEXT_TIMEOUT_MS = 5_000;
+const DEFAULT_MEMORY_TIMEOUT_MS = 10_000;
+const DEFAULT_MEMORY_REFRESH_MINUTES = 10;
 
-function logError(message: string): void {
-  try {
-    const timestamp = new Date().toISOString().replace("T", " ").replace("Z", "");
-    appendFileSync("/tmp/dere_context_hook.log", `[${timestamp}] ${message}\n`);
-  } catch {
-    // ignore logging failures
-  }
+const logError = createDebugLog("context_hook");
+
+// Set by the CLI for projects under [capture].exclude_paths. Reading context
+// is fine, but nothing that records the session may reach the daemon.
+function captureDisabled(): boolean {
+  return process.env.DERE_NO_CAPTURE === "1";
 }
 
 async function loadInitialDocuments(sessionId: number | null): Promise<void> {
-  if (!sessionId) {
+  if (!sessionId || captureDisabled()) {
     return;
   }
 
@@ -99,13 +102,80 @@ async function getContextFromDaemon(sessionId: number | null): Promise<string |
   }
 }
 
+type MemoryState = { builtAt: number; context: string };
+
+function memoryRefreshMs(): number {
+  const raw = process.env.DERE_CONTEXT_REFRESH_MINUTES;
+  const minutes = raw === undefined || raw === "" ? DEFAULT_MEMORY_REFRESH_MINUTES : Number(raw);
+  return Number.isFinite(minutes) && minutes > 0 ? minutes * 60_000 : 0;
+}
+
+async function readMemoryState(path: string): Promise<MemoryState | null> {
+  try {
+    return JSON.parse(await readFile(path, "utf-8")) as MemoryState;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Memory relevant to the current prompt, rebuilt at most once per refresh
+ * interval ([context].refresh_minutes) and only returned when it differs
+ * from what this session was last given, so the same block isn't re-injected
+ * every turn.
+ */
+async function getPromptMemory(sessionId: number | null, prompt: string): Promise<string | null> {
+  const refreshMs = memoryRefreshMs();
+  if (!sessionId || !prompt.trim() || refreshMs === 0 || captureDisabled()) {
+    return null;
+  }
+
+  const stateFile = `/tmp/dere_prompt_memory_${sessionId}.json`;
+  const state = await readMemoryState(stateFile);
+  if (state && Date.now() - state.builtAt < refreshMs) {
+    return null;
+  }
+
+  try {
+    const { status, data } = await daemonRequest<{ status?: string; context?: string }>({
+      path: "/context/build",
+      method: "POST",
+      // No user_id: captured prompts go to the daemon's default graph group,
+      // so memory has to be searched there too.
+      body: {
+        session_id: sessionId,
+        project_path: process.cwd(),
+        current_prompt: prompt,
+        context_config: projectContextConfig(),
+      },
+      timeoutMs: DEFAULT_MEMORY_TIMEOUT_MS,
+    });
+    if (status < 200 || status >= 300) {
+      logError(`Failed to build prompt memory: ${status}`);
+      return null;
+    }
+
+    const context = data?.status === "ready" ? (data.context ?? "").trim() : "";
+    await writeFile(stateFile, JSON.stringify({ builtAt: Date.now(), context }), { mode: 0o600 });
+    if (!context || context === state?.context) {
+      return null;
+    }
+    return `<relevant_memory>\n${context}\n</relevant_memory>`;
+  } catch (error) {
+    logError(`Failed to build prompt memory: ${String(error)}`);
+    return null;
+  }
+}
+
 async function main(): Promise<void> {
+  let prompt = "";
   try {
     const stdin = await Bun.stdin.text();
     if (!stdin) {
       return;
     }
-    JSON.parse(stdin);
+    const input = JSON.parse(stdin) as { prompt?: unknown };
+    prompt = typeof input.prompt === "string" ? input.prompt : "";
   } catch (error) {
     logError(`Error reading input: ${String(error)}`);
     return;
@@ -117,7 +187,15 @@ async function main(): Promise<void> {
 
     await loadInitialDocuments(sessionIdValue);
 
-    const contextStr = await getContextFromDaemon(sessionIdValue);
+    // dere --fast: no per-prompt context either
+    const contextEnabled = process.env.DERE_CONTEXT_MODE !== "none";
+    const [baseContext, promptMemory] = contextEnabled
+      ? await Promise.all([
+          getContextFromDaemon(sessionIdValue),
+          getPromptMemory(sessionIdValue, prompt),
+        ])
+      : [null, null];
+    const contextStr = [baseContext, promptMemory].filter(Boolean).join("\n\n");
     if (contextStr) {
       const output = {
         hookSpecificOutput: {
@@ -1,14 +1,7 @@
-import { appendFileSync } from "node:fs";
-
 import { RPCClient } from "./rpc_client.js";
+import { createDebugLog } from "../lib/debug-log.ts";
 
-function logDebug(message: string): void {
-  try {
-    appendFileSync("/tmp/dere_session_end_debug.log", `${message}\n`);
-  } catch {
-    // ignore logging failures
-  }
-}
+const logDebug = createDebugLog("session_end_hook");
 
 async function main(): Promise<void> {
   const timestamp = new Date().toLocaleString();
@@ -1,18 +1,20 @@
-import { appendFileSync } from "node:fs";
 import { stat } from "node:fs/promises";
 
-import { daemonRequest } from "../lib/daemon-client.ts";
+import { daemonRequest, isRetryableError } from "../lib/daemon-client.ts";
+import { createDebugLog } from "../lib/debug-log.ts";
+import { projectContextConfig } from "../lib/project-context.ts";
 
 const DEFAULT_CONTEXT_TIMEOUT_MS = 10_000;
+// Ask the daemon to give up waiting well before the request timeout so a slow
+// graph search never stalls session start.
+const DEFAULT_CONTEXT_DEADLINE_MS = 3_000;
+const RETRY_DELAY_MS = 500;
+// A build still running at the deadline keeps going on the daemon; ask again
+// a couple of times, joining that build, before starting without context.
+const BUILDING_POLLS = 2;
+const BUILDING_POLL_DEADLINE_MS = 1_500;
 
-function logError(message: string): void {
-  try {
-    const timestamp = new Date().toISOString().replace("T", " ").replace("Z", "");
-    appendFileSync("/tmp/dere_session_context_hook.log", `[${timestamp}] ${message}\n`);
-  } catch {
-    // ignore logging failures
-  }
-}
+const logError = createDebugLog("session_start_context_hook");
 
 async function isValidDirectory(path: string): Promise<boolean> {
   try {
@@ -33,6 +35,7 @@ async function getSessionStartContext(args: {
     const payload: Record<string, unknown> = {
       session_id: args.sessionId,
       user_id: args.userId,
+      deadline_ms: DEFAULT_CONTEXT_DEADLINE_MS,
     };
     if (args.workingDir) {
       payload.working_dir = args.workingDir;
@@ -40,22 +43,52 @@ async function getSessionStartContext(args: {
     if (args.medium) {
       payload.medium = args.medium;
     }
+    if (process.env.DERE_SESSION_TYPE) {
+      payload.session_type = process.env.DERE_SESSION_TYPE;
+    }
+    if (process.env.DERE_CONTEXT_MODE) {
+      payload.context_mode = process.env.DERE_CONTEXT_MODE;
+    }
+    const contextConfig = projectContextConfig();
+    if (contextConfig) {
+      payload.context_config = contextConfig;
+    }
 
-    const { status, data } = await daemonRequest<{
-      status?: string;
-      context?: string;
-    }>({
-      path: "/context/build_session_start",
-      method: "POST",
-      body: payload,
-      timeoutMs: DEFAULT_CONTEXT_TIMEOUT_MS,
-    });
+    const request = () =>
+      daemonRequest<{
+        status?: string;
+        context?: string;
+        code?: string;
+      }>({
+        path: "/context/build_session_start",
+        method: "POST",
+        body: payload,
+        timeoutMs: DEFAULT_CONTEXT_TIMEOUT_MS,
+      });
+
+    let { status, data } = await request();
+    if (isRetryableError(status, data)) {
+      logError(`Session-start context unavailable (${String(data?.code)}), retrying once`);
+      await new Promise((resolve) => setTimeout(resolve, RETRY_DELAY_MS));
+      ({ status, data } = await request());
+    }
 
     if (status < 200 || status >= 300) {
-      logError(`Failed to get session-start context from daemon: ${status}`);
+      // Permanent failures (or a second transient one): start without context.
+      const code = data?.code ? ` (${data.code})` : "";
+      logError(`Failed to get session-start context from daemon: ${status}${code}`);
       return null;
     }
 
+    for (let poll = 0; poll < BUILDING_POLLS && data?.status === "building"; poll += 1) {
+      payload.deadline_ms = BUILDING_POLL_DEADLINE_MS;
+      ({ status, data } = await request());
+      if (status < 200 || status >= 300) {
+        logError(`Session-start context poll failed: ${status}`);
+        return null;
+      }
+    }
+
     if (data?.status === "ready" || data?.status === "cached") {
       return data.context ?? null;
     }
@@ -68,6 +101,27 @@ async function getSessionStartContext(args: {
   }
 }
 
+/**
+ * Record Claude's own session id on the dere session so `dere --list-sessions`
+ * and `dere -r` can resume it. Runs after the context request, which creates
+ * the session row if the CLI didn't reserve one.
+ */
+async function recordClaudeSessionId(sessionId: number, claudeSessionId: string): Promise<void> {
+  try {
+    const { status } = await daemonRequest({
+      path: `/sessions/${sessionId}/claude_session`,
+      method: "POST",
+      body: { claude_session_id: claudeSessionId },
+      timeoutMs: DEFAULT_CONTEXT_TIMEOUT_MS,
+    });
+    if (status < 200 || status >= 300) {
+      logError(`Failed to record Claude session id: ${status}`);
+    }
+  } catch (error) {
+    logError(`Failed to record Claude session id: ${String(error)}`);
+  }
+}
+
 async function main(): Promise<void> {
   let stdinJson: Record<string, unknown> | null = null;
   try {
@@ -90,6 +144,12 @@ async function main(): Promise<void> {
       console.log(JSON.stringify({ suppressOutput: true }));
       return;
     }
+    // Set by the CLI for projects under [capture].exclude_paths; building the
+    // context would record the session.
+    if (process.env.DERE_NO_CAPTURE === "1") {
+      console.log(JSON.stringify({ suppressOutput: true }));
+      return;
+    }
 
     const sessionId = sessionIdValue;
     const workingDirEnv = process.env.PWD;
@@ -108,6 +168,11 @@ async function main(): Promise<void> {
       workingDir,
       medium,
     });
+    const claudeSessionId =
+      typeof stdinJson?.session_id === "string" ? stdinJson.session_id.trim() : "";
+    if (claudeSessionId) {
+      await recordClaudeSessionId(sessionId, claudeSessionId);
+    }
 
     if (contextStr && contextStr.trim()) {
       const output = {
@@ -1,7 +1,7 @@
-import { appendFileSync } from "node:fs";
 import { readFile } from "node:fs/promises";
 
 import { RPCClient } from "./rpc_client.js";
+import { createDebugLog } from "../lib/debug-log.ts";
 
 type TranscriptEntry = {
   type?: string;
@@ -11,13 +11,7 @@ type TranscriptEntry = {
   };
 };
 
-function logDebug(message: string): void {
-  try {
-    appendFileSync("/tmp/dere_stop_hook_debug.log", `${message}\n`);
-  } catch {
-    // ignore logging failures
-  }
-}
+const logDebug = createDebugLog("stop_hook");
 
 async function readTranscript(transcriptPath: string): Promise<TranscriptEntry[]> {
   try {
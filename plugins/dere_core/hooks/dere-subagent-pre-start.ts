@@ -1,10 +1,10 @@
 import { parse } from "@iarna/toml";
-import { appendFileSync } from "node:fs";
 import { readFile } from "node:fs/promises";
 import { dirname, join } from "node:path";
 
 import { getConfigPath } from "@dere/shared-config";
 import { RPCClient } from "./rpc_client.js";
+import { createDebugLog } from "../lib/debug-log.ts";
 
 type PersonalityConfig = {
   identity?: {
@@ -15,13 +15,7 @@ type PersonalityConfig = {
   standards?: string[];
 };
 
-function logDebug(message: string): void {
-  try {
-    appendFileSync("/tmp/dere_subagent_pre_start_debug.log", `${message}\n`);
-  } catch {
-    // ignore logging failures
-  }
-}
+const logDebug = createDebugLog("subagent_pre_start_hook");
 
 function getEmbeddedDir(): string {
   return (
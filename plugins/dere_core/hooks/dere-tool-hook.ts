@@ -0,0 +1,40 @@
+import { captureToolEvents, loadConfig } from "@dere/shared-config";
+
+import { RPCClient } from "./rpc_client.js";
+import { createDebugLog } from "../lib/debug-log.ts";
+
+const logDebug = createDebugLog("tool_hook");
+
+async function toolEventsEnabled(): Promise<boolean> {
+  try {
+    return captureToolEvents(await loadConfig());
+  } catch {
+    return false;
+  }
+}
+
+async function main(): Promise<void> {
+  // Not a dere session, or [capture].tool_events is off - nothing to record
+  const sessionId = Number.parseInt(process.env.DERE_SESSION_ID ?? "0", 10);
+  if (!sessionId || !process.env.DERE_PERSONALITY || !(await toolEventsEnabled())) {
+    console.log(JSON.stringify({ suppressOutput: true }));
+    return;
+  }
+
+  try {
+    const data = JSON.parse(await Bun.stdin.text()) as Record<string, unknown>;
+    const toolName = typeof data.tool_name === "string" ? data.tool_name : "";
+    if (toolName) {
+      const result = await new RPCClient().captureToolEvent(sessionId, toolName, data.tool_input);
+      logDebug(`${toolName}: ${JSON.stringify(result)}`);
+    }
+  } catch (error) {
+    // Fail silently - a missed tool event isn't worth interrupting the session
+    logDebug(`Error: ${String(error)}`);
+  }
+  console.log(JSON.stringify({ suppressOutput: true }));
+}
+
+if (import.meta.main) {
+  void main();
+}
@@ -2,6 +2,8 @@ import { readFile } from "node:fs/promises";
 import { homedir } from "node:os";
 import { join } from "node:path";
 
+import { estimateTokens } from "../lib/tokens.ts";
+
 /**
  * Simple TOML parser for personality files.
  * Only extracts [prompt].content - not a full TOML parser.
@@ -66,10 +68,6 @@ function parsePersonalityToml(text: string): { prompt?: { content?: string } } {
   return {};
 }
 
-function estimateTokens(text: string): number {
-  return Math.floor(text.length / 4);
-}
-
 function getCompressedReminder(prompt: string): string {
   const lines = prompt.trim().split("\n");
   for (const rawLine of lines) {
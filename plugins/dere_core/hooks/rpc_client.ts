@@ -1,9 +1,19 @@
+import { captureMinPromptChars, isMeaningfulPrompt, loadConfig } from "@dere/shared-config";
+
 import { daemonRequest } from "../lib/daemon-client.ts";
 
 type JsonRecord = Record<string, unknown>;
 
 const REQUEST_TIMEOUT_MS = 2_000;
 
+async function minPromptChars(): Promise<number> {
+  try {
+    return captureMinPromptChars(await loadConfig());
+  } catch {
+    return captureMinPromptChars({});
+  }
+}
+
 export class RPCClient {
   private async call(endpoint: string, params?: JsonRecord): Promise<JsonRecord | null> {
     const { status, data } = await daemonRequest<JsonRecord>({
@@ -31,6 +41,26 @@ export class RPCClient {
     prompt: string,
     messageType: "user" | "assistant" = "user",
   ): Promise<JsonRecord | null> {
+    // Set by the CLI for projects under [capture].exclude_paths; the daemon
+    // refuses those captures too, but they never need to leave the machine.
+    if (process.env.DERE_NO_CAPTURE === "1") {
+      return { status: "excluded" };
+    }
+    // Nothing worth a round trip; slash commands are stored whatever their length.
+    const isCommand = prompt.trimStart().startsWith("/");
+    if (!isCommand && !isMeaningfulPrompt(prompt, await minPromptChars())) {
+      return { status: "skipped" };
+    }
+    // Set by the CLI for --no-entities/--no-summary/--no-embeddings and --bare.
+    const disabledTasks = (process.env.DERE_DISABLED_TASKS ?? "")
+      .split(",")
+      .map((task) => task.trim())
+      .filter(Boolean);
+    // Set by the CLI for --tag.
+    const tags = (process.env.DERE_SESSION_TAGS ?? "")
+      .split(",")
+      .map((tag) => tag.trim())
+      .filter(Boolean);
     return this.call("/conversation/capture", {
       session_id: sessionId,
       personality,
@@ -38,6 +68,10 @@ export class RPCClient {
       prompt,
       message_type: messageType,
       is_command: false,
+      ...(disabledTasks.length > 0 ? { disabled_tasks: disabledTasks } : {}),
+      ...(tags.length > 0 ? { tags } : {}),
+      ...(process.env.DERE_MODE ? { mode: process.env.DERE_MODE } : {}),
+      ...(process.env.DERE_CONTEXT_HINT ? { context_hint: process.env.DERE_CONTEXT_HINT } : {}),
     });
   }
 
@@ -50,6 +84,21 @@ export class RPCClient {
     return this.captureConversation(sessionId, personality, projectPath, response, "assistant");
   }
 
+  async captureToolEvent(
+    sessionId: number,
+    toolName: string,
+    toolInput: unknown,
+  ): Promise<JsonRecord | null> {
+    if (process.env.DERE_NO_CAPTURE === "1") {
+      return { status: "excluded" };
+    }
+    return this.call("/conversation/tool-event", {
+      session_id: sessionId,
+      tool_name: toolName,
+      tool_input: toolInput,
+    });
+  }
+
   async endSession(sessionId: number, exitReason = "normal"): Promise<JsonRecord | null> {
     return this.call("/sessions/end", { session_id: sessionId, exit_reason: exitReason });
   }
@@ -37,3 +37,15 @@ export async function daemonRequest<T = Record<string, unknown>>(args: {
     clearTimeout(timeout);
   }
 }
+
+/**
+ * True when the daemon reported a transient failure (database or model
+ * unavailable) that is worth retrying, as opposed to a permanent one.
+ */
+export function isRetryableError(status: number, data: unknown): boolean {
+  if (status < 400) {
+    return false;
+  }
+  const body = data as { retryable?: unknown } | null;
+  return body?.retryable === true;
+}
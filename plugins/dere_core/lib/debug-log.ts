@@ -0,0 +1,53 @@
+import { appendFileSync, chmodSync, mkdirSync, renameSync, statSync } from "node:fs";
+import { homedir } from "node:os";
+import { dirname, join } from "node:path";
+
+const MAX_LOG_BYTES = 10 * 1024 * 1024;
+
+function debugEnabled(): boolean {
+  const value = process.env.DERE_DEBUG?.trim().toLowerCase();
+  return !(value === "0" || value === "false" || value === "off");
+}
+
+function resolveLogPath(name: string): string {
+  // Inline data dir logic to avoid @dere/shared-config dependency
+  const override = process.env.DERE_DEBUG_LOG?.trim();
+  if (override) {
+    return override;
+  }
+  const dataDir = process.env.XDG_DATA_HOME ?? join(homedir(), ".local", "share");
+  return join(dataDir, "dere", "logs", `${name}.log`);
+}
+
+function rotateIfNeeded(path: string): void {
+  try {
+    if (statSync(path).size > MAX_LOG_BYTES) {
+      renameSync(path, `${path}.1`);
+    }
+  } catch {
+    // missing file is fine
+  }
+}
+
+/**
+ * Create a debug logger for a hook. Writes to DERE_DEBUG_LOG, or
+ * ~/.local/share/dere/logs/<name>.log, with owner-only permissions and a
+ * single rolled backup past 10MB. DERE_DEBUG=0 disables it entirely.
+ */
+export function createDebugLog(name: string): (message: string) => void {
+  return (message: string) => {
+    if (!debugEnabled()) {
+      return;
+    }
+    try {
+      const path = resolveLogPath(name);
+      mkdirSync(dirname(path), { recursive: true, mode: 0o700 });
+      rotateIfNeeded(path);
+      const timestamp = new Date().toISOString().replace("T", " ").replace("Z", "");
+      appendFileSync(path, `[${timestamp}] [${name}] ${message}\n`, { mode: 0o600 });
+      chmodSync(path, 0o600);
+    } catch {
+      // ignore logging failures
+    }
+  };
+}
@@ -0,0 +1,19 @@
+/**
+ * The project's [context] table, set by the CLI from the nearest .dere.toml.
+ * The daemon reads only the global config, so context builds forward this
+ * for the project's values to apply.
+ */
+export function projectContextConfig(): Record<string, unknown> | null {
+  const raw = process.env.DERE_PROJECT_CONTEXT;
+  if (!raw) {
+    return null;
+  }
+  try {
+    const value = JSON.parse(raw) as unknown;
+    return value && typeof value === "object" && !Array.isArray(value)
+      ? (value as Record<string, unknown>)
+      : null;
+  } catch {
+    return null;
+  }
+}
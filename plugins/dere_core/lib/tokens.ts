@@ -0,0 +1,12 @@
+import { DEFAULT_CHARS_PER_TOKEN, estimateTokens as estimateWithRatio } from "@dere/shared-config";
+
+function charsPerToken(): number {
+  // Set by the CLI from [context].chars_per_token
+  const value = Number(process.env.DERE_CHARS_PER_TOKEN);
+  return Number.isFinite(value) && value > 0 ? value : DEFAULT_CHARS_PER_TOKEN;
+}
+
+/** Approximate token count using the configured chars-per-token ratio. */
+export function estimateTokens(text: string): number {
+  return estimateWithRatio(text, charsPerToken());
+}
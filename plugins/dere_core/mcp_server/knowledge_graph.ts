@@ -579,6 +579,143 @@ server.registerTool(
   },
 );
 
+const SearchMemorySchema = z.object({
+  query: z.string(),
+  limit: z.number().int().optional().default(10),
+  project: z.string().optional(),
+  from: z.enum(["user", "assistant"]).optional(),
+  context_window: z.number().int().min(0).max(5).optional(),
+});
+
+server.registerTool(
+  "search_memory",
+  {
+    description:
+      "Semantic search over past conversation messages. Returns the closest matches with their session ids.",
+    inputSchema: SearchMemorySchema.shape,
+  },
+  async (args) => {
+    const parsed = SearchMemorySchema.parse(args);
+    const data = await requestJson<JsonRecord>({
+      path: "/search/conversations",
+      method: "POST",
+      body: {
+        query: parsed.query,
+        limit: parsed.limit,
+        working_dir: parsed.project ?? null,
+        message_type: parsed.from ?? null,
+        context_window: parsed.context_window ?? 0,
+      },
+    });
+
+    const results = Array.isArray(data.results) ? data.results : [];
+    if (!results.length) {
+      return { content: [{ type: "text", text: `No past messages found for '${parsed.query}'` }] };
+    }
+
+    const parts: string[] = [`## Past conversations matching '${parsed.query}'\n`];
+    for (const item of results as Array<Record<string, any>>) {
+      const ts = typeof item.timestamp === "number" ? item.timestamp : 0;
+      const when = ts ? new Date(ts * 1000).toISOString().slice(0, 10) : "unknown date";
+      const similarity = typeof item.similarity === "number" ? item.similarity.toFixed(2) : "?";
+      const role = item.message_type ?? "unknown";
+      const session = item.session_id ?? "?";
+      parts.push(`- [session ${session}, ${when}, ${similarity}] ${role}: ${item.text ?? ""}`);
+      const context = Array.isArray(item.context)
+        ? (item.context as Array<Record<string, any>>)
+        : [];
+      for (const turn of context) {
+        if (turn.conversation_id === item.conversation_id) {
+          continue;
+        }
+        parts.push(`  - ${turn.message_type ?? "unknown"}: ${turn.prompt ?? ""}`);
+      }
+    }
+
+    return { content: [{ type: "text", text: parts.join("\n") }] };
+  },
+);
+
+const GetSessionSummarySchema = z.object({
+  session_id: z.number().int(),
+});
+
+server.registerTool(
+  "get_session_summary",
+  {
+    description: "Get the stored summary of a past session by id (e.g. from search_memory).",
+    inputSchema: GetSessionSummarySchema.shape,
+  },
+  async (args) => {
+    const parsed = GetSessionSummarySchema.parse(args);
+    const { status, data, text } = await daemonRequest<JsonRecord>({
+      path: `/sessions/${parsed.session_id}/summary`,
+      method: "GET",
+    });
+    if (status === 404) {
+      return { content: [{ type: "text", text: `Session ${parsed.session_id} not found` }] };
+    }
+    if (status < 200 || status >= 300 || !data) {
+      throw new Error(`Daemon request failed (${status}): ${text}`);
+    }
+
+    const started =
+      typeof data.start_time === "number" ? new Date(data.start_time * 1000).toISOString() : "";
+    const parts: string[] = [`## Session ${parsed.session_id}${data.name ? `: ${data.name}` : ""}`];
+    parts.push(`**Project:** ${data.working_dir ?? "unknown"}`);
+    if (data.personality) {
+      parts.push(`**Personality:** ${data.personality}`);
+    }
+    if (started) {
+      parts.push(`**Started:** ${started}`);
+    }
+    parts.push("");
+    const summary = typeof data.summary === "string" ? data.summary : "";
+    parts.push(summary || "(no summary yet)");
+
+    return { content: [{ type: "text", text: parts.join("\n") }] };
+  },
+);
+
+const ListEntitiesSchema = z.object({
+  type: z.string().optional(),
+  limit: z.number().int().optional().default(50),
+});
+
+server.registerTool(
+  "list_entities",
+  {
+    description:
+      "List knowledge graph entities, most mentioned first, optionally filtered by type (e.g. Person, Project).",
+    inputSchema: ListEntitiesSchema.shape,
+  },
+  async (args) => {
+    const parsed = ListEntitiesSchema.parse(args);
+    const data = await requestJson<JsonRecord>({
+      path: "/kg/entities",
+      method: "GET",
+      query: { labels: parsed.type, limit: parsed.limit, sort_by: "mention_count" },
+    });
+
+    const entities = Array.isArray(data.entities) ? data.entities : [];
+    if (!entities.length) {
+      const suffix = parsed.type ? ` of type '${parsed.type}'` : "";
+      return { content: [{ type: "text", text: `No entities found${suffix}` }] };
+    }
+
+    const total = typeof data.total === "number" ? data.total : entities.length;
+    const parts: string[] = [`## Entities${parsed.type ? ` (${parsed.type})` : ""}\n`];
+    for (const entity of entities) {
+      parts.push(`- ${formatEntity(entity as Record<string, any>)}`);
+    }
+    if (total > entities.length) {
+      parts.push(`\n*Showing ${entities.length} of ${total} entities*`);
+    }
+
+    return { content: [{ type: "text", text: parts.join("\n") }] };
+  },
+);
+
 const RecallContextSchema = z.object({
   around_date: z.string().optional(),
   limit: z.number().int().optional().default(20),
@@ -1,6 +1,8 @@
 import { readFileSync } from "node:fs";
 import { join } from "node:path";
 
+import { daemonRequest } from "../lib/daemon-client.ts";
+
 const RESET = "\u001b[0m";
 const RED = "\u001b[31m";
 const GREEN = "\u001b[32m";
@@ -14,6 +16,7 @@ const WHITE = "\u001b[97m";
 type SessionPayload = {
   model?: { id?: string };
   cwd?: string;
+  cost?: { total_cost_usd?: number; total_duration_ms?: number };
 };
 
 function hexToAnsi(hexColor: string): string {
@@ -163,6 +166,34 @@ function shortenPath(path: string): string {
   return value;
 }
 
+function formatCost(costUsd: number): string {
+  return `${GRAY}$${RESET}${costUsd.toFixed(2)}`;
+}
+
+async function recordSessionCost(session: SessionPayload, personality: string): Promise<void> {
+  const sessionId = Number.parseInt(process.env.DERE_SESSION_ID ?? "", 10);
+  const totalCost = session.cost?.total_cost_usd;
+  if (!Number.isFinite(sessionId) || typeof totalCost !== "number") {
+    return;
+  }
+  try {
+    await daemonRequest({
+      path: "/costs/record",
+      body: {
+        session_id: sessionId,
+        total_cost_usd: totalCost,
+        total_duration_ms: session.cost?.total_duration_ms ?? 0,
+        model: session.model?.id ?? null,
+        working_dir: session.cwd ?? null,
+        personality: personality || null,
+      },
+      timeoutMs: 300,
+    });
+  } catch {
+    // statusline must never fail because the daemon is down
+  }
+}
+
 function showDereStatusOnly(): void {
   const parts: string[] = [];
   const daemonRunning = checkDaemonStatus();
@@ -228,6 +259,10 @@ async function main(): Promise<void> {
     parts.push(formatSessionType(sessionType));
   }
 
+  if (process.env.DERE_NO_CAPTURE === "1") {
+    parts.push(`${YELLOW}⊘${RESET} no-capture`);
+  }
+
   if (customPrompts) {
     parts.push(`${GRAY}□${RESET} ${customPrompts}`);
   }
@@ -240,6 +275,11 @@ async function main(): Promise<void> {
     parts.push(`${GRAY}▸${RESET} ${shortenPath(session.cwd)}`);
   }
 
+  const totalCost = session?.cost?.total_cost_usd;
+  if (process.env.DERE_SHOW_COST === "1" && typeof totalCost === "number") {
+    parts.push(formatCost(totalCost));
+  }
+
   // Show bypass mode indicator at the end
   if (permissionMode) {
     const formatted = formatPermissionMode(permissionMode);
@@ -251,6 +291,11 @@ async function main(): Promise<void> {
   if (parts.length > 0) {
     process.stdout.write(parts.join(`${GRAY} │ ${RESET}`));
   }
+
+  // Excluded projects (DERE_NO_CAPTURE) leave no cost rows behind either.
+  if (daemonRunning && session && process.env.DERE_NO_CAPTURE !== "1") {
+    await recordSessionCost(session, personality);
+  }
 }
 
 if (import.meta.main) {